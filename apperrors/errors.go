@@ -0,0 +1,213 @@
+// Package apperrors defines the domain errors of the service. Every error
+// has a stable machine-readable code and the HTTP status it maps to, and its
+// message is safe to show to clients. The underlying cause, such as a
+// database error, can be attached for logging but is never sent to clients.
+package apperrors
+
+import (
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/constants"
+)
+
+// Error is a domain error. Two errors are considered equal by errors.Is
+// when they have the same code, so a sentinel matches its customised copies.
+//
+// Key names the message in the i18n catalogs and defaults to the code.
+// Message is the English text used when no catalog has the key.
+type Error struct {
+	Code    string
+	Status  int
+	Key     string
+	Message string
+
+	cause error
+}
+
+func New(code string, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Key:     code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a more specific client message. The
+// message is sent as is, because it has no key in the i18n catalogs.
+func (e *Error) WithMessage(message string) *Error {
+	output := *e
+	output.Key = ""
+	output.Message = message
+	return &output
+}
+
+// WithMessageKey returns a copy of e with a more specific client message
+// that is translated through the i18n catalogs under key.
+func (e *Error) WithMessageKey(key, message string) *Error {
+	output := *e
+	output.Key = key
+	output.Message = message
+	return &output
+}
+
+// Wrap returns a copy of e that records cause for logging.
+func (e *Error) Wrap(cause error) *Error {
+	output := *e
+	output.cause = cause
+	return &output
+}
+
+var (
+	ErrBadRequest           = New("BAD_REQUEST", http.StatusBadRequest, "the request is malformed")
+	ErrValidation           = New("VALIDATION_FAILED", http.StatusBadRequest, "the request is invalid")
+	ErrInvalidCredentials   = New("INVALID_CREDENTIALS", http.StatusUnauthorized, "invalid phone number, email or password")
+	ErrUnauthorized         = New("UNAUTHORIZED", http.StatusUnauthorized, "authentication is required")
+	ErrInvalidToken         = New("INVALID_TOKEN", http.StatusForbidden, "the token is invalid or expired")
+	ErrForbidden            = New("FORBIDDEN", http.StatusForbidden, "you are not allowed to perform this action")
+	ErrNotFound             = New("NOT_FOUND", http.StatusNotFound, "the resource was not found")
+	ErrMethodNotAllowed     = New("METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed, "the method is not allowed on this resource")
+	ErrConflict             = New("CONFLICT", http.StatusConflict, "the resource conflicts with existing data")
+	ErrUnsupportedMediaType = New("UNSUPPORTED_MEDIA_TYPE", http.StatusUnsupportedMediaType, "the content type is not supported")
+	ErrLocked               = New("LOCKED", http.StatusLocked, "the account is locked")
+	ErrPayloadTooLarge      = New("PAYLOAD_TOO_LARGE", http.StatusRequestEntityTooLarge, "the request is too large")
+	ErrTooManyRequests      = New("TOO_MANY_REQUESTS", http.StatusTooManyRequests, "too many requests, please try again later")
+	ErrInternal             = New("INTERNAL", http.StatusInternalServerError, "internal server error")
+	ErrServiceUnavailable   = New("SERVICE_UNAVAILABLE", http.StatusServiceUnavailable, "the service is unavailable")
+
+	ErrPhoneNumberExists        = ErrConflict.WithMessageKey("PHONE_NUMBER_EXISTS", "phone number already exists")
+	ErrEmailExists              = ErrConflict.WithMessageKey("EMAIL_EXISTS", "email already exists")
+	ErrPhoneOrEmailExists       = ErrConflict.WithMessageKey("PHONE_OR_EMAIL_EXISTS", "phone number or email already exists")
+	ErrInvalidVerificationToken = ErrInvalidToken.WithMessageKey("INVALID_VERIFICATION_TOKEN", "the verification token is invalid or expired")
+	ErrInvalidOTP               = ErrInvalidCredentials.WithMessageKey("INVALID_OTP", "the code is invalid or expired")
+	ErrEmptyRequest             = ErrValidation.WithMessageKey("EMPTY_REQUEST", "request body is required")
+	ErrInvalidContent           = ErrBadRequest.WithMessageKey("INVALID_CONTENT_TYPE", constants.ContentTypeJson)
+	ErrOAuthClientNotFound      = ErrNotFound.WithMessageKey("OAUTH_CLIENT_NOT_FOUND", "the OAuth client was not found")
+	ErrInvalidAuthorizeRequest  = ErrBadRequest.WithMessageKey("INVALID_AUTHORIZE_REQUEST", "the client or redirect URI is invalid")
+	ErrRedirectURIRequired      = ErrValidation.WithMessageKey("REDIRECT_URI_REQUIRED", "the authorization_code grant requires at least one redirect URI")
+	ErrPublicClientCredentials  = ErrValidation.WithMessageKey("PUBLIC_CLIENT_CREDENTIALS", "public clients cannot use the client_credentials grant")
+	ErrInvalidAccessToken       = ErrUnauthorized.WithMessageKey("INVALID_ACCESS_TOKEN", "the access token is invalid, expired or revoked")
+	ErrInsufficientScope        = ErrForbidden.WithMessageKey("INSUFFICIENT_SCOPE", "the access token does not have the required scope")
+	ErrAPIKeyNotFound           = ErrNotFound.WithMessageKey("API_KEY_NOT_FOUND", "the API key was not found")
+	ErrAPIKeyScope              = ErrForbidden.WithMessageKey("API_KEY_SCOPE", "the API key does not have the required scope")
+	ErrOrganizationNotFound     = ErrNotFound.WithMessageKey("ORGANIZATION_NOT_FOUND", "the organization was not found")
+	ErrInvalidParent            = ErrValidation.WithMessageKey("INVALID_PARENT", "companies have no parent, estates belong to a company and divisions to an estate")
+	ErrMemberExists             = ErrConflict.WithMessageKey("MEMBER_EXISTS", "the user is already a member of the organization")
+	ErrMemberNotFound           = ErrNotFound.WithMessageKey("MEMBER_NOT_FOUND", "the user is not a member of the organization")
+	ErrLastOwner                = ErrConflict.WithMessageKey("LAST_OWNER", "a company must keep at least one owner")
+	ErrInvitationNotFound       = ErrNotFound.WithMessageKey("INVITATION_NOT_FOUND", "the invitation was not found")
+	ErrInvitationExists         = ErrConflict.WithMessageKey("INVITATION_EXISTS", "the phone number already has a pending invitation to the organization")
+	ErrInvitationClosed         = ErrConflict.WithMessageKey("INVITATION_CLOSED", "the invitation was already accepted or revoked")
+	ErrInvalidInvitation        = ErrInvalidToken.WithMessageKey("INVALID_INVITATION", "the invitation is invalid, expired, revoked or already accepted")
+	ErrInvalidCSV               = ErrValidation.WithMessageKey("INVALID_CSV", "the CSV file is malformed or lacks the full_name, phone, role and organization columns")
+	ErrIncompleteRow            = ErrValidation.WithMessageKey("INCOMPLETE_ROW", "the row has fewer columns than the header")
+	ErrDuplicateRow             = ErrConflict.WithMessageKey("DUPLICATE_ROW", "the phone number is on an earlier row of the file")
+	ErrImportTooLarge           = ErrPayloadTooLarge.WithMessageKey("IMPORT_TOO_LARGE", "the file must be at most 10 MB")
+	ErrInvalidExportFormat      = ErrValidation.WithMessageKey("INVALID_EXPORT_FORMAT", "the format must be csv or jsonl")
+	ErrImpersonationForbidden   = ErrForbidden.WithMessageKey("IMPERSONATION_FORBIDDEN", "this action is not allowed while impersonating a user")
+	ErrAttributeNotFound        = ErrNotFound.WithMessageKey("ATTRIBUTE_NOT_FOUND", "the profile attribute was not found")
+	ErrAttributeExists          = ErrConflict.WithMessageKey("ATTRIBUTE_EXISTS", "a profile attribute with this name already exists")
+	ErrInvalidAttributeRules    = ErrValidation.WithMessageKey("INVALID_ATTRIBUTE_RULES", "the rules are not supported for the type of the attribute")
+	ErrAvatarRequired           = ErrValidation.WithMessageKey("AVATAR_REQUIRED", "the avatar field must contain an image file")
+	ErrAvatarTooLarge           = ErrPayloadTooLarge.WithMessageKey("AVATAR_TOO_LARGE", "the image must be at most 5 MB")
+	ErrImageTooLarge            = ErrPayloadTooLarge.WithMessageKey("IMAGE_TOO_LARGE", "the image must be at most 25 megapixels")
+	ErrUnsupportedImage         = ErrUnsupportedMediaType.WithMessageKey("UNSUPPORTED_IMAGE", "the image must be a JPEG or PNG file")
+	ErrSMSDisabled              = ErrServiceUnavailable.WithMessageKey("SMS_DISABLED", "login codes cannot be sent, as no SMS gateway is configured")
+)
+
+// All lists every error defined by this package, so that tests can check
+// their message keys against the i18n catalogs.
+func All() []*Error {
+	return []*Error{
+		ErrBadRequest,
+		ErrValidation,
+		ErrInvalidCredentials,
+		ErrUnauthorized,
+		ErrInvalidToken,
+		ErrForbidden,
+		ErrNotFound,
+		ErrMethodNotAllowed,
+		ErrConflict,
+		ErrUnsupportedMediaType,
+		ErrLocked,
+		ErrTooManyRequests,
+		ErrPayloadTooLarge,
+		ErrInternal,
+		ErrServiceUnavailable,
+		ErrPhoneNumberExists,
+		ErrEmailExists,
+		ErrPhoneOrEmailExists,
+		ErrInvalidVerificationToken,
+		ErrInvalidOTP,
+		ErrEmptyRequest,
+		ErrInvalidContent,
+		ErrOAuthClientNotFound,
+		ErrInvalidAuthorizeRequest,
+		ErrRedirectURIRequired,
+		ErrPublicClientCredentials,
+		ErrInvalidAccessToken,
+		ErrInsufficientScope,
+		ErrAPIKeyNotFound,
+		ErrAPIKeyScope,
+		ErrOrganizationNotFound,
+		ErrInvalidParent,
+		ErrMemberExists,
+		ErrMemberNotFound,
+		ErrLastOwner,
+		ErrInvitationNotFound,
+		ErrInvitationExists,
+		ErrInvitationClosed,
+		ErrInvalidInvitation,
+		ErrInvalidCSV,
+		ErrIncompleteRow,
+		ErrDuplicateRow,
+		ErrImportTooLarge,
+		ErrInvalidExportFormat,
+		ErrImpersonationForbidden,
+		ErrAttributeNotFound,
+		ErrAttributeExists,
+		ErrInvalidAttributeRules,
+		ErrAvatarRequired,
+		ErrAvatarTooLarge,
+		ErrImageTooLarge,
+		ErrUnsupportedImage,
+		ErrSMSDisabled,
+	}
+}
+
+// FromStatus returns the domain error for an HTTP status produced outside
+// the handlers, e.g. by the router, falling back to ErrInternal.
+func FromStatus(status int) *Error {
+	for _, e := range []*Error{
+		ErrBadRequest,
+		ErrUnauthorized,
+		ErrForbidden,
+		ErrNotFound,
+		ErrMethodNotAllowed,
+		ErrConflict,
+		ErrUnsupportedMediaType,
+		ErrLocked,
+		ErrTooManyRequests,
+	} {
+		if e.Status == status {
+			return e
+		}
+	}
+	return ErrInternal
+}
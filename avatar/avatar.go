@@ -0,0 +1,242 @@
+// Package avatar turns uploaded profile photos into avatar thumbnails. Only
+// the decoded pixels are kept: thumbnails are encoded again as JPEG, which
+// drops EXIF metadata such as the location a photo was taken at, and
+// anything else hidden in the upload.
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+)
+
+const (
+	// MaxBytes is the largest photo accepted.
+	MaxBytes = 5 << 20
+	// MaxPixels limits the memory decoding a photo takes, since a small
+	// file can hold a huge image.
+	MaxPixels = 25_000_000
+	// ContentType is the type of the thumbnails.
+	ContentType = "image/jpeg"
+)
+
+// Sizes are the widths and heights of the square thumbnails, in pixels.
+var Sizes = []int{64, 128, 256}
+
+type Thumbnail struct {
+	Size int
+	Data []byte
+}
+
+// Process checks that data is a JPEG or PNG image by its magic bytes and
+// returns a thumbnail of every size. Photos are cropped to the square in
+// their middle and turned upright according to their EXIF orientation.
+// Transparent areas become white.
+func Process(data []byte) ([]Thumbnail, error) {
+	var decodeConfig func(r *bytes.Reader) (image.Config, error)
+	var decode func(r *bytes.Reader) (image.Image, error)
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return jpeg.DecodeConfig(r) }
+		decode = func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) }
+	case "image/png":
+		decodeConfig = func(r *bytes.Reader) (image.Config, error) { return png.DecodeConfig(r) }
+		decode = func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) }
+	default:
+		return nil, apperrors.ErrUnsupportedImage
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperrors.ErrUnsupportedImage.Wrap(err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, apperrors.ErrImageTooLarge
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperrors.ErrUnsupportedImage.Wrap(err)
+	}
+
+	square := cropSquare(img)
+	orientation := exifOrientation(data)
+
+	thumbnails := make([]Thumbnail, 0, len(Sizes))
+	for _, size := range Sizes {
+		// The middle square of the upright photo is the upright middle
+		// square, so only the small thumbnails need turning.
+		thumbnail := orient(resize(square, size), orientation)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, Thumbnail{
+			Size: size,
+			Data: buf.Bytes(),
+		})
+	}
+	return thumbnails, nil
+}
+
+// cropSquare copies the square in the middle of img on a white background.
+func cropSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), img, origin, draw.Over)
+	return square
+}
+
+// resize scales a square image to size by averaging the pixels each pixel
+// of the thumbnail covers. Images smaller than size are enlarged by
+// repeating pixels.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	side := src.Bounds().Dx()
+
+	span := func(i int) (int, int) {
+		from, to := i*side/size, (i+1)*side/size
+		if to <= from {
+			to = from + 1
+		}
+		return from, to
+	}
+
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[offset+c])
+					}
+					offset += 4
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			offset := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+	return dst
+}
+
+// orient turns img upright according to an EXIF orientation, 1 to 8.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	// source returns the pixel of img shown at x, y of the upright image.
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2:
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3:
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4:
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5:
+		source = func(x, y int) (int, int) { return y, x }
+	case 6:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	default:
+		return img
+	}
+
+	bounds := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		bounds = image.Rect(0, 0, h, w)
+	}
+
+	dst := image.NewRGBA(bounds)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation returns the orientation tag of the EXIF metadata of a
+// JPEG file, or 1, upright, when it has none.
+func exifOrientation(data []byte) int {
+	const orientationTag = 0x0112
+
+	// Walk the segments of the file up to the image data.
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		i += 2 + length
+
+		if marker != 0xE1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			continue
+		}
+
+		tiff := segment[6:]
+		if len(tiff) < 8 {
+			return 1
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd < 8 || ifd+2 > len(tiff) {
+			return 1
+		}
+		entries := int(order.Uint16(tiff[ifd:]))
+		for k := 0; k < entries; k++ {
+			entry := ifd + 2 + 12*k
+			if entry+12 > len(tiff) {
+				return 1
+			}
+			if order.Uint16(tiff[entry:]) == orientationTag {
+				orientation := int(order.Uint16(tiff[entry+8:]))
+				if orientation < 1 || orientation > 8 {
+					return 1
+				}
+				return orientation
+			}
+		}
+		return 1
+	}
+	return 1
+}
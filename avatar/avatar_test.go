@@ -0,0 +1,110 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcess(t *testing.T) {
+	encodePNG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, img))
+		return buf.Bytes()
+	}
+
+	t.Run("Positive Scenario, Thumbnails are square JPEG images", func(t *testing.T) {
+		// Red on the left and blue on the right, around a transparent
+		// square in the middle.
+		img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+		for y := 0; y < 300; y++ {
+			for x := 0; x < 600; x++ {
+				switch {
+				case x >= 150 && x < 450:
+					img.Set(x, y, color.Transparent)
+				case x < 300:
+					img.Set(x, y, color.RGBA{R: 255, A: 255})
+				default:
+					img.Set(x, y, color.RGBA{B: 255, A: 255})
+				}
+			}
+		}
+
+		thumbnails, err := Process(encodePNG(img))
+		assert.NoError(t, err)
+		if assert.Len(t, thumbnails, len(Sizes)) {
+			for i, thumbnail := range thumbnails {
+				assert.Equal(t, Sizes[i], thumbnail.Size)
+				decoded, err := jpeg.Decode(bytes.NewReader(thumbnail.Data))
+				assert.NoError(t, err)
+				assert.Equal(t, image.Rect(0, 0, Sizes[i], Sizes[i]), decoded.Bounds())
+
+				// Only the transparent middle is left, now white.
+				r, g, b, _ := decoded.At(Sizes[i]/2, Sizes[i]/2).RGBA()
+				assert.Greater(t, r>>8, uint32(240))
+				assert.Greater(t, g>>8, uint32(240))
+				assert.Greater(t, b>>8, uint32(240))
+			}
+		}
+	})
+
+	t.Run("Negative Scenario, Other files are rejected by their content", func(t *testing.T) {
+		_, err := Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedImage)
+
+		var buf bytes.Buffer
+		assert.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 10, 10), color.Palette{color.White}), nil))
+		_, err = Process(buf.Bytes())
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedImage)
+
+		// A PNG signature followed by garbage.
+		_, err = Process(append([]byte("\x89PNG\r\n\x1a\n"), "garbage"...))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupportedImage)
+
+		// A small PNG claiming to be 6000 pixels wide and high.
+		huge := encodePNG(image.NewGray(image.Rect(0, 0, 1, 1)))
+		binary.BigEndian.PutUint32(huge[16:], 6000)
+		binary.BigEndian.PutUint32(huge[20:], 6000)
+		binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+		_, err = Process(huge)
+		assert.ErrorIs(t, err, apperrors.ErrImageTooLarge)
+	})
+
+	t.Run("Positive Scenario, Photos are turned upright", func(t *testing.T) {
+		// The APP1 segment of a big endian EXIF block with orientation 6.
+		app1 := []byte{
+			0xFF, 0xE1, 0x00, 0x22,
+			'E', 'x', 'i', 'f', 0, 0,
+			'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+			0x00, 0x01,
+			0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+		}
+		var buf bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil))
+		photo := append(append([]byte{0xFF, 0xD8}, app1...), buf.Bytes()[2:]...)
+		assert.Equal(t, 6, exifOrientation(photo))
+		assert.Equal(t, 1, exifOrientation(buf.Bytes()))
+
+		_, err := Process(photo)
+		assert.NoError(t, err)
+
+		// Red on the left of the sensor is at the top once rotated.
+		img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, color.RGBA{R: 255, A: 255})
+		img.Set(1, 0, color.RGBA{B: 255, A: 255})
+		upright := orient(img, 6)
+		assert.Equal(t, image.Rect(0, 0, 1, 2), upright.Bounds())
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, upright.At(0, 0))
+		assert.Equal(t, color.RGBA{B: 255, A: 255}, upright.At(0, 1))
+	})
+}
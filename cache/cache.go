@@ -0,0 +1,121 @@
+// Package cache stores short-lived copies of data read from the database.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps values by key until their TTL expires. Get reports a key that
+// is missing or expired with ok false, not with an error.
+type Store interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// DefaultLRUSize is the number of entries an LRUStore keeps unless told
+// otherwise.
+const DefaultLRUSize = 10000
+
+// LRUStore keeps entries in the memory of the process, evicting the least
+// recently used one when it is full. Each process has its own entries, so
+// deleting a key in one does not delete it in the others.
+type LRUStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+type NewLRUStoreOptions struct {
+	// Size is the maximum number of entries, DefaultLRUSize when zero.
+	Size int
+}
+
+func NewLRUStore(opts NewLRUStoreOptions) *LRUStore {
+	size := opts.Size
+	if size <= 0 {
+		size = DefaultLRUSize
+	}
+	return &LRUStore{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+func (s *LRUStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !s.now().Before(entry.expires) {
+		s.order.Remove(element)
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (s *LRUStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &lruEntry{
+		key:     key,
+		value:   value,
+		expires: s.now().Add(ttl),
+	}
+	if element, ok := s.entries[key]; ok {
+		element.Value = entry
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(entry)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (s *LRUStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := s.entries[key]; ok {
+			s.order.Remove(element)
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet
+// removed.
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Positive Scenario, The least recently used entry is evicted", func(t *testing.T) {
+		store := NewLRUStore(NewLRUStoreOptions{Size: 2})
+		assert.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.NoError(t, store.Set(ctx, "b", []byte("2"), time.Minute))
+
+		_, ok, _ := store.Get(ctx, "a")
+		assert.True(t, ok)
+
+		assert.NoError(t, store.Set(ctx, "c", []byte("3"), time.Minute))
+		_, ok, _ = store.Get(ctx, "b")
+		assert.False(t, ok)
+		value, ok, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), value)
+		assert.Equal(t, 2, store.Len())
+
+		assert.NoError(t, store.Delete(ctx, "a", "missing"))
+		_, ok, _ = store.Get(ctx, "a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Negative Scenario, Expired entries are misses", func(t *testing.T) {
+		now := time.Now()
+		store := NewLRUStore(NewLRUStoreOptions{})
+		store.now = func() time.Time { return now }
+
+		assert.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+		now = now.Add(time.Minute)
+		_, ok, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, 0, store.Len())
+	})
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := NewRedisStore(NewRedisStoreOptions{Client: client})
+
+	t.Run("Positive Scenario, Entries are prefixed and expire", func(t *testing.T) {
+		assert.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.True(t, server.Exists(DefaultRedisPrefix+"a"))
+
+		value, ok, err := store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), value)
+
+		server.FastForward(time.Minute)
+		_, ok, err = store.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		assert.NoError(t, store.Set(ctx, "b", []byte("2"), time.Minute))
+		assert.NoError(t, store.Delete(ctx, "b", "missing"))
+		_, ok, _ = store.Get(ctx, "b")
+		assert.False(t, ok)
+	})
+
+	t.Run("Negative Scenario, Server failures are errors", func(t *testing.T) {
+		server.SetError("LOADING")
+		defer server.SetError("")
+
+		_, ok, err := store.Get(ctx, "a")
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("Positive Scenario, Lookups are counted by kind", func(t *testing.T) {
+		metrics := NewMetrics()
+		metrics.Hit("profile")
+		metrics.Hit("profile")
+		metrics.Miss("profile")
+		metrics.Error("login")
+		assert.Equal(t, map[string]Counts{
+			"profile": {Hits: 2, Misses: 1},
+			"login":   {Errors: 1},
+		}, metrics.Snapshot())
+		assert.JSONEq(t, `{"profile": {"hits": 2, "misses": 1, "errors": 0}, "login": {"hits": 0, "misses": 0, "errors": 1}}`, metrics.String())
+	})
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Counts are the lookups of one kind of entry. Errors counts the store
+// failures, after which lookups fall back to the database.
+type Counts struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
+// Metrics counts the lookups of each kind of entry. It is an expvar.Var,
+// so it can be published with expvar.Publish.
+type Metrics struct {
+	mu     sync.Mutex
+	counts map[string]*Counts
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counts: map[string]*Counts{},
+	}
+}
+
+func (m *Metrics) Hit(kind string) {
+	m.add(kind, func(counts *Counts) { counts.Hits++ })
+}
+
+func (m *Metrics) Miss(kind string) {
+	m.add(kind, func(counts *Counts) { counts.Misses++ })
+}
+
+func (m *Metrics) Error(kind string) {
+	m.add(kind, func(counts *Counts) { counts.Errors++ })
+}
+
+func (m *Metrics) add(kind string, fn func(*Counts)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts, ok := m.counts[kind]
+	if !ok {
+		counts = &Counts{}
+		m.counts[kind] = counts
+	}
+	fn(counts)
+}
+
+// Snapshot returns a copy of the counts by kind.
+func (m *Metrics) Snapshot() map[string]Counts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]Counts, len(m.counts))
+	for kind, counts := range m.counts {
+		snapshot[kind] = *counts
+	}
+	return snapshot
+}
+
+// String returns the counts as a JSON object by kind.
+func (m *Metrics) String() string {
+	output, _ := json.Marshal(m.Snapshot())
+	return string(output)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisPrefix is prepended to the keys in Redis unless told
+// otherwise, so the database can be shared with other services.
+const DefaultRedisPrefix = "userservice:"
+
+// RedisStore keeps entries in Redis, or any server speaking its protocol,
+// where every instance of the service sees the same entries. Expiry is left
+// to the server.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+type NewRedisStoreOptions struct {
+	Client redis.UniversalClient
+	// Prefix is prepended to every key, DefaultRedisPrefix when empty.
+	Prefix string
+}
+
+func NewRedisStore(opts NewRedisStoreOptions) *RedisStore {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisStore{
+		client: opts.Client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, s.prefix+key)
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/importer"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/phone"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const usage = `usage: admin [-driver postgres|sqlite] <command>
+
+commands:
+  grant PHONE              make the user with the E.164 phone number an admin
+  revoke PHONE             make the user with the E.164 phone number a regular user
+  import [-dry-run] FILE   register the users of a CSV file with the columns
+                           full_name, phone, role and organization`
+
+func main() {
+	driver := flag.String("driver", repository.DriverPostgres, "database driver: postgres or sqlite")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var role string
+	switch args[0] {
+	case "import":
+		// Imported once the repository is open.
+	case "grant":
+		role = repository.RoleAdmin
+	case "revoke":
+		role = repository.RoleUser
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
+		Driver: *driver,
+		Dsn:    os.Getenv("DATABASE_URL"),
+	})
+
+	if args[0] == "import" {
+		importUsers(repo, args[1:])
+		return
+	}
+
+	ctx := context.Background()
+	phoneNumber := args[1]
+	filter := repository.ProfileFilter{
+		Phone: &phoneNumber,
+	}
+	profiles, err := repo.GetProfile(ctx, filter)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(profiles) == 0 {
+		log.Fatalf("no user has the phone number %s", phoneNumber)
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	err = repo.UpdateProfile(ctx, filter, repository.ProfilePatch{
+		Role:      &role,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("user %d is now %s", profiles[0].UserId, role)
+}
+
+// importUsers imports a CSV file like POST /admin/users/import and prints
+// the result of every row that did not succeed.
+func importUsers(repo repository.RepositoryInterface, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "check the file without saving anything")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	phoneNormalizer := phone.NewNormalizerFromEnv()
+	report, err := importer.NewImporter(importer.NewImporterOptions{
+		Repository: repo,
+		Validator: middlewares.NewValidator(middlewares.NewValidatorOptions{
+			PhoneNormalizer: phoneNormalizer,
+		}),
+		PhoneNormalizer: phoneNormalizer,
+	}).Import(context.Background(), file, *dryRun)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, result := range report.Results {
+		switch {
+		case len(result.Violations) > 0:
+			fmt.Printf("line %d: %s: %s\n", result.Line, result.PhoneNumber, result.Violations.Error())
+		case result.Err != nil:
+			fmt.Printf("line %d: %s: %s\n", result.Line, result.PhoneNumber, result.Err.Message)
+		}
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d joined, %d unchanged, %d failed\n", prefix,
+		report.Count(importer.StatusCreated),
+		report.Count(importer.StatusJoined),
+		report.Count(importer.StatusUnchanged),
+		report.Count(importer.StatusFailed))
+	if report.Count(importer.StatusFailed) > 0 {
+		os.Exit(1)
+	}
+}
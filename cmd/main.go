@@ -1,22 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"github.com/SawitProRecruitment/UserService/cache"
 	"github.com/SawitProRecruitment/UserService/generated"
 	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/metrics"
 	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/phone"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/SawitProRecruitment/UserService/storage"
+	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
 	//_ = godotenv.Load(".env")
+	backend := flag.String("backend", "postgres", "repository backend: postgres, sqlite, or memory to run without a database")
+	flag.Parse()
+
+	server := newServer(*backend)
 
 	e := echo.New()
+	e.HTTPErrorHandler = middlewares.HTTPErrorHandler
 
 	// middleware
+	e.Use(middleware.RequestID())
+	e.Use(middlewares.RequestMetrics())
+	e.Use(middlewares.Locale())
+	// Before the content type check, so that rejected requests are audited.
+	e.Use(server.AuditImpersonation())
+	e.Use(server.RestrictImpersonation())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
@@ -24,23 +52,245 @@ func main() {
 	e.Use(middlewares.ValidateContentType())
 	e.Use(middleware.Logger())
 
-	var server generated.ServerInterface = newServer()
+	// Blob keys are random, so the directory is not listed.
+	e.Static("/blobs", blobDir())
 
 	generated.RegisterHandlers(e, server)
+	go serveMetrics()
 	e.Logger.Fatal(e.Start(":1323"))
 }
 
-func newServer() *handler.Server {
-	dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Dsn: dbDsn,
-	})
+func newServer(backend string) *handler.Server {
+	var repo repository.RepositoryInterface
+	switch backend {
+	case "memory":
+		log.Println("using the in-memory repository, data is lost on restart")
+		repo = repository.NewMemoryRepository()
+	case repository.DriverPostgres, repository.DriverSQLite:
+		dbDsn := os.Getenv("DATABASE_URL")
+		sqlRepo := repository.NewRepository(repository.NewRepositoryOptions{
+			Driver: backend,
+			Dsn:    dbDsn,
+		})
+		if os.Getenv("CHECK_SCHEMA_VERSION") == "true" {
+			checkSchemaVersion(sqlRepo)
+		}
+		sqlDB, err := sqlRepo.Db.DB()
+		if err != nil {
+			log.Fatalf("getting the connection pool failed: %v", err)
+		}
+		metrics.RegisterDB(sqlDB, backend)
+		repo = sqlRepo
+	default:
+		log.Fatalf("unknown backend %q", backend)
+	}
+	repo = newCachedRepository(repo)
+
+	phoneNormalizer := phone.NewNormalizerFromEnv()
 
 	//validator := middlewares.NewValidator()
-	var validator middlewares.CustomValidatorInterface = middlewares.NewValidator()
+	var validator middlewares.CustomValidatorInterface = middlewares.NewValidator(middlewares.NewValidatorOptions{
+		PhoneNormalizer: phoneNormalizer,
+	})
+	if os.Getenv("OTP_KEY") == "" {
+		log.Println("OTP_KEY is not set, one-time login codes only work on the instance that sent them until it restarts")
+	}
+
 	opts := handler.NewServerOptions{
-		Repository: repo,
-		Validator:  validator,
+		Repository:          repo,
+		Validator:           validator,
+		PhoneNormalizer:     phoneNormalizer,
+		Mailer:              newMailer(),
+		SMSSender:           newSMSSender(),
+		Issuer:              os.Getenv("OAUTH_ISSUER"),
+		IntrospectionSecret: os.Getenv("INTROSPECTION_SECRET"),
+		OTPKey:              []byte(os.Getenv("OTP_KEY")),
+		BlobStore:           newBlobStore(),
 	}
 	return handler.NewServer(opts)
 }
+
+// newCachedRepository puts the cache selected by CACHE_DRIVER in front of
+// repo: lru keeps up to CACHE_SIZE entries in each process, and redis keeps
+// them in the Redis at REDIS_URL, shared by every instance. Without a
+// CACHE_DRIVER, repo is used directly. CACHE_PROFILE_TTL and
+// CACHE_TOKEN_TTL override how long entries are kept. The hits and misses
+// are published at /metrics and /debug/vars.
+func newCachedRepository(repo repository.RepositoryInterface) repository.RepositoryInterface {
+	var store cache.Store
+	switch driver := os.Getenv("CACHE_DRIVER"); driver {
+	case "", "none":
+		return repo
+	case "lru":
+		size := 0
+		if value := os.Getenv("CACHE_SIZE"); value != "" {
+			var err error
+			if size, err = strconv.Atoi(value); err != nil || size <= 0 {
+				log.Fatalf("invalid CACHE_SIZE %q", value)
+			}
+		}
+		store = cache.NewLRUStore(cache.NewLRUStoreOptions{
+			Size: size,
+		})
+	case "redis":
+		redisOpts, err := redis.ParseURL(os.Getenv("REDIS_URL"))
+		if err != nil {
+			log.Fatalf("invalid REDIS_URL: %v", err)
+		}
+		store = cache.NewRedisStore(cache.NewRedisStoreOptions{
+			Client: redis.NewClient(redisOpts),
+		})
+	default:
+		log.Fatalf("unknown CACHE_DRIVER %q", driver)
+	}
+
+	cached := repository.NewCachedRepository(repository.NewCachedRepositoryOptions{
+		Repository: repo,
+		Store:      store,
+		ProfileTTL: durationEnv("CACHE_PROFILE_TTL"),
+		TokenTTL:   durationEnv("CACHE_TOKEN_TTL"),
+	})
+	expvar.Publish("cache", cached.Metrics())
+	metrics.RegisterCache(cached.Metrics())
+	return cached
+}
+
+// serveMetrics serves /metrics and /debug/vars on METRICS_ADDR, :2112 by
+// default, apart from the API so that they are not exposed with it.
+func serveMetrics() {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":2112"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// durationEnv parses the environment variable name, such as 90s or 5m. It
+// returns zero when the variable is unset.
+func durationEnv(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("invalid %s %q", name, value)
+	}
+	return duration
+}
+
+// blobDir returns BLOB_DIR, the directory avatars are stored in, by default
+// blobs in the working directory.
+func blobDir() string {
+	if dir := os.Getenv("BLOB_DIR"); dir != "" {
+		return dir
+	}
+	return "blobs"
+}
+
+// newBlobStore keeps avatars in blobDir, served by the service at /blobs.
+// BLOB_BASE_URL overrides the URL they are served at, for example when a
+// CDN serves the directory; it defaults to /blobs under OAUTH_ISSUER, the
+// public URL of the service.
+func newBlobStore() storage.BlobStore {
+	baseURL := os.Getenv("BLOB_BASE_URL")
+	if baseURL == "" {
+		issuer := os.Getenv("OAUTH_ISSUER")
+		if issuer == "" {
+			issuer = "http://localhost:1323"
+		}
+		baseURL = strings.TrimRight(issuer, "/") + "/blobs"
+	}
+
+	return storage.NewFileStore(storage.NewFileStoreOptions{
+		Dir:     blobDir(),
+		BaseURL: baseURL,
+	})
+}
+
+// newMailer picks the mail sender from MAIL_DRIVER: smtp delivers through
+// SMTP_HOST, file appends messages to MAIL_FILE, and log, the default,
+// writes them to the server log.
+func newMailer() mail.Sender {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			log.Fatalf("invalid SMTP_PORT %q", os.Getenv("SMTP_PORT"))
+		}
+		return mail.NewSMTPSender(mail.NewSMTPSenderOptions{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	case "file":
+		file, err := os.OpenFile(os.Getenv("MAIL_FILE"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return mail.NewWriterSender(file, from)
+	case "", "log":
+		return mail.NewWriterSender(log.Writer(), from)
+	default:
+		log.Fatalf("unknown MAIL_DRIVER %q", driver)
+		return nil
+	}
+}
+
+// newSMSSender returns the sender selected by SMS_DRIVER: http posts
+// messages to SMS_HTTP_URL, and log writes login codes and invitation
+// tokens to the server log, which is only safe locally. Without a driver
+// nothing is texted and OTP logins are refused.
+func newSMSSender() sms.Sender {
+	switch driver := os.Getenv("SMS_DRIVER"); driver {
+	case "http":
+		url := os.Getenv("SMS_HTTP_URL")
+		if url == "" {
+			log.Fatalln("SMS_HTTP_URL is required with SMS_DRIVER=http")
+		}
+		return sms.NewHTTPSender(sms.NewHTTPSenderOptions{
+			URL:   url,
+			Token: os.Getenv("SMS_HTTP_TOKEN"),
+		})
+	case "log":
+		log.Println("SMS_DRIVER=log writes login codes to the log, use it for local development only")
+		return sms.NewWriterSender(log.Writer())
+	case "":
+		log.Println("warning: SMS_DRIVER is not set, OTP logins and invitations are disabled; use http, or log for local development")
+		return sms.NewDisabledSender()
+	default:
+		log.Fatalf("unknown SMS_DRIVER %q", driver)
+		return nil
+	}
+}
+
+// checkSchemaVersion refuses to start the server on a database whose schema
+// version this binary does not know, e.g. after a rollback of the service
+// without rolling back its migrations. Pending migrations are only logged.
+func checkSchemaVersion(repo *repository.Repository) {
+	migrator, err := migrations.NewMigrator(repo.Db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = migrator.Check(context.Background())
+	if errors.Is(err, migrations.ErrPendingMigrations) {
+		log.Println(err)
+		return
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const usage = `usage: migrate [-driver postgres|sqlite] <command>
+
+commands:
+  up          apply all pending migrations
+  down        revert the most recently applied migration
+  status      list migrations and whether they are applied
+  to VERSION  migrate up or down to VERSION (0 reverts everything)`
+
+func main() {
+	driver := flag.String("driver", repository.DriverPostgres, "database driver: postgres or sqlite")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
+		Driver: *driver,
+		Dsn:    os.Getenv("DATABASE_URL"),
+	})
+
+	migrator, err := migrations.NewMigrator(repo.Db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printStatus(ctx, migrator)
+	case "to":
+		if len(args) < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		var version uint64
+		version, err = strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("invalid version %q", args[1])
+		}
+		err = migrator.To(ctx, uint(version))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("schema version %d (latest %d)", version, migrator.Latest())
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%06d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// Scopes of API keys. Session tokens of /login are not limited by scopes.
+const (
+	apiKeyScopeProfileRead  = "profile:read"
+	apiKeyScopeProfileWrite = "profile:write"
+	apiKeyScopeAdmin        = "admin"
+)
+
+// API keys look like pat_<prefix>_<secret>. The prefix is stored in clear
+// to find the key, and tells keys apart in listings.
+const apiKeyMarker = "pat_"
+
+func (s *Server) CreateApiKey(ctx echo.Context, params generated.CreateApiKeyParams) error {
+	var req *generated.APIKeyRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	// API keys cannot create more API keys, which would outlive revoking
+	// them.
+	user, _, tokenErr := s.sessionUser(ctx, params.Authorization)
+	if tokenErr == nil {
+		middlewares.SetLocale(ctx, user.Locale)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	prefix, _, err := utils.NewSecret(4)
+	if err != nil {
+		return err
+	}
+	secret, _, err := utils.NewSecret(32)
+	if err != nil {
+		return err
+	}
+	key := apiKeyMarker + prefix + "_" + secret
+
+	now := time.Now()
+	apiKey := repository.APIKey{
+		UserId:     user.UserId,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: utils.HashSecret(key),
+		Scopes:     strings.Join(req.Scopes, " "),
+		CreatedAt:  now.Format(utils.TimestampLayout),
+	}
+	if req.ExpiresInDays != nil {
+		expires := now.AddDate(0, 0, *req.ExpiresInDays).Format(utils.TimestampLayout)
+		apiKey.Expires = &expires
+	}
+
+	resCreateKey, err := s.Repository.CreateAPIKey(ctx.Request().Context(), apiKey)
+	if err != nil {
+		return err
+	}
+
+	res := apiKeyResponse(resCreateKey)
+	res.Key = &key
+	return ctx.JSON(201, res)
+}
+
+func (s *Server) ListApiKeys(ctx echo.Context, params generated.ListApiKeysParams) error {
+	user, _, err := s.sessionUser(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+	middlewares.SetLocale(ctx, user.Locale)
+
+	userID := user.UserId
+	resGetKey, err := s.Repository.GetAPIKey(ctx.Request().Context(), repository.APIKeyFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]generated.APIKeyResponse, 0, len(resGetKey))
+	for _, key := range resGetKey {
+		keys = append(keys, apiKeyResponse(key))
+	}
+
+	return ctx.JSON(200, generated.APIKeyListResponse{
+		ApiKeys: keys,
+	})
+}
+
+func (s *Server) RevokeApiKey(ctx echo.Context, keyId int64, params generated.RevokeApiKeyParams) error {
+	user, _, err := s.sessionUser(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+	middlewares.SetLocale(ctx, user.Locale)
+
+	// Keys of other users are reported as not found.
+	userID := user.UserId
+	filter := repository.APIKeyFilter{
+		KeyID:  &keyId,
+		UserID: &userID,
+	}
+	resGetKey, err := s.Repository.GetAPIKey(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetKey) == 0 {
+		return apperrors.ErrAPIKeyNotFound
+	}
+
+	if resGetKey[0].RevokedAt == nil {
+		now := time.Now().Format(utils.TimestampLayout)
+		err = s.Repository.UpdateAPIKey(ctx.Request().Context(), filter, repository.APIKeyPatch{
+			RevokedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.NoContent(204)
+}
+
+// apiKeyUser returns the user of an API key that grants scope, and records
+// that the key was used.
+func (s *Server) apiKeyUser(ctx echo.Context, key, scope string) (repository.Profile, error) {
+	prefix, _, ok := strings.Cut(strings.TrimPrefix(key, apiKeyMarker), "_")
+	if !ok {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeAPIKey, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, apperrors.ErrInvalidToken
+	}
+
+	resGetKey, err := s.Repository.GetAPIKey(ctx.Request().Context(), repository.APIKeyFilter{
+		Prefix: &prefix,
+	})
+	if err != nil {
+		return repository.Profile{}, err
+	}
+
+	if len(resGetKey) == 0 || !apiKeyUsable(resGetKey[0], key) {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeAPIKey, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, apperrors.ErrInvalidToken
+	}
+	apiKey := resGetKey[0]
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID: &apiKey.UserId,
+	})
+	if err != nil {
+		return repository.Profile{}, err
+	}
+
+	if len(resGetProfile) == 0 {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeAPIKey, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, apperrors.ErrInvalidToken
+	}
+
+	if !hasField(apiKey.Scopes, scope) {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeAPIKey, metrics.TokenResultInsufficientScope).Inc()
+		return repository.Profile{}, apperrors.ErrAPIKeyScope
+	}
+	metrics.TokenValidations.WithLabelValues(metrics.TokenTypeAPIKey, metrics.TokenResultValid).Inc()
+
+	now := time.Now().Format(utils.TimestampLayout)
+	err = s.Repository.UpdateAPIKey(ctx.Request().Context(), repository.APIKeyFilter{
+		KeyID: &apiKey.KeyId,
+	}, repository.APIKeyPatch{
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		return repository.Profile{}, err
+	}
+
+	return resGetProfile[0], nil
+}
+
+func apiKeyUsable(apiKey repository.APIKey, key string) bool {
+	if apiKey.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(utils.HashSecret(key)), []byte(apiKey.SecretHash)) != 1 {
+		return false
+	}
+	if apiKey.Expires == nil {
+		return true
+	}
+
+	expires, err := utils.ParseTimestamp(*apiKey.Expires)
+	return err == nil && time.Now().Before(expires)
+}
+
+func apiKeyResponse(apiKey repository.APIKey) generated.APIKeyResponse {
+	return generated.APIKeyResponse{
+		Id:         apiKey.KeyId,
+		Name:       apiKey.Name,
+		Prefix:     apiKeyMarker + apiKey.Prefix,
+		Scopes:     fields(apiKey.Scopes),
+		ExpiresAt:  rfc3339(apiKey.Expires),
+		LastUsedAt: rfc3339(apiKey.LastUsedAt),
+		RevokedAt:  rfc3339(apiKey.RevokedAt),
+		CreatedAt:  *rfc3339(&apiKey.CreatedAt),
+	}
+}
+
+// rfc3339 formats a nullable timestamp read from the database for a
+// response, passing it through unchanged when it cannot be parsed.
+func rfc3339(timestamp *string) *string {
+	if timestamp == nil {
+		return nil
+	}
+
+	output := *timestamp
+	if parsed, err := utils.ParseTimestamp(*timestamp); err == nil {
+		output = parsed.Format(time.RFC3339)
+	}
+	return &output
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	newServer := func() (*Server, string) {
+		server := newTestServer(NewServerOptions{})
+		_, session := createTestUser(t, server, repository.Profile{
+			FullName: "Data Team",
+			Phone:    "+6281234567890",
+			Role:     repository.RoleUser,
+		})
+		return server, session
+	}
+
+	createKey := func(server *Server, session, body string) generated.APIKeyResponse {
+		c, rec := newTestContext(echo.POST, "http://localhost:1323/api-keys", body)
+		err := server.CreateApiKey(c, generated.CreateApiKeyParams{
+			Authorization: session,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var res generated.APIKeyResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+
+	getProfile := func(server *Server, authorization string) (*httptest.ResponseRecorder, error) {
+		c, rec := newTestContext(echo.GET, "http://localhost:1323/profile", "")
+		return rec, server.GetProfile(c, generated.GetProfileParams{
+			Authorization: authorization,
+		})
+	}
+
+	t.Run("Positive Scenario, Key reads the profile until it is revoked", func(t *testing.T) {
+		server, session := newServer()
+		created := createKey(server, session, `{"name": "Nightly export", "scopes": ["profile:read"], "expiresInDays": 30}`)
+		if !assert.NotNil(t, created.Key) {
+			return
+		}
+		assert.True(t, strings.HasPrefix(*created.Key, created.Prefix+"_"))
+		assert.NotNil(t, created.ExpiresAt)
+
+		rec, err := getProfile(server, "Bearer "+*created.Key)
+		assert.NoError(t, err)
+		var profile generated.ProfileResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+		assert.Equal(t, "Data Team", profile.FullName)
+
+		c, rec := newTestContext(echo.GET, "http://localhost:1323/api-keys", "")
+		err = server.ListApiKeys(c, generated.ListApiKeysParams{
+			Authorization: session,
+		})
+		assert.NoError(t, err)
+		var list generated.APIKeyListResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+		if assert.Len(t, list.ApiKeys, 1) {
+			assert.Nil(t, list.ApiKeys[0].Key)
+			assert.NotNil(t, list.ApiKeys[0].LastUsedAt)
+		}
+
+		c, rec = newTestContext(echo.DELETE, "http://localhost:1323/api-keys", "")
+		err = server.RevokeApiKey(c, created.Id, generated.RevokeApiKeyParams{
+			Authorization: session,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		_, err = getProfile(server, "Bearer "+*created.Key)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("Negative Scenario, Key is limited to its scopes", func(t *testing.T) {
+		server, session := newServer()
+		created := createKey(server, session, `{"name": "Dashboard", "scopes": ["profile:read"]}`)
+
+		c, _ := newTestContext(echo.PATCH, "http://localhost:1323/profile", `{"fullName": "Renamed"}`)
+		err := server.UpdateProfile(c, generated.UpdateProfileParams{
+			Authorization: "Bearer " + *created.Key,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrAPIKeyScope)
+
+		// API keys cannot mint more keys.
+		c, _ = newTestContext(echo.POST, "http://localhost:1323/api-keys", `{"name": "Escalated", "scopes": ["admin"]}`)
+		err = server.CreateApiKey(c, generated.CreateApiKeyParams{
+			Authorization: "Bearer " + *created.Key,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+
+		_, err = getProfile(server, "Bearer "+created.Prefix+"_wrong")
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+}
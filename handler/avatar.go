@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/avatar"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// avatarFormOverhead is the room left in upload requests for the multipart
+// headers around the photo.
+const avatarFormOverhead = 64 << 10
+
+// UploadAvatar stores the thumbnails of a new avatar under a new key, so
+// clients caching the previous ones by URL see the change, and then deletes
+// the previous avatar.
+func (s *Server) UploadAvatar(ctx echo.Context, params generated.UploadAvatarParams) error {
+	profile, err := s.authenticate(ctx, params.Authorization, apiKeyScopeProfileWrite)
+	if err != nil {
+		return err
+	}
+
+	middlewares.SetLocale(ctx, profile.Locale)
+
+	req := ctx.Request()
+	req.Body = http.MaxBytesReader(ctx.Response(), req.Body, avatar.MaxBytes+avatarFormOverhead)
+	fileHeader, err := ctx.FormFile("avatar")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return apperrors.ErrAvatarTooLarge
+	}
+	if err != nil {
+		return apperrors.ErrAvatarRequired.Wrap(err)
+	}
+
+	if fileHeader.Size > avatar.MaxBytes {
+		return apperrors.ErrAvatarTooLarge
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	thumbnails, err := avatar.Process(data)
+	if err != nil {
+		return err
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(req.Context(), repository.ProfileFilter{
+		UserID: &profile.UserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetProfile) == 0 {
+		return apperrors.ErrInvalidToken
+	}
+
+	version, _, err := utils.NewSecret(8)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("avatars/%d/%s", profile.UserId, version)
+	for _, thumbnail := range thumbnails {
+		err := s.BlobStore.Put(req.Context(), avatarThumbnailKey(key, thumbnail.Size), avatar.ContentType, bytes.NewReader(thumbnail.Data))
+		if err != nil {
+			s.deleteAvatar(ctx, key)
+			return err
+		}
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	err = s.Repository.UpdateProfile(req.Context(), repository.ProfileFilter{
+		UserID: &profile.UserId,
+	}, repository.ProfilePatch{
+		AvatarKey: &key,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		s.deleteAvatar(ctx, key)
+		return err
+	}
+
+	if previous := resGetProfile[0].AvatarKey; previous != nil {
+		s.deleteAvatar(ctx, *previous)
+	}
+
+	url, thumbnailURLs := s.avatarURLs(key)
+	return ctx.JSON(200, generated.AvatarResponse{
+		AvatarUrl:        url,
+		AvatarThumbnails: thumbnailURLs,
+	})
+}
+
+// avatarURLs returns the URL of the largest thumbnail of an avatar, and the
+// URLs of all of them by size.
+func (s *Server) avatarURLs(key string) (string, generated.AvatarThumbnails) {
+	thumbnails := generated.AvatarThumbnails{}
+	for _, size := range avatar.Sizes {
+		thumbnails[strconv.Itoa(size)] = s.BlobStore.URL(avatarThumbnailKey(key, size))
+	}
+	return s.BlobStore.URL(avatarThumbnailKey(key, avatar.Sizes[len(avatar.Sizes)-1])), thumbnails
+}
+
+// deleteAvatar deletes the thumbnails of an avatar. Failures only leave
+// unused files behind, so they are logged. The thumbnails are deleted even
+// when the client has gone away.
+func (s *Server) deleteAvatar(ctx echo.Context, key string) {
+	for _, size := range avatar.Sizes {
+		if err := s.BlobStore.Delete(context.Background(), avatarThumbnailKey(key, size)); err != nil {
+			ctx.Logger().Errorf("deleting avatar %s failed: %v", key, err)
+		}
+	}
+}
+
+func avatarThumbnailKey(key string, size int) string {
+	return fmt.Sprintf("%s/%d.jpg", key, size)
+}
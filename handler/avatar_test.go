@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/avatar"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/storage"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadAvatar(t *testing.T) {
+	newFixture := func() (*echo.Echo, *storage.MemoryStore, string) {
+		store := storage.NewMemoryStore()
+		server := newTestServer(NewServerOptions{BlobStore: store})
+		_, session := createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567891",
+			Role:     repository.RoleUser,
+		})
+		return newTestRouter(server, middlewares.ValidateContentType()), store, session
+	}
+
+	upload := func(e *echo.Echo, session, field string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile(field, "photo.png")
+		assert.NoError(t, err)
+		_, err = part.Write(data)
+		assert.NoError(t, err)
+		assert.NoError(t, form.Close())
+
+		req := httptest.NewRequest(echo.PUT, "/profile/avatar", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		req.Header.Set(echo.HeaderAuthorization, session)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var photo bytes.Buffer
+	assert.NoError(t, png.Encode(&photo, image.NewGray(image.Rect(0, 0, 300, 200))))
+
+	t.Run("Positive Scenario, The avatar replaces the previous one", func(t *testing.T) {
+		e, store, session := newFixture()
+
+		rec := upload(e, session, "avatar", photo.Bytes())
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var first generated.AvatarResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &first))
+		assert.Len(t, first.AvatarThumbnails, len(avatar.Sizes))
+		assert.Equal(t, first.AvatarThumbnails["256"], first.AvatarUrl)
+		assert.Len(t, store.Blobs(), len(avatar.Sizes))
+
+		rec = upload(e, session, "avatar", photo.Bytes())
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var second generated.AvatarResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &second))
+		assert.NotEqual(t, first.AvatarUrl, second.AvatarUrl)
+
+		blobs := store.Blobs()
+		assert.Len(t, blobs, len(avatar.Sizes))
+		for _, url := range second.AvatarThumbnails {
+			blob, ok := blobs[strings.TrimPrefix(url, "/blobs/")]
+			if assert.True(t, ok, url) {
+				assert.Equal(t, avatar.ContentType, blob.ContentType)
+			}
+		}
+
+		req := httptest.NewRequest(echo.GET, "/profile", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, session)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var profile generated.ProfileResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+		if assert.NotNil(t, profile.AvatarUrl) {
+			assert.Equal(t, second.AvatarUrl, *profile.AvatarUrl)
+		}
+	})
+
+	t.Run("Negative Scenario, Invalid uploads are rejected", func(t *testing.T) {
+		e, store, session := newFixture()
+
+		rec := upload(e, session, "avatar", []byte("#!/bin/sh\necho hello\n"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+		rec = upload(e, session, "photo", photo.Bytes())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = upload(e, session, "avatar", append(photo.Bytes(), make([]byte, avatar.MaxBytes)...))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		req := httptest.NewRequest(echo.PUT, "/profile/avatar", bytes.NewReader(photo.Bytes()))
+		req.Header.Set(echo.HeaderContentType, "image/png")
+		req.Header.Set(echo.HeaderAuthorization, session)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		assert.Empty(t, store.Blobs())
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+)
+
+// emailVerificationTTL is how long a verification token can be used.
+const emailVerificationTTL = 24 * time.Hour
+
+// emailVerification is a pending verification of an email address. Only the
+// hash is stored, the token is sent to the address.
+type emailVerification struct {
+	email   string
+	token   string
+	hash    string
+	expires string
+}
+
+// normalizeEmail makes emails compare regardless of case and surrounding
+// spaces, matching the unique index on LOWER(email) of verified addresses.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// verifiedEmailHolder returns the user among profiles who verified their
+// address. Several users may have typed the same address, as anyone can
+// type one they do not own, but only one of them can verify it.
+func verifiedEmailHolder(profiles []repository.Profile) (repository.Profile, bool) {
+	for _, profile := range profiles {
+		if profile.EmailVerifiedAt != nil {
+			return profile, true
+		}
+	}
+	return repository.Profile{}, false
+}
+
+func newEmailVerification(email string) (emailVerification, error) {
+	token, hash, err := utils.NewSecret(32)
+	if err != nil {
+		return emailVerification{}, err
+	}
+
+	return emailVerification{
+		email:   email,
+		token:   token,
+		hash:    hash,
+		expires: time.Now().Add(emailVerificationTTL).Format(utils.TimestampLayout),
+	}, nil
+}
+
+// apply adds the columns that change the address to patch, marking it as
+// unverified until the new token is used.
+func (v *emailVerification) apply(patch *repository.ProfilePatch) {
+	patch.Email = &v.email
+	patch.ClearEmailVerifiedAt = true
+	patch.EmailVerificationHash = &v.hash
+	patch.EmailVerificationExpires = &v.expires
+}
+
+// sendEmailVerification mails the token in the user's language. The user
+// has already been saved, so a failure is only logged and the user can
+// request a new token by updating the address.
+func (s *Server) sendEmailVerification(ctx context.Context, locale string, verification emailVerification) error {
+	subject, _ := i18n.Lookup(locale, "email.verification.subject", nil)
+	body, _ := i18n.Lookup(locale, "email.verification.body", map[string]string{
+		"token": verification.token,
+	})
+
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      verification.email,
+		Subject: subject,
+		Body:    body,
+	})
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/mail"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var emailTokenRegex = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func TestVerifyEmail(t *testing.T) {
+	newServer := func() (*Server, *mail.FakeSender) {
+		mailer := mail.NewFakeSender()
+		server := newTestServer(NewServerOptions{Mailer: mailer})
+		return server, mailer
+	}
+
+	register := func(server *Server, body string) error {
+		c, _ := newTestContext(echo.POST, "http://localhost:1323/register", body)
+		return server.Register(c)
+	}
+
+	verify := func(server *Server, token string) error {
+		c, _ := newTestContext(echo.POST, "http://localhost:1323/email/verify", `{"token": "`+token+`"}`)
+		return server.VerifyEmail(c)
+	}
+
+	lastToken := func(mailer *mail.FakeSender) string {
+		messages := mailer.Messages()
+		if !assert.NotEmpty(t, messages) {
+			return ""
+		}
+		return emailTokenRegex.FindString(messages[len(messages)-1].Body)
+	}
+
+	profile := func(server *Server) repository.Profile {
+		phoneNumber := "+6281234567890"
+		profiles, err := server.Repository.GetProfile(context.Background(), repository.ProfileFilter{Phone: &phoneNumber})
+		assert.NoError(t, err)
+		assert.Len(t, profiles, 1)
+		return profiles[0]
+	}
+
+	const body = `{"fullName": "Field Worker", "phoneNumber": "+6281234567890", "password": "fieldW0rker!", "email": "Worker@Example.com"}`
+
+	t.Run("Positive Scenario, The mailed token verifies the address once", func(t *testing.T) {
+		server, mailer := newServer()
+
+		assert.NoError(t, register(server, body))
+		messages := mailer.Messages()
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, "worker@example.com", messages[0].To)
+		}
+		token := lastToken(mailer)
+		assert.NotEmpty(t, token)
+		assert.Nil(t, profile(server).EmailVerifiedAt)
+
+		assert.NoError(t, verify(server, token))
+		assert.NotNil(t, profile(server).EmailVerifiedAt)
+
+		assert.ErrorIs(t, verify(server, token), apperrors.ErrInvalidVerificationToken)
+	})
+
+	t.Run("Negative Scenario, An expired token is refused", func(t *testing.T) {
+		server, mailer := newServer()
+
+		assert.NoError(t, register(server, body))
+		token := lastToken(mailer)
+
+		userID := profile(server).UserId
+		expired := time.Now().Add(-time.Minute).Format(utils.TimestampLayout)
+		err := server.Repository.UpdateProfile(context.Background(), repository.ProfileFilter{UserID: &userID}, repository.ProfilePatch{
+			EmailVerificationExpires: &expired,
+		})
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, verify(server, token), apperrors.ErrInvalidVerificationToken)
+		assert.Nil(t, profile(server).EmailVerifiedAt)
+	})
+
+	t.Run("Negative Scenario, A failed mail does not fail the registration", func(t *testing.T) {
+		server, mailer := newServer()
+		mailer.Err = assert.AnError
+
+		assert.NoError(t, register(server, body))
+		assert.NotNil(t, profile(server).EmailVerificationHash)
+	})
+}
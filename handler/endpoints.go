@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"github.com/SawitProRecruitment/UserService/apperrors"
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/middlewares"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/SawitProRecruitment/UserService/utils"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,85 +16,265 @@ import (
 	"time"
 )
 
+// dummyPasswordHash is compared with the password of unknown users, so that
+// rejecting them takes as long as rejecting a wrong password. It has the
+// cost of utils.HashPassword, and the result of the comparison is ignored.
+const dummyPasswordHash = "$2a$14$gW4b8swlLI4B9Fp92Z.9/OtzlTbHn3xDWPP2WpTpVoHqjvgVzwmrS"
+
 func (s *Server) Login(ctx echo.Context) error {
 	var req *generated.LoginRequest
 	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
 	if err != nil {
-		return err
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonInvalidRequest, apperrors.ErrBadRequest.Wrap(err))
 	}
 
 	err = s.Validator.Validate(req)
 	if err != nil {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonInvalidRequest, validationError(err))
 	}
 
-	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), map[string]interface{}{
-		"phone": req.PhoneNumber,
-	})
+	filterGetProfile, ok := s.loginFilter(req)
+	if !ok {
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonUnknownUser, apperrors.ErrInvalidCredentials)
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), filterGetProfile)
 	if err != nil {
-		return ctx.JSON(500, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonError, err)
 	}
 
+	// Of the users who typed the address, the one who verified it logs in.
+	if holder, ok := verifiedEmailHolder(resGetProfile); ok && filterGetProfile.Email != nil {
+		resGetProfile = []repository.Profile{holder}
+	}
+
+	// An unknown phone number or email and a wrong password get the same
+	// error after the same bcrypt comparison, so the endpoint cannot be used
+	// to find out who is registered.
 	if len(resGetProfile) == 0 {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: fmt.Sprintf("User with phone number %s not found", req.PhoneNumber),
-		})
+		checkPasswordHash(req.Password, dummyPasswordHash)
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonUnknownUser, apperrors.ErrInvalidCredentials)
 	}
 
-	if !utils.CheckPasswordHash(req.Password, resGetProfile[0].Password) {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: fmt.Sprintf("Invalid password"),
-		})
+	if !checkPasswordHash(req.Password, resGetProfile[0].Password) {
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonWrongPassword, apperrors.ErrInvalidCredentials)
 	}
 
-	filterGetLoginData := map[string]interface{}{
-		"user_id": resGetProfile[0].UserId,
+	// Anyone can type an address they do not own, so only verified ones
+	// identify the user.
+	if filterGetProfile.Email != nil && resGetProfile[0].EmailVerifiedAt == nil {
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonUnverifiedEmail, apperrors.ErrInvalidCredentials)
 	}
 
-	resGetLogin, err := s.Repository.GetLogin(ctx.Request().Context(), filterGetLoginData)
+	// The saved locale is only used once the credentials are accepted, as
+	// failures in the user's language would tell who is registered.
+	middlewares.SetLocale(ctx, resGetProfile[0].Locale)
+
+	// The login row is read and then inserted or updated in one transaction,
+	// so concurrent logins of the same user cannot both insert a row.
+	var jwtToken string
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		token, err := s.issueToken(ctx, repo, resGetProfile[0])
+		jwtToken = token
+		return err
+	})
 	if err != nil {
-		return ctx.JSON(500, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return loginFailure(metrics.LoginMethodPassword, metrics.LoginReasonError, err)
 	}
 
-	jwtToken, expiresAt, _ := utils.GenerateToken(resGetProfile[0])
+	metrics.LoginSuccesses.WithLabelValues(metrics.LoginMethodPassword).Inc()
+	return ctx.JSON(200, generated.LoginResponse{
+		Message: "success",
+		Token:   jwtToken,
+		UserID:  int(resGetProfile[0].UserId),
+	})
+}
 
-	if len(resGetLogin) == 0 {
-		_, err = s.Repository.InsertIntoLogin(ctx.Request().Context(), repository.LoginModel{
-			UserId:   resGetProfile[0].UserId,
-			Ip:       ctx.Request().RemoteAddr,
-			Token:    jwtToken,
-			Expires:  expiresAt,
-			Requests: 0,
+func (s *Server) StartOtpLogin(ctx echo.Context) error {
+	var req *generated.OtpStartRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	phoneNumber, err := s.normalizePhone(req.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	// Failing before the lookup answers registered and unregistered numbers
+	// alike.
+	if s.smsDisabled() {
+		return apperrors.ErrSMSDisabled
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		Phone: &phoneNumber,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Requests are counted by phone number and answered in the request's
+	// locale whether or not the number is registered, and only registered
+	// numbers are sent a code, so the endpoint cannot be used to find out
+	// who is registered. Counting the recent requests and recording a new
+	// one in one transaction keeps concurrent requests from exceeding the
+	// limit together.
+	var code string
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		now := time.Now()
+		since := now.Add(-otpRateWindow).Format(utils.TimestampLayout)
+		resGetOTPRequest, err := repo.GetOTPRequest(ctx.Request().Context(), repository.OTPRequestFilter{
+			Phone:        &phoneNumber,
+			CreatedAfter: &since,
 		})
-	} else {
-		updatedData := map[string]interface{}{
-			"ip":         ctx.Request().RemoteAddr,
-			"requests":   resGetLogin[0].Requests + 1,
-			"updated_at": time.Now(),
+		if err != nil {
+			return err
 		}
 
-		if resGetLogin[0].Expires < time.Now().Format("2006-01-02 15:04:05") {
-			updatedData["token"] = jwtToken
-			updatedData["expires"] = expiresAt
-		} else {
-			jwtToken = resGetLogin[0].Token
+		if len(resGetOTPRequest) >= otpRateLimit {
+			return apperrors.ErrTooManyRequests
+		}
+
+		_, err = repo.CreateOTPRequest(ctx.Request().Context(), repository.OTPRequest{
+			Phone:     phoneNumber,
+			CreatedAt: now.Format(utils.TimestampLayout),
+		})
+		if err != nil || len(resGetProfile) == 0 {
+			return err
 		}
 
-		err = s.Repository.UpdateLogin(ctx.Request().Context(), filterGetLoginData, updatedData)
+		code, err = newOTPCode()
+		if err != nil {
+			return err
+		}
+
+		_, err = repo.CreateLoginOTP(ctx.Request().Context(), repository.LoginOTP{
+			UserId:    resGetProfile[0].UserId,
+			CodeHash:  s.hashOTP(code),
+			Expires:   now.Add(otpTTL).Format(utils.TimestampLayout),
+			CreatedAt: now.Format(utils.TimestampLayout),
+		})
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
+	if len(resGetProfile) > 0 {
+		err = s.sendOTP(ctx.Request().Context(), smsLocale(ctx, resGetProfile[0]), phoneNumber, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.JSON(200, generated.MessageResponse{
+		Message: "success",
+	})
+}
+
+func (s *Server) VerifyOtpLogin(ctx echo.Context) error {
+	var req *generated.OtpVerifyRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonInvalidRequest, apperrors.ErrBadRequest.Wrap(err))
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonInvalidRequest, validationError(err))
+	}
+
+	phoneNumber, err := s.PhoneNormalizer.Normalize(req.PhoneNumber)
+	if err != nil {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonUnknownUser, apperrors.ErrInvalidOTP)
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		Phone: &phoneNumber,
+	})
 	if err != nil {
-		return ctx.JSON(500, generated.ErrorResponse{
-			Message: err.Error(),
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonError, err)
+	}
+
+	if len(resGetProfile) == 0 {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonUnknownUser, apperrors.ErrInvalidOTP)
+	}
+	middlewares.SetLocale(ctx, resGetProfile[0].Locale)
+
+	// A wrong code must still record the attempt, so it is reported after
+	// the transaction commits instead of by failing it.
+	var (
+		jwtToken string
+		rejected bool
+	)
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetLoginOTP, err := repo.GetLoginOTP(ctx.Request().Context(), repository.LoginOTPFilter{
+			UserID: &resGetProfile[0].UserId,
 		})
+		if err != nil {
+			return err
+		}
+
+		// Only the most recent code is valid.
+		if len(resGetLoginOTP) == 0 || !otpUsable(resGetLoginOTP[len(resGetLoginOTP)-1]) {
+			rejected = true
+			return nil
+		}
+
+		otp := resGetLoginOTP[len(resGetLoginOTP)-1]
+		filterOTP := repository.LoginOTPFilter{
+			OtpID: &otp.OtpId,
+		}
+		attempts := otp.Attempts + 1
+
+		if !s.otpMatches(otp, req.Code) {
+			rejected = true
+			return repo.UpdateLoginOTP(ctx.Request().Context(), filterOTP, repository.LoginOTPPatch{
+				Attempts: &attempts,
+			})
+		}
+
+		now := time.Now().Format(utils.TimestampLayout)
+		err = repo.UpdateLoginOTP(ctx.Request().Context(), filterOTP, repository.LoginOTPPatch{
+			Attempts:   &attempts,
+			ConsumedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Receiving the code proves the user owns the phone number.
+		if resGetProfile[0].PhoneVerifiedAt == nil {
+			err = repo.UpdateProfile(ctx.Request().Context(), repository.ProfileFilter{
+				UserID: &resGetProfile[0].UserId,
+			}, repository.ProfilePatch{
+				PhoneVerifiedAt: &now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		jwtToken, err = s.issueToken(ctx, repo, resGetProfile[0])
+		return err
+	})
+	if err != nil {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonError, err)
+	}
+
+	if rejected {
+		return loginFailure(metrics.LoginMethodOTP, metrics.LoginReasonInvalidCode, apperrors.ErrInvalidOTP)
 	}
 
+	metrics.LoginSuccesses.WithLabelValues(metrics.LoginMethodOTP).Inc()
+
 	return ctx.JSON(200, generated.LoginResponse{
 		Message: "success",
 		Token:   jwtToken,
@@ -99,73 +283,138 @@ func (s *Server) Login(ctx echo.Context) error {
 }
 
 func (s *Server) GetProfile(ctx echo.Context, params generated.GetProfileParams) error {
-	claims, err := utils.ValidateToken(params.Authorization)
+	profile, err := s.authenticate(ctx, params.Authorization, apiKeyScopeProfileRead)
 	if err != nil {
-		return ctx.JSON(403, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return err
 	}
 
-	mapClaims := claims.(jwt.MapClaims)
-	return ctx.JSON(200, generated.ProfileResponse{
-		FullName:    mapClaims["FullName"].(string),
+	middlewares.SetLocale(ctx, profile.Locale)
+
+	attributes, err := profileAttributes(ctx.Request().Context(), s.Repository, profile.UserId, false)
+	if err != nil {
+		return err
+	}
+
+	res := generated.ProfileResponse{
+		Attributes:  (*generated.ProfileAttributes)(&attributes),
+		CreatedAt:   *rfc3339(&profile.CreatedAt),
+		Email:       profile.Email,
+		FullName:    profile.FullName,
+		Locale:      &profile.Locale,
 		Message:     "success",
-		PhoneNumber: mapClaims["Phone"].(string),
-	})
+		PhoneNumber: profile.Phone,
+		Status:      profile.Status,
+		UpdatedAt:   *rfc3339(&profile.UpdatedAt),
+		UserID:      profile.UserId,
+	}
+
+	if profile.AvatarKey != nil {
+		url, thumbnails := s.avatarURLs(*profile.AvatarKey)
+		res.AvatarUrl = &url
+		res.AvatarThumbnails = &thumbnails
+	}
+
+	return ctx.JSON(200, res)
 }
 
 func (s *Server) UpdateProfile(ctx echo.Context, params generated.UpdateProfileParams) error {
 	var req *generated.UpdateProfileRequest
-	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	// Numbers are kept as json.Number for the profile attributes.
+	decoder := json.NewDecoder(ctx.Request().Body)
+	decoder.UseNumber()
+	err := decoder.Decode(&req)
 	if err != nil {
-		return err
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	// The token is read before validating so that validation messages are
+	// in the user's language, but a bad body is still reported first.
+	profile, tokenErr := s.authenticate(ctx, params.Authorization, apiKeyScopeProfileWrite)
+	if tokenErr == nil {
+		middlewares.SetLocale(ctx, profile.Locale)
 	}
 
 	err = s.Validator.Validate(req)
 	if err != nil {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return validationError(err)
 	}
 
-	claims, err := utils.ValidateToken(params.Authorization)
-	if err != nil {
-		return ctx.JSON(403, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+	if tokenErr != nil {
+		return tokenErr
 	}
 
-	mapClaims := claims.(jwt.MapClaims)
-	var userID float64
-	if _, ok := mapClaims["UserId"]; ok {
-		userID = mapClaims["UserId"].(float64)
+	// The phone number and email log the user in, so changing them would
+	// hand the account to whoever impersonates the user.
+	if (req.PhoneNumber != nil || req.Email != nil) && impersonated(params.Authorization) {
+		return apperrors.ErrImpersonationForbidden
 	}
 
-	updatedBy := map[string]interface{}{
-		"user_id": userID,
+	var phoneNumber *string
+	if req.PhoneNumber != nil {
+		normalized, err := s.normalizePhone(*req.PhoneNumber)
+		if err != nil {
+			return err
+		}
+		phoneNumber = &normalized
 	}
 
-	updatedData := map[string]interface{}{
-		"updated_at": time.Now().Format("2006-01-02 15:04:05"),
+	userID := profile.UserId
+	updatedBy := repository.ProfileFilter{
+		UserID: &userID,
 	}
 
-	if req.FullName != nil {
-		updatedData["full_name"] = req.FullName
+	now := time.Now().Format("2006-01-02 15:04:05")
+	updatedData := repository.ProfilePatch{
+		FullName:             req.FullName,
+		Phone:                phoneNumber,
+		Locale:               req.Locale,
+		UpdatedAt:            &now,
+		ClearPhoneVerifiedAt: phoneNumber != nil,
 	}
 
-	if req.PhoneNumber != nil {
-		updatedData["phone"] = req.PhoneNumber
+	var verification *emailVerification
+	if req.Email != nil {
+		newVerification, err := newEmailVerification(normalizeEmail(*req.Email))
+		if err != nil {
+			return err
+		}
+
+		verification = &newVerification
+		verification.apply(&updatedData)
 	}
 
-	err = s.Repository.UpdateProfile(ctx.Request().Context(), updatedBy, updatedData)
+	// The attributes are saved with the rest of the profile, or not at all.
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		if verification != nil {
+			resGetProfile, err := repo.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+				Email: &verification.email,
+			})
+			if err != nil {
+				return err
+			}
+
+			if holder, ok := verifiedEmailHolder(resGetProfile); ok && holder.UserId != userID {
+				return apperrors.ErrEmailExists
+			}
+		}
+
+		err := repo.UpdateProfile(ctx.Request().Context(), updatedBy, updatedData)
+		if err != nil || req.Attributes == nil {
+			return err
+		}
+		return s.setProfileAttributes(ctx.Request().Context(), repo, userID, *req.Attributes, false)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return conflictError(err, phoneNumber != nil, verification != nil)
+	}
 	if err != nil {
-		code := 500
-		if strings.Contains(err.Error(), "SQLSTATE 23505") {
-			code = 409
+		return err
+	}
+
+	if verification != nil {
+		if err := s.sendEmailVerification(ctx.Request().Context(), middlewares.GetLocale(ctx), *verification); err != nil {
+			ctx.Logger().Errorf("sending the email verification of user %d failed: %v", userID, err)
 		}
-		return ctx.JSON(code, generated.ErrorResponse{
-			Message: err.Error(),
-		})
 	}
 
 	return ctx.JSON(200, generated.ErrorResponse{
@@ -175,52 +424,443 @@ func (s *Server) UpdateProfile(ctx echo.Context, params generated.UpdateProfileP
 
 func (s *Server) Register(ctx echo.Context) error {
 	var req *generated.RegisterRequest
-	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	// Numbers are kept as json.Number for the profile attributes.
+	decoder := json.NewDecoder(ctx.Request().Body)
+	decoder.UseNumber()
+	err := decoder.Decode(&req)
 	if err != nil {
-		return err
+		return apperrors.ErrBadRequest.Wrap(err)
 	}
 
 	err = s.Validator.Validate(req)
 	if err != nil {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: err.Error(),
+		return validationError(err)
+	}
+
+	phoneNumber, err := s.normalizePhone(req.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, _ := hashPassword(req.Password)
+
+	var locale string
+	if req.Locale != nil {
+		locale = *req.Locale
+	}
+	middlewares.SetLocale(ctx, locale)
+
+	var verification *emailVerification
+	if req.Email != nil {
+		newVerification, err := newEmailVerification(normalizeEmail(*req.Email))
+		if err != nil {
+			return err
+		}
+		verification = &newVerification
+	}
+
+	// Checking the phone number and email and creating the user in one
+	// transaction keeps two concurrent registrations from both passing the
+	// checks.
+	var resCreateProfile repository.Profile
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetProfile, err := repo.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+			Phone: &phoneNumber,
 		})
+		if err != nil {
+			return err
+		}
+
+		if len(resGetProfile) > 0 {
+			return apperrors.ErrPhoneNumberExists
+		}
+
+		now := time.Now().Format("2006-01-02 15:04:05")
+		profile := repository.Profile{
+			FullName:  req.FullName,
+			Password:  passwordHash,
+			Phone:     phoneNumber,
+			Locale:    locale,
+			Role:      repository.RoleUser,
+			Status:    1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if verification != nil {
+			resGetProfile, err = repo.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+				Email: &verification.email,
+			})
+			if err != nil {
+				return err
+			}
+
+			// Addresses that nobody verified yet can be claimed again.
+			if _, ok := verifiedEmailHolder(resGetProfile); ok {
+				return apperrors.ErrEmailExists
+			}
+
+			profile.Email = &verification.email
+			profile.EmailVerificationHash = &verification.hash
+			profile.EmailVerificationExpires = &verification.expires
+		}
+
+		resCreateProfile, err = repo.CreateProfile(ctx.Request().Context(), profile)
+		if err != nil || req.Attributes == nil {
+			return err
+		}
+		return s.setProfileAttributes(ctx.Request().Context(), repo, resCreateProfile.UserId, *req.Attributes, false)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return conflictError(err, true, verification != nil)
+	}
+	if err != nil {
+		return err
 	}
 
-	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), map[string]interface{}{
-		"phone": req.PhoneNumber,
+	if verification != nil {
+		if err := s.sendEmailVerification(ctx.Request().Context(), middlewares.GetLocale(ctx), *verification); err != nil {
+			ctx.Logger().Errorf("sending the email verification of user %d failed: %v", resCreateProfile.UserId, err)
+		}
+	}
+
+	metrics.Registrations.Inc()
+	return ctx.JSON(200, generated.RegisterResponse{
+		Message: "success",
+		UserID:  int(resCreateProfile.UserId),
 	})
+}
+
+func (s *Server) VerifyEmail(ctx echo.Context) error {
+	var req *generated.VerifyEmailRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
 	if err != nil {
-		return ctx.JSON(400, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return apperrors.ErrBadRequest.Wrap(err)
 	}
 
-	if len(resGetProfile) > 0 {
-		return ctx.JSON(409, generated.ErrorResponse{
-			Message: "phone number already exists",
-		})
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	hash := utils.HashSecret(req.Token)
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		EmailVerificationHash: &hash,
+	})
+	if err != nil {
+		return err
 	}
 
-	hashPassword, _ := utils.HashPassword(req.Password)
+	if len(resGetProfile) == 0 || resGetProfile[0].EmailVerificationExpires == nil {
+		return apperrors.ErrInvalidVerificationToken
+	}
+	middlewares.SetLocale(ctx, resGetProfile[0].Locale)
 
+	expires, err := utils.ParseTimestamp(*resGetProfile[0].EmailVerificationExpires)
+	if err != nil || time.Now().After(expires) {
+		return apperrors.ErrInvalidVerificationToken
+	}
+
+	// Filtering on the hash as well keeps a concurrent change of the
+	// address from being marked as verified by the old token.
 	now := time.Now().Format("2006-01-02 15:04:05")
-	resCreateProfile, err := s.Repository.CreateProfile(ctx.Request().Context(), repository.Profile{
-		FullName:  req.FullName,
-		Password:  hashPassword,
-		Phone:     req.PhoneNumber,
-		Status:    1,
-		CreatedAt: now,
-		UpdatedAt: now,
+	err = s.Repository.UpdateProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID:                &resGetProfile[0].UserId,
+		EmailVerificationHash: &hash,
+	}, repository.ProfilePatch{
+		EmailVerifiedAt:        &now,
+		ClearEmailVerification: true,
+		UpdatedAt:              &now,
 	})
+	// Another user verified the address first.
+	if errors.Is(err, repository.ErrConflict) {
+		return apperrors.ErrEmailExists.Wrap(err)
+	}
 	if err != nil {
-		return ctx.JSON(500, generated.ErrorResponse{
-			Message: err.Error(),
-		})
+		return err
 	}
 
-	return ctx.JSON(200, generated.RegisterResponse{
+	return ctx.JSON(200, generated.MessageResponse{
 		Message: "success",
-		UserID:  int(resCreateProfile.UserId),
 	})
 }
+
+// issueToken records a login of profile and returns its token, reusing the
+// previous token while it has not expired. It must run inside RunInTx.
+func (s *Server) issueToken(ctx echo.Context, repo repository.RepositoryInterface, profile repository.Profile) (string, error) {
+	filterGetLoginData := repository.LoginFilter{
+		UserID: &profile.UserId,
+	}
+
+	resGetLogin, err := repo.GetLogin(ctx.Request().Context(), filterGetLoginData)
+	if err != nil {
+		return "", err
+	}
+
+	newToken, expiresAt, _ := utils.GenerateToken(profile)
+
+	if len(resGetLogin) == 0 {
+		_, err = repo.InsertIntoLogin(ctx.Request().Context(), repository.LoginModel{
+			UserId:   profile.UserId,
+			Ip:       ctx.Request().RemoteAddr,
+			Token:    newToken,
+			Expires:  expiresAt,
+			Requests: 0,
+		})
+		return newToken, err
+	}
+
+	ip := ctx.Request().RemoteAddr
+	requests := resGetLogin[0].Requests + 1
+	now := time.Now().Format("2006-01-02 15:04:05")
+	updatedData := repository.LoginPatch{
+		Ip:        &ip,
+		Requests:  &requests,
+		UpdatedAt: &now,
+	}
+
+	// Expiries that cannot be parsed are replaced like expired ones.
+	jwtToken := resGetLogin[0].Token
+	expires, err := utils.ParseTimestamp(resGetLogin[0].Expires)
+	if err != nil || !time.Now().Before(expires) {
+		jwtToken = newToken
+		updatedData.Token = &newToken
+		updatedData.Expires = &expiresAt
+	}
+
+	return jwtToken, repo.UpdateLogin(ctx.Request().Context(), filterGetLoginData, updatedData)
+}
+
+// replaceToken records token as the session of the user, replacing the
+// previous token. It must run inside RunInTx.
+func (s *Server) replaceToken(ctx echo.Context, repo repository.RepositoryInterface, userID int64, token, expiresAt string) error {
+	filter := repository.LoginFilter{
+		UserID: &userID,
+	}
+
+	resGetLogin, err := repo.GetLogin(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	ip := ctx.Request().RemoteAddr
+	if len(resGetLogin) == 0 {
+		_, err = repo.InsertIntoLogin(ctx.Request().Context(), repository.LoginModel{
+			UserId:  userID,
+			Ip:      ip,
+			Token:   token,
+			Expires: expiresAt,
+		})
+		return err
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	return repo.UpdateLogin(ctx.Request().Context(), filter, repository.LoginPatch{
+		Ip:        &ip,
+		Token:     &token,
+		Expires:   &expiresAt,
+		UpdatedAt: &now,
+	})
+}
+
+// hashPassword hashes password like utils.HashPassword, observing how long
+// it takes in metrics.PasswordHashDuration.
+func hashPassword(password string) (string, error) {
+	defer observePasswordHash(metrics.PasswordHashOperationHash, time.Now())
+	return utils.HashPassword(password)
+}
+
+// checkPasswordHash compares password with hash like
+// utils.CheckPasswordHash, observing how long it takes.
+func checkPasswordHash(password, hash string) bool {
+	defer observePasswordHash(metrics.PasswordHashOperationCompare, time.Now())
+	return utils.CheckPasswordHash(password, hash)
+}
+
+func observePasswordHash(operation string, start time.Time) {
+	metrics.PasswordHashDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// loginFailure counts a failed login with method for reason, and returns
+// err.
+func loginFailure(method, reason string, err error) error {
+	metrics.LoginFailures.WithLabelValues(method, reason).Inc()
+	return err
+}
+
+// sessionClaims validates a token issued by /login. The other tokens signed
+// with the same key must not act as the user's own session.
+func sessionClaims(authorization string) (jwt.MapClaims, error) {
+	claims, err := utils.ValidateToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	mapClaims := claims.(jwt.MapClaims)
+	if _, ok := mapClaims["client_id"]; ok {
+		return nil, errors.New("an OAuth access token is not a session token")
+	}
+	// id_tokens and invitation tokens are issued for an audience.
+	if _, ok := mapClaims["aud"]; ok {
+		return nil, errors.New("a token with an audience is not a session token")
+	}
+	return mapClaims, nil
+}
+
+// authenticate returns the user of a session token of /login, or of an API
+// key that grants scope. The user is read from the database either way.
+func (s *Server) authenticate(ctx echo.Context, authorization, scope string) (repository.Profile, error) {
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if strings.HasPrefix(token, apiKeyMarker) {
+		return s.apiKeyUser(ctx, token, scope)
+	}
+
+	profile, _, err := s.sessionUser(ctx, authorization)
+	return profile, err
+}
+
+// sessionLive tells whether a session token is live: while it is the token
+// recorded for the user's last login, so a token replaced by the next login
+// or by switching organization stops working. Impersonation tokens are not
+// recorded as logins and are live until they expire.
+func (s *Server) sessionLive(ctx echo.Context, token string, mapClaims jwt.MapClaims) (bool, error) {
+	if _, ok := impersonatorID(mapClaims); ok {
+		return true, nil
+	}
+
+	userID := userIDFromClaims(mapClaims)
+	resGetLogin, err := s.Repository.GetLogin(ctx.Request().Context(), repository.LoginFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(resGetLogin) > 0 && subtle.ConstantTimeCompare([]byte(resGetLogin[0].Token), []byte(token)) == 1, nil
+}
+
+// sessionUser returns the user of a live session token of /login, and its
+// claims. Session tokens only carry the user ID, so the profile is read
+// from the database and changes to it show at once. Tokens of deleted users
+// are invalid.
+func (s *Server) sessionUser(ctx echo.Context, authorization string) (repository.Profile, jwt.MapClaims, error) {
+	mapClaims, err := sessionClaims(authorization)
+	if err != nil {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeSession, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, nil, apperrors.ErrInvalidToken.Wrap(err)
+	}
+
+	live, err := s.sessionLive(ctx, strings.TrimPrefix(authorization, "Bearer "), mapClaims)
+	if err != nil {
+		return repository.Profile{}, nil, err
+	}
+
+	if !live {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeSession, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, nil, apperrors.ErrInvalidToken
+	}
+
+	userID := userIDFromClaims(mapClaims)
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return repository.Profile{}, nil, err
+	}
+
+	if len(resGetProfile) == 0 {
+		metrics.TokenValidations.WithLabelValues(metrics.TokenTypeSession, metrics.TokenResultInvalid).Inc()
+		return repository.Profile{}, nil, apperrors.ErrInvalidToken
+	}
+	metrics.TokenValidations.WithLabelValues(metrics.TokenTypeSession, metrics.TokenResultValid).Inc()
+	return resGetProfile[0], mapClaims, nil
+}
+
+func userIDFromClaims(mapClaims jwt.MapClaims) int64 {
+	userID, _ := mapClaims["UserId"].(float64)
+	return int64(userID)
+}
+
+// requireAdmin returns the user of a session token or of an API key with
+// the admin scope, and fails unless the user is an admin. The role is read
+// from the database rather than from the token, so revoking it takes effect
+// at once.
+func (s *Server) requireAdmin(ctx echo.Context, authorization string) (repository.Profile, error) {
+	caller, err := s.authenticate(ctx, authorization, apiKeyScopeAdmin)
+	if err != nil {
+		return repository.Profile{}, err
+	}
+	middlewares.SetLocale(ctx, caller.Locale)
+
+	if caller.Role != repository.RoleAdmin {
+		return repository.Profile{}, apperrors.ErrForbidden
+	}
+	return caller, nil
+}
+
+// loginFilter selects the user logging in by email or phone number, with
+// the password hash to compare.
+func (s *Server) loginFilter(req *generated.LoginRequest) (filter repository.ProfileFilter, ok bool) {
+	filter.WithSecrets = true
+	if req.Email != nil && *req.Email != "" {
+		email := normalizeEmail(*req.Email)
+		filter.Email = &email
+		return filter, true
+	}
+
+	if req.PhoneNumber == nil {
+		return filter, false
+	}
+
+	// A number that cannot be normalized cannot be registered either, so it
+	// gets the same error as an unknown one.
+	phoneNumber, err := s.PhoneNormalizer.Normalize(*req.PhoneNumber)
+	if err != nil {
+		return filter, false
+	}
+	filter.Phone = &phoneNumber
+	return filter, true
+}
+
+// conflictError reports a unique violation that the checks before writing
+// could not see, e.g. because of a concurrent request. Conflicts the checks
+// already named are returned as they are.
+func conflictError(err error, phoneSet, emailSet bool) error {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) && appErr.Key != apperrors.ErrConflict.Key {
+		return err
+	}
+
+	switch {
+	case phoneSet && emailSet:
+		return apperrors.ErrPhoneOrEmailExists.Wrap(err)
+	case emailSet:
+		return apperrors.ErrEmailExists.Wrap(err)
+	default:
+		return apperrors.ErrPhoneNumberExists.Wrap(err)
+	}
+}
+
+// validationError turns an error of the validator into a domain error,
+// keeping the ones that already are.
+func validationError(err error) error {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return apperrors.ErrValidation.WithMessage(err.Error()).Wrap(err)
+}
+
+// normalizePhone returns the E.164 form of a phone number from a request,
+// reported as a validation error of the phoneNumber field when it has none.
+func (s *Server) normalizePhone(number string) (string, error) {
+	normalized, err := s.PhoneNormalizer.Normalize(number)
+	if err != nil {
+		violations := middlewares.ValidationErrors{{
+			Field:   "phoneNumber",
+			Rule:    "phone",
+			Message: "invalid field 'phoneNumber', must be a valid phone number",
+		}}
+		return "", apperrors.ErrValidation.WithMessage(violations.Error()).Wrap(violations)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	exportFormatCSV   = "csv"
+	exportFormatJSONL = "jsonl"
+	// exportPageSize is the number of users read per query while
+	// exporting.
+	exportPageSize = 500
+)
+
+// exportColumns is the header of CSV exports.
+var exportColumns = []string{
+	"id", "full_name", "phone_number", "email", "status", "role", "locale",
+	"email_verified_at", "phone_verified_at", "created_at", "updated_at",
+}
+
+// ExportUsers streams the users matching the filters page by page, so
+// exports of any size use the memory of a single page. Errors after the
+// first page can no longer be reported to the client, which sees a
+// truncated file; they are logged instead.
+func (s *Server) ExportUsers(ctx echo.Context, params generated.ExportUsersParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	format := exportFormatCSV
+	if params.Format != nil {
+		format = *params.Format
+	}
+	if format != exportFormatCSV && format != exportFormatJSONL {
+		return apperrors.ErrInvalidExportFormat
+	}
+
+	filter := repository.UserSearchFilter{
+		Query:  params.Q,
+		Role:   params.Role,
+		Status: params.Status,
+		OrgID:  params.OrganizationId,
+	}
+	if params.CreatedFrom != nil {
+		createdFrom := params.CreatedFrom.In(time.Local).Format(utils.TimestampLayout)
+		filter.CreatedFrom = &createdFrom
+	}
+	if params.CreatedTo != nil {
+		createdTo := params.CreatedTo.In(time.Local).Format(utils.TimestampLayout)
+		filter.CreatedTo = &createdTo
+	}
+
+	// The first page is read before the response is committed, so that
+	// failing queries are still reported as errors.
+	users, err := s.Repository.SearchUsers(ctx.Request().Context(), filter, 0, exportPageSize)
+	if err != nil {
+		return err
+	}
+
+	res := ctx.Response()
+	filename := "users." + format
+	if format == exportFormatCSV {
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	}
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(res)
+	encoder := json.NewEncoder(res)
+	if format == exportFormatCSV {
+		// Write errors of the csv package are reported by the next Write.
+		_ = csvWriter.Write(exportColumns)
+	}
+
+	for len(users) > 0 {
+		for _, user := range users {
+			if format == exportFormatCSV {
+				err = csvWriter.Write(exportCSVRecord(user))
+			} else {
+				err = encoder.Encode(exportedUser(user))
+			}
+			if err != nil {
+				ctx.Logger().Errorf("exporting users failed: %v", err)
+				return nil
+			}
+		}
+		csvWriter.Flush()
+		res.Flush()
+
+		if len(users) < exportPageSize {
+			break
+		}
+		users, err = s.Repository.SearchUsers(ctx.Request().Context(), filter, users[len(users)-1].UserId, exportPageSize)
+		if err != nil {
+			ctx.Logger().Errorf("exporting users failed: %v", err)
+			return nil
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		ctx.Logger().Errorf("exporting users failed: %v", err)
+	}
+	return nil
+}
+
+func exportedUser(user repository.UserSummary) generated.ExportedUser {
+	return generated.ExportedUser{
+		Id:              user.UserId,
+		FullName:        user.FullName,
+		PhoneNumber:     user.Phone,
+		Email:           user.Email,
+		Status:          user.Status,
+		Role:            user.Role,
+		Locale:          user.Locale,
+		EmailVerifiedAt: rfc3339(user.EmailVerifiedAt),
+		PhoneVerifiedAt: rfc3339(user.PhoneVerifiedAt),
+		CreatedAt:       *rfc3339(&user.CreatedAt),
+		UpdatedAt:       *rfc3339(&user.UpdatedAt),
+	}
+}
+
+// exportCSVRecord returns the fields of user in the order of exportColumns.
+// Missing values are empty.
+func exportCSVRecord(user repository.UserSummary) []string {
+	exported := exportedUser(user)
+	optional := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+
+	return []string{
+		strconv.FormatInt(exported.Id, 10),
+		spreadsheetSafe(exported.FullName),
+		exported.PhoneNumber,
+		spreadsheetSafe(optional(exported.Email)),
+		strconv.FormatInt(exported.Status, 10),
+		exported.Role,
+		exported.Locale,
+		optional(exported.EmailVerifiedAt),
+		optional(exported.PhoneVerifiedAt),
+		exported.CreatedAt,
+		exported.UpdatedAt,
+	}
+}
+
+// spreadsheetSafe prefixes values that spreadsheets would run as formulas
+// with a quote. Only the fields users choose freely need it; phone numbers
+// are normalized to E.164 on input.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
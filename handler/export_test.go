@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportUsers(t *testing.T) {
+	newFixture := func(users int) (*Server, string) {
+		server := newTestServer(NewServerOptions{})
+		_, session := createTestUser(t, server, repository.Profile{
+			FullName:  "Head Office",
+			Phone:     "+6281200000000",
+			Role:      repository.RoleAdmin,
+			CreatedAt: "2024-01-01 00:00:00",
+			UpdatedAt: "2024-01-01 00:00:00",
+		})
+		for i := 1; i <= users; i++ {
+			_, err := server.Repository.CreateProfile(context.Background(), repository.Profile{
+				FullName:  fmt.Sprintf("Worker %d", i),
+				Password:  "hash",
+				Phone:     fmt.Sprintf("+628130%07d", i),
+				Role:      repository.RoleUser,
+				CreatedAt: "2024-02-01 00:00:00",
+				UpdatedAt: "2024-02-01 00:00:00",
+			})
+			assert.NoError(t, err)
+		}
+
+		return server, session
+	}
+
+	export := func(server *Server, params generated.ExportUsersParams) (*httptest.ResponseRecorder, error) {
+		c, rec := newTestContext(echo.GET, "http://localhost:1323/admin/users/export", "")
+		return rec, server.ExportUsers(c, params)
+	}
+
+	t.Run("Positive Scenario, CSV export reads every page", func(t *testing.T) {
+		server, session := newFixture(exportPageSize + 1)
+		role := repository.RoleUser
+		rec, err := export(server, generated.ExportUsersParams{
+			Role:          &role,
+			Authorization: session,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/csv")
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, exportPageSize+2) {
+			assert.Equal(t, exportColumns, records[0])
+			assert.Equal(t, "Worker 1", records[1][1])
+			assert.Equal(t, fmt.Sprintf("Worker %d", exportPageSize+1), records[len(records)-1][1])
+		}
+		assert.NotContains(t, rec.Body.String(), "hash")
+	})
+
+	t.Run("Positive Scenario, JSON Lines export without secrets", func(t *testing.T) {
+		server, session := newFixture(2)
+		format := exportFormatJSONL
+		query := "worker 2"
+		rec, err := export(server, generated.ExportUsersParams{
+			Format:        &format,
+			Q:             &query,
+			Authorization: session,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		if assert.Len(t, lines, 1) {
+			assert.Equal(t, "+6281300000002", lines[0]["phoneNumber"])
+			assert.NotContains(t, lines[0], "password")
+		}
+	})
+
+	t.Run("Negative Scenario, Invalid format and non-admins are rejected", func(t *testing.T) {
+		server, session := newFixture(1)
+		format := "xlsx"
+		_, err := export(server, generated.ExportUsersParams{
+			Format:        &format,
+			Authorization: session,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidExportFormat)
+
+		_, userSession := createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281399999999",
+			Role:     repository.RoleUser,
+		})
+		_, err = export(server, generated.ExportUsersParams{
+			Authorization: userSession,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("Positive Scenario, Formulas are not exported as formulas", func(t *testing.T) {
+		assert.Equal(t, "'=HYPERLINK(\"x\")", spreadsheetSafe("=HYPERLINK(\"x\")"))
+		assert.Equal(t, "Budi Santoso", spreadsheetSafe("Budi Santoso"))
+		assert.Equal(t, "", spreadsheetSafe(""))
+	})
+}
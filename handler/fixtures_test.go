@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServer creates a Server for the handler tests, backed by a memory
+// repository and the default validator unless opts sets them.
+func newTestServer(opts NewServerOptions) *Server {
+	if opts.Repository == nil {
+		opts.Repository = repository.NewMemoryRepository()
+	}
+	if opts.Validator == nil {
+		opts.Validator = middlewares.NewValidator(middlewares.NewValidatorOptions{})
+	}
+	return NewServer(opts)
+}
+
+// createTestUser creates the profile in the repository of the server and
+// returns it with the Authorization header of a session of the user, which
+// is recorded as the user's last login. The password is a placeholder, as
+// the tests log in with the session instead.
+func createTestUser(t *testing.T, server *Server, profile repository.Profile) (repository.Profile, string) {
+	if profile.Password == "" {
+		profile.Password = "hash"
+	}
+	created, err := server.Repository.CreateProfile(context.Background(), profile)
+	assert.NoError(t, err)
+
+	token, expiresAt, err := utils.GenerateToken(created)
+	assert.NoError(t, err)
+	_, err = server.Repository.InsertIntoLogin(context.Background(), repository.LoginModel{
+		UserId:  created.UserId,
+		Token:   token,
+		Expires: expiresAt,
+	})
+	assert.NoError(t, err)
+	return created, "Bearer " + token
+}
+
+// newTestContext creates the echo context of a JSON request to target, to
+// call the endpoints of a Server directly.
+func newTestContext(method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+// newTestRouter registers the endpoints of the server on an echo instance
+// handling errors as main does, to send requests through the routes.
+func newTestRouter(server *Server, middleware ...echo.MiddlewareFunc) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = middlewares.HTTPErrorHandler
+	e.Use(middleware...)
+	generated.RegisterHandlers(e, server)
+	return e
+}
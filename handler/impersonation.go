@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) ImpersonateUser(ctx echo.Context, userId int64, params generated.ImpersonateUserParams) error {
+	admin, err := s.requireAdmin(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	var req *generated.ImpersonationRequest
+	err = json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID: &userId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetProfile) == 0 {
+		return apperrors.ErrNotFound
+	}
+
+	sessionID, _, err := utils.NewSecret(16)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := utils.GenerateImpersonationToken(resGetProfile[0], admin.UserId, sessionID)
+	if err != nil {
+		return err
+	}
+
+	// The token is only handed out once its audit is saved.
+	err = s.auditImpersonation(ctx, repository.ImpersonationAudit{
+		SessionId: sessionID,
+		ActorId:   admin.UserId,
+		UserId:    userId,
+		Status:    201,
+		Reason:    &req.Reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(201, generated.ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: time.Now().Add(utils.ImpersonationTTL).Format(time.RFC3339),
+		UserId:    userId,
+		ActorId:   admin.UserId,
+	})
+}
+
+// AuditImpersonation records every request made with an impersonation
+// token in the impersonation audit, with the response status. Failing to
+// save the audit is logged but does not fail the request, whose response
+// has been sent already.
+func (s *Server) AuditImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			mapClaims, err := sessionClaims(ctx.Request().Header.Get(echo.HeaderAuthorization))
+			if err != nil {
+				return next(ctx)
+			}
+			actorID, ok := impersonatorID(mapClaims)
+			if !ok {
+				return next(ctx)
+			}
+
+			// The error is handled here to know the status it is sent with.
+			if err := next(ctx); err != nil {
+				ctx.Error(err)
+			}
+
+			sessionID, _ := mapClaims["jti"].(string)
+			err = s.auditImpersonation(ctx, repository.ImpersonationAudit{
+				SessionId: sessionID,
+				ActorId:   actorID,
+				UserId:    userIDFromClaims(mapClaims),
+				Status:    ctx.Response().Status,
+			})
+			if err != nil {
+				ctx.Logger().Errorf("auditing request %s of admin %d as user %d failed: %v",
+					ctx.Request().RequestURI, actorID, userIDFromClaims(mapClaims), err)
+			}
+			return nil
+		}
+	}
+}
+
+// impersonationRoutes are the routes impersonation tokens may use: reading
+// what the user sees, and editing the profile, whose phone number and email
+// UpdateProfile still refuses to change.
+var impersonationRoutes = map[string]bool{
+	"GET /profile":                          true,
+	"PATCH /profile":                        true,
+	"PUT /profile/avatar":                   true,
+	"GET /api-keys":                         true,
+	"GET /organizations":                    true,
+	"GET /organizations/:orgId":             true,
+	"GET /organizations/:orgId/members":     true,
+	"GET /organizations/:orgId/invitations": true,
+	"GET /blobs*":                           true,
+}
+
+// RestrictImpersonation refuses impersonation tokens on every route but
+// impersonationRoutes, so that new endpoints are closed to them until they
+// are added there. It runs after AuditImpersonation, which records the
+// refusals.
+func (s *Server) RestrictImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if impersonated(ctx.Request().Header.Get(echo.HeaderAuthorization)) &&
+				!impersonationRoutes[ctx.Request().Method+" "+ctx.Path()] {
+				return apperrors.ErrImpersonationForbidden
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// auditImpersonation saves audit for the current request. The audit is
+// saved even when the client has gone away.
+func (s *Server) auditImpersonation(ctx echo.Context, audit repository.ImpersonationAudit) error {
+	audit.Method = ctx.Request().Method
+	audit.Path = ctx.Request().RequestURI
+	audit.Ip = ctx.RealIP()
+	audit.RequestId = ctx.Response().Header().Get(echo.HeaderXRequestID)
+	audit.CreatedAt = time.Now().Format(utils.TimestampLayout)
+
+	_, err := s.Repository.CreateImpersonationAudit(context.Background(), audit)
+	return err
+}
+
+// impersonatorID returns the user ID of the admin in the act claim of a
+// session token, and whether the token has one.
+func impersonatorID(mapClaims jwt.MapClaims) (int64, bool) {
+	act, ok := mapClaims["act"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	subject, _ := act["sub"].(string)
+	actorID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return actorID, true
+}
+
+// impersonated tells whether authorization is an impersonation token. Such
+// tokens are refused by RestrictImpersonation outside of a few routes.
+func impersonated(authorization string) bool {
+	mapClaims, err := sessionClaims(authorization)
+	if err != nil {
+		return false
+	}
+
+	_, ok := impersonatorID(mapClaims)
+	return ok
+}
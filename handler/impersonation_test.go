@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation(t *testing.T) {
+	type fixture struct {
+		server      *Server
+		repo        *repository.MemoryRepository
+		admin       repository.Profile
+		user        repository.Profile
+		session     string
+		userSession string
+	}
+
+	newFixture := func() fixture {
+		repo := repository.NewMemoryRepository()
+		server := newTestServer(NewServerOptions{Repository: repo})
+		admin, session := createTestUser(t, server, repository.Profile{
+			FullName: "Support Staff",
+			Phone:    "+6281234567890",
+			Role:     repository.RoleAdmin,
+		})
+		user, userSession := createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567891",
+			Role:     repository.RoleUser,
+		})
+		return fixture{
+			server:      server,
+			repo:        repo,
+			admin:       admin,
+			user:        user,
+			session:     session,
+			userSession: userSession,
+		}
+	}
+
+	newContext := func(method, body string) (echo.Context, *httptest.ResponseRecorder) {
+		return newTestContext(method, "http://localhost:1323/admin/users/2/impersonate", body)
+	}
+
+	impersonate := func(f fixture, authorization string) (string, error) {
+		ctx, rec := newContext(echo.POST, `{"reason": "Ticket 1234"}`)
+		err := f.server.ImpersonateUser(ctx, f.user.UserId, generated.ImpersonateUserParams{
+			Authorization: authorization,
+		})
+
+		var res generated.ImpersonationResponse
+		if err == nil {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, f.admin.UserId, res.ActorId)
+		}
+		return "Bearer " + res.Token, err
+	}
+
+	t.Run("Positive Scenario, Requests as the user are audited", func(t *testing.T) {
+		f := newFixture()
+		token, err := impersonate(f, f.session)
+		assert.NoError(t, err)
+
+		e := newTestRouter(f.server, f.server.AuditImpersonation(), f.server.RestrictImpersonation())
+
+		req := httptest.NewRequest(echo.GET, "/profile", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Field Worker")
+
+		req = httptest.NewRequest(echo.PATCH, "/profile", strings.NewReader(`{"phoneNumber": "081234567899"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, token)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		audits, err := f.repo.GetImpersonationAudit(context.Background(), repository.ImpersonationAuditFilter{
+			UserID: &f.user.UserId,
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, audits, 3) {
+			assert.Equal(t, "Ticket 1234", *audits[0].Reason)
+			assert.Equal(t, audits[0].SessionId, audits[1].SessionId)
+			assert.Equal(t, f.admin.UserId, audits[1].ActorId)
+			assert.Equal(t, "/profile", audits[1].Path)
+			assert.Equal(t, http.StatusOK, audits[1].Status)
+			assert.Equal(t, http.MethodPatch, audits[2].Method)
+			assert.Equal(t, http.StatusForbidden, audits[2].Status)
+		}
+
+		// Requests with the admin's own session are not audited.
+		req = httptest.NewRequest(echo.GET, "/profile", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, f.session)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		audits, err = f.repo.GetImpersonationAudit(context.Background(), repository.ImpersonationAuditFilter{
+			ActorID: &f.admin.UserId,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, audits, 3)
+
+		// The token is live for resource servers too, although it is not
+		// the user's last login.
+		c, _ := newTestContext(echo.POST, "http://localhost:1323/introspect", "")
+		res, err := f.server.introspect(c, strings.TrimPrefix(token, "Bearer "))
+		assert.NoError(t, err)
+		assert.True(t, res.Active)
+	})
+
+	t.Run("Negative Scenario, Impersonation tokens cannot take over the account", func(t *testing.T) {
+		f := newFixture()
+		token, err := impersonate(f, f.session)
+		assert.NoError(t, err)
+
+		e := newTestRouter(f.server, f.server.AuditImpersonation(), f.server.RestrictImpersonation())
+		send := func(method, target, body, authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := send(echo.PATCH, "/profile", `{"email": "attacker@example.com"}`, token)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = send(echo.PATCH, "/profile", `{"fullName": "Field Worker 2"}`, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		for _, route := range []struct{ method, target, body string }{
+			{echo.POST, "/api-keys", `{"name": "backdoor", "scopes": ["profile:read"]}`},
+			{echo.DELETE, "/api-keys/1", ""},
+			{echo.GET, "/oauth/authorize?response_type=code&client_id=app&redirect_uri=https://app.example.com", ""},
+			{echo.POST, "/organizations", `{"name": "Sawit Nusantara", "type": "company"}`},
+			{echo.POST, "/organizations/1/members", `{"phoneNumber": "081234567899", "role": "owner"}`},
+			{echo.DELETE, "/organizations/1/members/1", ""},
+			{echo.POST, "/organizations/1/invitations", `{"phoneNumber": "081234567899", "role": "owner"}`},
+			{echo.DELETE, "/organizations/1/invitations/1", ""},
+			{echo.POST, "/organizations/1/invitations/1/resend", ""},
+			{echo.POST, "/invitations/join", `{"token": "token"}`},
+			{echo.POST, "/session/organization", `{}`},
+			{echo.GET, "/admin/users/export", ""},
+		} {
+			rec := send(route.method, route.target, route.body, token)
+			assert.Equal(t, http.StatusForbidden, rec.Code, route.target)
+			assert.Contains(t, rec.Body.String(), apperrors.ErrImpersonationForbidden.Message, route.target)
+		}
+
+		// Admins impersonating admins do not get their powers.
+		adminToken, _, err := utils.GenerateImpersonationToken(f.admin, f.admin.UserId, "session")
+		assert.NoError(t, err)
+		rec = send(echo.POST, "/admin/users/2/impersonate", `{"reason": "Ticket 1234"}`, "Bearer "+adminToken)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// Users cannot impersonate.
+		_, err = impersonate(f, f.userSession)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/importer"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) ImportUsers(ctx echo.Context, params generated.ImportUsersParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	req := ctx.Request()
+	req.Body = http.MaxBytesReader(ctx.Response(), req.Body, importer.MaxBytes)
+	dryRun := params.DryRun != nil && *params.DryRun
+	report, err := s.Importer.Import(req.Context(), req.Body, dryRun)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return apperrors.ErrImportTooLarge
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, importReportResponse(middlewares.GetLocale(ctx), report))
+}
+
+// importReportResponse converts report, translating the reasons rows
+// failed to locale.
+func importReportResponse(locale string, report importer.Report) generated.ImportReport {
+	rows := make([]generated.ImportRowResult, 0, len(report.Results))
+	for _, result := range report.Results {
+		row := generated.ImportRowResult{
+			Line:        result.Line,
+			PhoneNumber: result.PhoneNumber,
+			Status:      result.Status,
+		}
+		if result.UserID != 0 {
+			userID := result.UserID
+			row.UserId = &userID
+		}
+		if result.Err != nil {
+			code := result.Err.Code
+			message := middlewares.LocalizeError(locale, result.Err)
+			row.Code = &code
+			row.Message = &message
+		}
+		if len(result.Violations) > 0 {
+			violations := make([]generated.FieldViolation, 0, len(result.Violations))
+			for _, violation := range result.Violations {
+				params := violation.Params
+				if params == nil {
+					params = []string{}
+				}
+				violations = append(violations, generated.FieldViolation{
+					Field:   violation.Field,
+					Rule:    violation.Rule,
+					Params:  params,
+					Message: middlewares.LocalizeViolation(locale, violation),
+				})
+			}
+			row.Errors = &violations
+		}
+		rows = append(rows, row)
+	}
+
+	return generated.ImportReport{
+		DryRun:    report.DryRun,
+		Created:   report.Count(importer.StatusCreated),
+		Joined:    report.Count(importer.StatusJoined),
+		Unchanged: report.Count(importer.StatusUnchanged),
+		Failed:    report.Count(importer.StatusFailed),
+		Rows:      rows,
+	}
+}
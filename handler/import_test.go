@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/importer"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportUsers(t *testing.T) {
+	type fixture struct {
+		echo         *echo.Echo
+		server       *Server
+		adminSession string
+		userSession  string
+		orgID        int64
+	}
+
+	newFixture := func() fixture {
+		server := newTestServer(NewServerOptions{})
+		_, adminSession := createTestUser(t, server, repository.Profile{
+			FullName: "Head Office",
+			Phone:    "+6281234567890",
+			Role:     repository.RoleAdmin,
+			Locale:   "id",
+		})
+		_, userSession := createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567891",
+			Role:     repository.RoleUser,
+		})
+		org, err := server.Repository.CreateOrganization(context.Background(), repository.Organization{
+			Name: "Kebun Sawit",
+			Type: repository.OrgTypeCompany,
+		})
+		assert.NoError(t, err)
+
+		return fixture{
+			echo:         newTestRouter(server, middlewares.ValidateContentType()),
+			server:       server,
+			adminSession: adminSession,
+			userSession:  userSession,
+			orgID:        org.OrgId,
+		}
+	}
+
+	upload := func(f fixture, session, query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, "/admin/users/import"+query, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		req.Header.Set(echo.HeaderAuthorization, session)
+		rec := httptest.NewRecorder()
+		f.echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	file := func(f fixture) string {
+		org := strconv.FormatInt(f.orgID, 10)
+		return "full_name,phone,role,organization\n" +
+			"Budi Santoso,0812-3456-7892,member," + org + "\n" +
+			"Siti Aminah,not a number,member," + org + "\n" +
+			"Budi Again,+6281234567892,member," + org + "\n"
+	}
+
+	t.Run("Positive Scenario, The report lists every row in the admin's locale", func(t *testing.T) {
+		f := newFixture()
+
+		rec := upload(f, f.adminSession, "", file(f))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var report generated.ImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.False(t, report.DryRun)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 2, report.Failed)
+		if assert.Len(t, report.Rows, 3) {
+			assert.Equal(t, importer.StatusCreated, report.Rows[0].Status)
+			assert.NotNil(t, report.Rows[0].UserId)
+
+			if assert.NotNil(t, report.Rows[1].Errors) {
+				assert.Equal(t, "phone", (*report.Rows[1].Errors)[0].Field)
+			}
+
+			duplicate, _ := i18n.Lookup("id", "DUPLICATE_ROW", nil)
+			assert.Equal(t, "CONFLICT", *report.Rows[2].Code)
+			assert.Equal(t, duplicate, *report.Rows[2].Message)
+		}
+
+		phoneNumber := "+6281234567892"
+		profiles, err := f.server.Repository.GetProfile(context.Background(), repository.ProfileFilter{Phone: &phoneNumber})
+		assert.NoError(t, err)
+		assert.Len(t, profiles, 1)
+	})
+
+	t.Run("Positive Scenario, A dry run saves nothing", func(t *testing.T) {
+		f := newFixture()
+
+		rec := upload(f, f.adminSession, "?dryRun=true", file(f))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var report generated.ImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 1, report.Created)
+
+		phoneNumber := "+6281234567892"
+		profiles, err := f.server.Repository.GetProfile(context.Background(), repository.ProfileFilter{Phone: &phoneNumber})
+		assert.NoError(t, err)
+		assert.Empty(t, profiles)
+	})
+
+	t.Run("Negative Scenario, Only admins import files of at most MaxBytes", func(t *testing.T) {
+		f := newFixture()
+
+		rec := upload(f, f.userSession, "", file(f))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = upload(f, f.adminSession, "", file(f)+strings.Repeat("x", importer.MaxBytes))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		tooLarge, _ := i18n.Lookup("id", "IMPORT_TOO_LARGE", nil)
+		assert.Contains(t, rec.Body.String(), tooLarge)
+
+		req := httptest.NewRequest(echo.POST, "/admin/users/import", strings.NewReader(file(f)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		req.Header.Set(echo.HeaderAuthorization, f.adminSession)
+		rec = httptest.NewRecorder()
+		f.echo.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
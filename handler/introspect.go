@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Values of token_type in introspection responses.
+const (
+	tokenTypeAccess  = "access_token"
+	tokenTypeRefresh = "refresh_token"
+	tokenTypeSession = "session"
+)
+
+func (s *Server) Introspect(ctx echo.Context) error {
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+
+	err := s.authenticateResourceServer(ctx)
+	if err != nil {
+		return writeOAuthError(ctx, err)
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		return writeOAuthError(ctx, invalidRequest("token is required"))
+	}
+
+	res, err := s.introspect(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, res)
+}
+
+// authenticateResourceServer lets through callers holding the shared
+// introspection secret as a bearer token, and otherwise requires the
+// credentials of a confidential OAuth client.
+func (s *Server) authenticateResourceServer(ctx echo.Context) error {
+	invalidClient := newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+
+	if authorization := ctx.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(authorization, "Bearer ") {
+		secret := strings.TrimPrefix(authorization, "Bearer ")
+		if s.IntrospectionSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(s.IntrospectionSecret)) != 1 {
+			return invalidClient
+		}
+		return requireForm(ctx)
+	}
+
+	client, err := s.authenticateClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Anybody can claim to be a public client.
+	if client.SecretHash == nil {
+		return invalidClient
+	}
+	return nil
+}
+
+// introspect describes token as RFC 7662 2.2 does. Tokens that are
+// unknown, expired, revoked or belong to a deleted user are inactive, and
+// nothing else is said about them.
+func (s *Server) introspect(ctx echo.Context, token string) (generated.IntrospectionResponse, error) {
+	inactive := generated.IntrospectionResponse{}
+
+	// token_type_hint is ignored like at /oauth/revoke; refresh tokens are
+	// looked up first since they are not JWTs.
+	refreshHash := utils.HashSecret(token)
+	resGetToken, err := s.Repository.GetOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+		RefreshHash: &refreshHash,
+	})
+	if err != nil {
+		return inactive, err
+	}
+
+	if len(resGetToken) > 0 {
+		if !refreshTokenUsable(resGetToken[0]) {
+			return inactive, nil
+		}
+
+		res := oauthTokenIntrospection(resGetToken[0], tokenTypeRefresh)
+		if expires, err := utils.ParseTimestamp(*resGetToken[0].RefreshExpires); err == nil {
+			exp := expires.Unix()
+			res.Exp = &exp
+		}
+		return s.withUserStatus(ctx, res, resGetToken[0].UserId)
+	}
+
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	mapClaims := claims.(jwt.MapClaims)
+	var res generated.IntrospectionResponse
+	var userID *int64
+	if _, ok := mapClaims["client_id"]; ok {
+		tokenID, _ := mapClaims["jti"].(string)
+		resGetToken, err := s.Repository.GetOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+			TokenID: &tokenID,
+		})
+		if err != nil {
+			return inactive, err
+		}
+
+		if tokenID == "" || len(resGetToken) == 0 || resGetToken[0].RevokedAt != nil {
+			return inactive, nil
+		}
+		res = oauthTokenIntrospection(resGetToken[0], tokenTypeAccess)
+		userID = resGetToken[0].UserId
+	} else {
+		// Session tokens are live by the same rule as at the API, and the
+		// other tokens signed with the key are not sessions.
+		if _, err := sessionClaims(token); err != nil {
+			return inactive, nil
+		}
+		live, err := s.sessionLive(ctx, token, mapClaims)
+		if err != nil {
+			return inactive, err
+		}
+
+		if !live {
+			return inactive, nil
+		}
+
+		sessionUserID := userIDFromClaims(mapClaims)
+		sub := strconv.FormatInt(sessionUserID, 10)
+		tokenType := tokenTypeSession
+		res = generated.IntrospectionResponse{
+			Sub:       &sub,
+			TokenType: &tokenType,
+		}
+		userID = &sessionUserID
+	}
+
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		unix := exp.Unix()
+		res.Exp = &unix
+	}
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		unix := iat.Unix()
+		res.Iat = &unix
+	}
+	return s.withUserStatus(ctx, res, userID)
+}
+
+// withUserStatus marks res active and adds the status of the user of the
+// token, unless the user no longer exists.
+func (s *Server) withUserStatus(ctx echo.Context, res generated.IntrospectionResponse, userID *int64) (generated.IntrospectionResponse, error) {
+	if userID != nil {
+		resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+			UserID: userID,
+		})
+		if err != nil {
+			return generated.IntrospectionResponse{}, err
+		}
+
+		if len(resGetProfile) == 0 {
+			return generated.IntrospectionResponse{}, nil
+		}
+		res.UserStatus = &resGetProfile[0].Status
+	}
+
+	res.Active = true
+	return res, nil
+}
+
+func oauthTokenIntrospection(token repository.OAuthToken, tokenType string) generated.IntrospectionResponse {
+	sub := token.ClientId
+	if token.UserId != nil {
+		sub = strconv.FormatInt(*token.UserId, 10)
+	}
+
+	res := generated.IntrospectionResponse{
+		ClientId:  &token.ClientId,
+		Sub:       &sub,
+		TokenType: &tokenType,
+	}
+	if token.Scope != "" {
+		res.Scope = &token.Scope
+	}
+	if createdAt, err := utils.ParseTimestamp(token.CreatedAt); err == nil {
+		iat := createdAt.Unix()
+		res.Iat = &iat
+	}
+	return res
+}
@@ -0,0 +1,545 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// invitationTTL is how long an invitation can be accepted after it is
+// sent.
+const invitationTTL = 7 * 24 * time.Hour
+
+// Statuses of invitations in responses.
+const (
+	invitationPending  = "pending"
+	invitationAccepted = "accepted"
+	invitationRevoked  = "revoked"
+	invitationExpired  = "expired"
+)
+
+// invitationToken is a signed token for an invitation, of which only the
+// ID is stored.
+type invitationToken struct {
+	id      string
+	token   string
+	expires string
+}
+
+func (s *Server) CreateInvitation(ctx echo.Context, orgId int64, params generated.CreateInvitationParams) error {
+	var req *generated.InvitationRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	session, tokenErr := s.orgSession(ctx, params.Authorization)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	invitation, err := s.invite(ctx, session, orgId, req.PhoneNumber, req.Role)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(201, invitationResponse(invitation))
+}
+
+// invite records an invitation of phoneNumber to the organization and
+// texts its token. Registered users are invited the same way and join with
+// the token once logged in, so nobody is added without accepting and the
+// response does not tell whether the number is registered.
+func (s *Server) invite(ctx echo.Context, session orgSession, orgID int64, phoneNumber, role string) (repository.Invitation, error) {
+	org, sessionRole, err := s.orgAccess(ctx, session, orgID, repository.OrgRoleAdmin)
+	if err != nil {
+		return repository.Invitation{}, err
+	}
+
+	// Only owners invite owners, so admins cannot promote themselves.
+	if orgRoleRank[role] > orgRoleRank[sessionRole] {
+		return repository.Invitation{}, apperrors.ErrForbidden
+	}
+
+	phoneNumber, err = s.normalizePhone(phoneNumber)
+	if err != nil {
+		return repository.Invitation{}, err
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return repository.Invitation{}, err
+	}
+
+	var invitation repository.Invitation
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetInvitation, err := repo.GetInvitation(ctx.Request().Context(), repository.InvitationFilter{
+			OrgID: &orgID,
+			Phone: &phoneNumber,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, other := range resGetInvitation {
+			if invitationStatus(other) == invitationPending {
+				return apperrors.ErrInvitationExists
+			}
+		}
+
+		now := time.Now().Format(utils.TimestampLayout)
+		invitation, err = repo.CreateInvitation(ctx.Request().Context(), repository.Invitation{
+			OrgId:     orgID,
+			Phone:     phoneNumber,
+			Role:      role,
+			InvitedBy: &session.userID,
+			TokenId:   token.id,
+			Expires:   token.expires,
+			SentAt:    now,
+			CreatedAt: now,
+		})
+		return err
+	})
+	if err != nil {
+		return repository.Invitation{}, err
+	}
+
+	if err := s.sendInvitation(ctx.Request().Context(), middlewares.GetLocale(ctx), phoneNumber, org.Name, token.token); err != nil {
+		ctx.Logger().Errorf("sending invitation %d failed: %v", invitation.InvitationId, err)
+	}
+
+	return invitation, nil
+}
+
+func (s *Server) ListInvitations(ctx echo.Context, orgId int64, params generated.ListInvitationsParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = s.orgAccess(ctx, session, orgId, repository.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	resGetInvitation, err := s.Repository.GetInvitation(ctx.Request().Context(), repository.InvitationFilter{
+		OrgID: &orgId,
+	})
+	if err != nil {
+		return err
+	}
+
+	invitations := make([]generated.InvitationResponse, 0, len(resGetInvitation))
+	for _, invitation := range resGetInvitation {
+		invitations = append(invitations, invitationResponse(invitation))
+	}
+
+	return ctx.JSON(200, generated.InvitationListResponse{
+		Invitations: invitations,
+	})
+}
+
+func (s *Server) ResendInvitation(ctx echo.Context, orgId int64, invitationId int64, params generated.ResendInvitationParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	org, role, err := s.orgAccess(ctx, session, orgId, repository.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.InvitationFilter{
+		InvitationID: &invitationId,
+		OrgID:        &orgId,
+	}
+	resGetInvitation, err := s.Repository.GetInvitation(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetInvitation) == 0 {
+		return apperrors.ErrInvitationNotFound
+	}
+	invitation := resGetInvitation[0]
+
+	if orgRoleRank[invitation.Role] > orgRoleRank[role] {
+		return apperrors.ErrForbidden
+	}
+
+	// Expired invitations can be resent, closed ones cannot.
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return apperrors.ErrInvitationClosed
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return err
+	}
+
+	// Filtering on the previous token keeps a concurrent acceptance from
+	// being overwritten. When another request got there first, the new
+	// token was not stored and must not be sent.
+	now := time.Now().Format(utils.TimestampLayout)
+	filter.TokenID = &invitation.TokenId
+	affected, err := s.Repository.UpdateInvitation(ctx.Request().Context(), filter, repository.InvitationPatch{
+		TokenID: &token.id,
+		Expires: &token.expires,
+		SentAt:  &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return apperrors.ErrInvitationClosed
+	}
+	invitation.TokenId = token.id
+	invitation.Expires = token.expires
+	invitation.SentAt = now
+
+	if err := s.sendInvitation(ctx.Request().Context(), middlewares.GetLocale(ctx), invitation.Phone, org.Name, token.token); err != nil {
+		ctx.Logger().Errorf("resending invitation %d failed: %v", invitation.InvitationId, err)
+	}
+
+	return ctx.JSON(200, invitationResponse(invitation))
+}
+
+func (s *Server) RevokeInvitation(ctx echo.Context, orgId int64, invitationId int64, params generated.RevokeInvitationParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = s.orgAccess(ctx, session, orgId, repository.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	filter := repository.InvitationFilter{
+		InvitationID: &invitationId,
+		OrgID:        &orgId,
+	}
+	resGetInvitation, err := s.Repository.GetInvitation(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetInvitation) == 0 {
+		return apperrors.ErrInvitationNotFound
+	}
+
+	if resGetInvitation[0].AcceptedAt != nil {
+		return apperrors.ErrInvitationClosed
+	}
+
+	if resGetInvitation[0].RevokedAt == nil {
+		now := time.Now().Format(utils.TimestampLayout)
+		_, err = s.Repository.UpdateInvitation(ctx.Request().Context(), filter, repository.InvitationPatch{
+			RevokedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.NoContent(204)
+}
+
+func (s *Server) AcceptInvitation(ctx echo.Context) error {
+	var req *generated.AcceptInvitationRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	var locale string
+	if req != nil && req.Locale != nil {
+		locale = *req.Locale
+	}
+	middlewares.SetLocale(ctx, locale)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	tokenID, err := invitationTokenID(req.Token)
+	if err != nil {
+		return apperrors.ErrInvalidInvitation.Wrap(err)
+	}
+
+	passwordHash, _ := hashPassword(req.Password)
+
+	var token string
+	var resCreateProfile repository.Profile
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetInvitation, err := repo.GetInvitation(ctx.Request().Context(), repository.InvitationFilter{
+			TokenID: &tokenID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(resGetInvitation) == 0 || invitationStatus(resGetInvitation[0]) != invitationPending {
+			return apperrors.ErrInvalidInvitation
+		}
+		invitation := resGetInvitation[0]
+
+		resGetProfile, err := repo.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+			Phone: &invitation.Phone,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Registered users join with JoinInvitation instead. Only the
+		// holder of the token texted to the number learns this.
+		if len(resGetProfile) > 0 {
+			return apperrors.ErrPhoneNumberExists
+		}
+
+		// The token was texted to the phone number, which verifies it.
+		now := time.Now().Format(utils.TimestampLayout)
+		resCreateProfile, err = repo.CreateProfile(ctx.Request().Context(), repository.Profile{
+			FullName:        req.FullName,
+			Password:        passwordHash,
+			Phone:           invitation.Phone,
+			Locale:          locale,
+			Role:            repository.RoleUser,
+			Status:          1,
+			PhoneVerifiedAt: &now,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = repo.CreateOrganizationMember(ctx.Request().Context(), repository.OrganizationMember{
+			OrgId:     invitation.OrgId,
+			UserId:    resCreateProfile.UserId,
+			Role:      invitation.Role,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		affected, err := repo.UpdateInvitation(ctx.Request().Context(), repository.InvitationFilter{
+			InvitationID: &invitation.InvitationId,
+			TokenID:      &tokenID,
+		}, repository.InvitationPatch{
+			AcceptedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The invitation was resent since it was read.
+		if affected == 0 {
+			return apperrors.ErrInvalidInvitation
+		}
+
+		token, err = s.issueToken(ctx, repo, resCreateProfile)
+		return err
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return conflictError(err, true, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, generated.LoginResponse{
+		Message: "success",
+		Token:   token,
+		UserID:  int(resCreateProfile.UserId),
+	})
+}
+
+func (s *Server) JoinInvitation(ctx echo.Context, params generated.JoinInvitationParams) error {
+	var req *generated.JoinInvitationRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	user, _, tokenErr := s.sessionUser(ctx, params.Authorization)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+	middlewares.SetLocale(ctx, user.Locale)
+
+	tokenID, err := invitationTokenID(req.Token)
+	if err != nil {
+		return apperrors.ErrInvalidInvitation.Wrap(err)
+	}
+
+	var org repository.Organization
+	var invitation repository.Invitation
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetInvitation, err := repo.GetInvitation(ctx.Request().Context(), repository.InvitationFilter{
+			TokenID: &tokenID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The token only proves the user holds the phone it was texted to.
+		if len(resGetInvitation) == 0 || invitationStatus(resGetInvitation[0]) != invitationPending || resGetInvitation[0].Phone != user.Phone {
+			return apperrors.ErrInvalidInvitation
+		}
+		invitation = resGetInvitation[0]
+
+		resGetOrg, err := repo.GetOrganization(ctx.Request().Context(), repository.OrganizationFilter{
+			OrgID: &invitation.OrgId,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(resGetOrg) == 0 {
+			return apperrors.ErrInvalidInvitation
+		}
+		org = resGetOrg[0]
+
+		now := time.Now().Format(utils.TimestampLayout)
+		_, err = repo.CreateOrganizationMember(ctx.Request().Context(), repository.OrganizationMember{
+			OrgId:     invitation.OrgId,
+			UserId:    user.UserId,
+			Role:      invitation.Role,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		affected, err := repo.UpdateInvitation(ctx.Request().Context(), repository.InvitationFilter{
+			InvitationID: &invitation.InvitationId,
+			TokenID:      &tokenID,
+		}, repository.InvitationPatch{
+			AcceptedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The invitation was resent since it was read.
+		if affected == 0 {
+			return apperrors.ErrInvalidInvitation
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return apperrors.ErrMemberExists
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, organizationResponse(org, invitation.Role))
+}
+
+func newInvitationToken() (invitationToken, error) {
+	id, _, err := utils.NewSecret(16)
+	if err != nil {
+		return invitationToken{}, err
+	}
+
+	expires := time.Now().Add(invitationTTL)
+	token, err := utils.GenerateInvitationToken(id, expires)
+	if err != nil {
+		return invitationToken{}, err
+	}
+
+	return invitationToken{
+		id:      id,
+		token:   token,
+		expires: expires.Format(utils.TimestampLayout),
+	}, nil
+}
+
+// invitationTokenID validates a token of GenerateInvitationToken and
+// returns its ID.
+func invitationTokenID(token string) (string, error) {
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	mapClaims := claims.(jwt.MapClaims)
+	audience, _ := mapClaims.GetAudience()
+	tokenID, _ := mapClaims["jti"].(string)
+	if len(audience) != 1 || audience[0] != utils.InvitationAudience || tokenID == "" {
+		return "", errors.New("not an invitation token")
+	}
+	return tokenID, nil
+}
+
+func invitationStatus(invitation repository.Invitation) string {
+	switch {
+	case invitation.AcceptedAt != nil:
+		return invitationAccepted
+	case invitation.RevokedAt != nil:
+		return invitationRevoked
+	}
+
+	expires, err := utils.ParseTimestamp(invitation.Expires)
+	if err != nil || !time.Now().Before(expires) {
+		return invitationExpired
+	}
+	return invitationPending
+}
+
+// sendInvitation texts the token of an invitation in the language of the
+// manager who sent it, since the worker has no account yet. The invitation
+// has already been saved, so a failure is only logged and the invitation
+// can be resent.
+func (s *Server) sendInvitation(ctx context.Context, locale, phoneNumber, organization, token string) error {
+	body, _ := i18n.Lookup(locale, "sms.invitation.body", map[string]string{
+		"organization": organization,
+		"token":        token,
+	})
+
+	return s.SMSSender.Send(ctx, sms.Message{
+		To:   phoneNumber,
+		Body: body,
+	})
+}
+
+func invitationResponse(invitation repository.Invitation) generated.InvitationResponse {
+	return generated.InvitationResponse{
+		Id:             invitation.InvitationId,
+		OrganizationId: invitation.OrgId,
+		PhoneNumber:    invitation.Phone,
+		Role:           invitation.Role,
+		Status:         invitationStatus(invitation),
+		ExpiresAt:      *rfc3339(&invitation.Expires),
+		SentAt:         *rfc3339(&invitation.SentAt),
+		AcceptedAt:     rfc3339(invitation.AcceptedAt),
+		CreatedAt:      *rfc3339(&invitation.CreatedAt),
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitations(t *testing.T) {
+	type fixture struct {
+		server    *Server
+		repo      *repository.MemoryRepository
+		smsSender *sms.FakeSender
+		session   string
+		orgID     int64
+	}
+
+	newFixture := func() fixture {
+		repo := repository.NewMemoryRepository()
+		smsSender := sms.NewFakeSender()
+		server := newTestServer(NewServerOptions{Repository: repo, SMSSender: smsSender})
+		manager, session := createTestUser(t, server, repository.Profile{
+			FullName: "Estate Manager",
+			Phone:    "+6281234567890",
+		})
+		org, err := repo.CreateOrganization(context.Background(), repository.Organization{
+			Name: "Sawit Nusantara",
+			Type: repository.OrgTypeCompany,
+		})
+		assert.NoError(t, err)
+		_, err = repo.CreateOrganizationMember(context.Background(), repository.OrganizationMember{
+			OrgId:  org.OrgId,
+			UserId: manager.UserId,
+			Role:   repository.OrgRoleAdmin,
+		})
+		assert.NoError(t, err)
+
+		return fixture{
+			server:    server,
+			repo:      repo,
+			smsSender: smsSender,
+			session:   session,
+			orgID:     org.OrgId,
+		}
+	}
+
+	newContext := func(method, body string) (echo.Context, *httptest.ResponseRecorder) {
+		return newTestContext(method, "http://localhost:1323/invitations", body)
+	}
+
+	invite := func(f fixture, body string) (generated.InvitationResponse, error) {
+		ctx, rec := newContext(echo.POST, body)
+		err := f.server.CreateInvitation(ctx, f.orgID, generated.CreateInvitationParams{
+			Authorization: f.session,
+		})
+
+		var res generated.InvitationResponse
+		if err == nil {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		}
+		return res, err
+	}
+
+	lastToken := func(f fixture) string {
+		messages := f.smsSender.Messages()
+		if !assert.NotEmpty(t, messages) {
+			return ""
+		}
+		body := messages[len(messages)-1].Body
+		return body[strings.LastIndex(body, " ")+1:]
+	}
+
+	accept := func(f fixture, token string) (*httptest.ResponseRecorder, error) {
+		ctx, rec := newContext(echo.POST, `{"token": "`+token+`", "fullName": "Field Worker", "password": "aBcdef1!"}`)
+		return rec, f.server.AcceptInvitation(ctx)
+	}
+
+	t.Run("Positive Scenario, Worker accepts and joins the organization", func(t *testing.T) {
+		f := newFixture()
+		invitation, err := invite(f, `{"phoneNumber": "0812-3456-7891", "role": "member"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, invitationPending, invitation.Status)
+		assert.Equal(t, "+6281234567891", f.smsSender.Messages()[0].To)
+
+		rec, err := accept(f, lastToken(f))
+		assert.NoError(t, err)
+		var login generated.LoginResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &login))
+		assert.NotEmpty(t, login.Token)
+
+		userID := int64(login.UserID)
+		members, err := f.repo.GetOrganizationMember(context.Background(), repository.OrganizationMemberFilter{
+			OrgID:  &f.orgID,
+			UserID: &userID,
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, members, 1) {
+			assert.Equal(t, repository.OrgRoleMember, members[0].Role)
+		}
+
+		// Invitations are used once.
+		_, err = accept(f, lastToken(f))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidInvitation)
+	})
+
+	t.Run("Positive Scenario, Registered user is invited alike and joins once logged in", func(t *testing.T) {
+		f := newFixture()
+		worker, workerSession := createTestUser(t, f.server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567891",
+		})
+
+		invitation, err := invite(f, `{"phoneNumber": "0812-3456-7891", "role": "member"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, invitationPending, invitation.Status)
+		token := lastToken(f)
+
+		_, err = accept(f, token)
+		assert.ErrorIs(t, err, apperrors.ErrPhoneNumberExists)
+
+		join := func(session string) error {
+			ctx, _ := newContext(echo.POST, `{"token": "`+token+`"}`)
+			return f.server.JoinInvitation(ctx, generated.JoinInvitationParams{
+				Authorization: session,
+			})
+		}
+
+		// The token was texted to the worker, not to the manager.
+		assert.ErrorIs(t, join(f.session), apperrors.ErrInvalidInvitation)
+
+		assert.NoError(t, join(workerSession))
+		members, err := f.repo.GetOrganizationMember(context.Background(), repository.OrganizationMemberFilter{
+			OrgID:  &f.orgID,
+			UserID: &worker.UserId,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, members, 1)
+
+		assert.ErrorIs(t, join(workerSession), apperrors.ErrInvalidInvitation)
+	})
+
+	t.Run("Negative Scenario, Resent and revoked tokens are rejected", func(t *testing.T) {
+		f := newFixture()
+		invitation, err := invite(f, `{"phoneNumber": "081234567891", "role": "admin"}`)
+		assert.NoError(t, err)
+		first := lastToken(f)
+
+		_, err = invite(f, `{"phoneNumber": "081234567891", "role": "member"}`)
+		assert.ErrorIs(t, err, apperrors.ErrInvitationExists)
+		_, err = invite(f, `{"phoneNumber": "081234567892", "role": "owner"}`)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		ctx, _ := newContext(echo.POST, "")
+		err = f.server.ResendInvitation(ctx, f.orgID, invitation.Id, generated.ResendInvitationParams{
+			Authorization: f.session,
+		})
+		assert.NoError(t, err)
+		second := lastToken(f)
+
+		_, err = accept(f, first)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidInvitation)
+
+		ctx, _ = newContext(echo.DELETE, "")
+		err = f.server.RevokeInvitation(ctx, f.orgID, invitation.Id, generated.RevokeInvitationParams{
+			Authorization: f.session,
+		})
+		assert.NoError(t, err)
+
+		_, err = accept(f, second)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidInvitation)
+
+		// Invitation tokens are not sessions.
+		ctx, _ = newContext(echo.GET, "")
+		err = f.server.ListOrganizations(ctx, generated.ListOrganizationsParams{
+			Authorization: "Bearer " + second,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+}
@@ -0,0 +1,551 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// oauthCodeTTL is how long an authorization code can be redeemed.
+	oauthCodeTTL = 5 * time.Minute
+	// oauthAccessTokenTTL is the lifetime of an access token.
+	oauthAccessTokenTTL = time.Hour
+	// oauthRefreshTokenTTL is the lifetime of a refresh token.
+	oauthRefreshTokenTTL = 30 * 24 * time.Hour
+
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+	grantClientCredentials = "client_credentials"
+)
+
+// codeChallengeRegex matches a base64url encoded SHA-256 hash, the only
+// PKCE challenge accepted.
+var codeChallengeRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// oauthError is an error of the OAuth endpoints. Unlike the other
+// endpoints, they must report errors with the codes of RFC 6749, in the
+// body of the token and revocation endpoints or in the redirect of the
+// authorization endpoint.
+type oauthError struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *oauthError) Error() string {
+	return e.code + ": " + e.description
+}
+
+func newOAuthError(status int, code, description string) *oauthError {
+	return &oauthError{
+		status:      status,
+		code:        code,
+		description: description,
+	}
+}
+
+func invalidRequest(description string) *oauthError {
+	return newOAuthError(http.StatusBadRequest, "invalid_request", description)
+}
+
+func invalidGrant(description string) *oauthError {
+	return newOAuthError(http.StatusBadRequest, "invalid_grant", description)
+}
+
+func (s *Server) OauthAuthorize(ctx echo.Context, params generated.OauthAuthorizeParams) error {
+	user, _, err := s.sessionUser(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+	middlewares.SetLocale(ctx, user.Locale)
+
+	resGetClient, err := s.Repository.GetOAuthClient(ctx.Request().Context(), repository.OAuthClientFilter{
+		ClientID: &params.ClientId,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Redirecting to a URI the client did not register would hand the
+	// code to anyone, so these errors are reported to the user instead.
+	if len(resGetClient) == 0 || !hasField(resGetClient[0].RedirectUris, params.RedirectUri) {
+		return apperrors.ErrInvalidAuthorizeRequest
+	}
+	client := resGetClient[0]
+
+	redirect := func(values url.Values) error {
+		location, err := url.Parse(params.RedirectUri)
+		if err != nil {
+			return apperrors.ErrInvalidAuthorizeRequest.Wrap(err)
+		}
+
+		query := location.Query()
+		for key := range values {
+			query.Set(key, values.Get(key))
+		}
+		if params.State != nil {
+			query.Set("state", *params.State)
+		}
+		location.RawQuery = query.Encode()
+		return ctx.Redirect(http.StatusFound, location.String())
+	}
+	redirectError := func(err *oauthError) error {
+		return redirect(url.Values{
+			"error":             {err.code},
+			"error_description": {err.description},
+		})
+	}
+
+	if params.ResponseType != "code" {
+		return redirectError(newOAuthError(0, "unsupported_response_type", "response_type must be code"))
+	}
+	if !hasField(client.GrantTypes, grantAuthorizationCode) {
+		return redirectError(newOAuthError(0, "unauthorized_client", "the client cannot use the authorization_code grant"))
+	}
+	if params.CodeChallengeMethod != nil && *params.CodeChallengeMethod != "S256" {
+		return redirectError(invalidRequest("code_challenge_method must be S256"))
+	}
+	if !codeChallengeRegex.MatchString(params.CodeChallenge) {
+		return redirectError(invalidRequest("code_challenge must be a base64url encoded SHA-256 hash"))
+	}
+
+	var requested string
+	if params.Scope != nil {
+		requested = *params.Scope
+	}
+	scope, ok := grantedScope(requested, client.Scopes)
+	if !ok {
+		return redirectError(newOAuthError(0, "invalid_scope", "the client cannot request these scopes"))
+	}
+
+	var nonce string
+	if params.Nonce != nil {
+		if len(*params.Nonce) > 255 {
+			return redirectError(invalidRequest("nonce must be at most 255 characters"))
+		}
+		nonce = *params.Nonce
+	}
+
+	code, hash, err := utils.NewSecret(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = s.Repository.CreateOAuthCode(ctx.Request().Context(), repository.OAuthCode{
+		CodeHash:      hash,
+		ClientId:      client.ClientId,
+		UserId:        user.UserId,
+		RedirectUri:   params.RedirectUri,
+		Scope:         scope,
+		CodeChallenge: params.CodeChallenge,
+		Nonce:         nonce,
+		Expires:       now.Add(oauthCodeTTL).Format(utils.TimestampLayout),
+		CreatedAt:     now.Format(utils.TimestampLayout),
+	})
+	if err != nil {
+		return err
+	}
+
+	return redirect(url.Values{
+		"code": {code},
+	})
+}
+
+func (s *Server) OauthToken(ctx echo.Context) error {
+	// Token responses must not be cached (RFC 6749 5.1).
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	ctx.Response().Header().Set("Pragma", "no-cache")
+
+	client, err := s.authenticateClient(ctx)
+	if err != nil {
+		return writeOAuthError(ctx, err)
+	}
+
+	var res generated.OAuthTokenResponse
+	switch grantType := ctx.FormValue("grant_type"); {
+	case grantType == "":
+		err = invalidRequest("grant_type is required")
+	case grantType != grantAuthorizationCode && grantType != grantRefreshToken && grantType != grantClientCredentials:
+		err = newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "the grant type is not supported")
+	case !hasField(client.GrantTypes, grantType):
+		err = newOAuthError(http.StatusBadRequest, "unauthorized_client", "the client cannot use this grant type")
+	case grantType == grantAuthorizationCode:
+		res, err = s.exchangeCode(ctx, client)
+	case grantType == grantRefreshToken:
+		res, err = s.refreshToken(ctx, client)
+	default:
+		res, err = s.clientCredentials(ctx, client)
+	}
+	if err != nil {
+		return writeOAuthError(ctx, err)
+	}
+
+	return ctx.JSON(200, res)
+}
+
+func (s *Server) OauthRevoke(ctx echo.Context) error {
+	client, err := s.authenticateClient(ctx)
+	if err != nil {
+		return writeOAuthError(ctx, err)
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		return writeOAuthError(ctx, invalidRequest("token is required"))
+	}
+
+	// token_type_hint only saves a lookup (RFC 7009 2.1), so both kinds of
+	// token are tried whatever it says.
+	filter := repository.OAuthTokenFilter{
+		ClientID: &client.ClientId,
+	}
+	refreshHash := utils.HashSecret(token)
+	resGetToken, err := s.Repository.GetOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+		ClientID:    &client.ClientId,
+		RefreshHash: &refreshHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetToken) > 0 {
+		filter.TokenID = &resGetToken[0].TokenId
+	} else if claims, err := utils.ValidateToken(token); err == nil {
+		// Expired access tokens fail validation, but are useless anyway.
+		if tokenID, ok := claims.(jwt.MapClaims)["jti"].(string); ok {
+			filter.TokenID = &tokenID
+		}
+	}
+
+	// Unknown tokens and tokens of other clients are ignored without an
+	// error (RFC 7009 2.2).
+	if filter.TokenID != nil {
+		now := time.Now().Format(utils.TimestampLayout)
+		err = s.Repository.UpdateOAuthToken(ctx.Request().Context(), filter, repository.OAuthTokenPatch{
+			RevokedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.NoContent(200)
+}
+
+// exchangeCode redeems an authorization code for tokens. The code is
+// consumed in the same transaction, so it can be redeemed once.
+func (s *Server) exchangeCode(ctx echo.Context, client repository.OAuthClient) (res generated.OAuthTokenResponse, err error) {
+	code := ctx.FormValue("code")
+	redirectURI := ctx.FormValue("redirect_uri")
+	verifier := ctx.FormValue("code_verifier")
+	if code == "" || redirectURI == "" || verifier == "" {
+		return res, invalidRequest("code, redirect_uri and code_verifier are required")
+	}
+
+	hash := utils.HashSecret(code)
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetCode, err := repo.GetOAuthCode(ctx.Request().Context(), repository.OAuthCodeFilter{
+			CodeHash: &hash,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Every mismatch gets the same error, so the response does not
+		// tell which part of a stolen code is wrong.
+		if len(resGetCode) == 0 || !oauthCodeUsable(resGetCode[0], client, redirectURI, verifier) {
+			return invalidGrant("the code is invalid, expired or was issued to another client")
+		}
+
+		now := time.Now().Format(utils.TimestampLayout)
+		err = repo.UpdateOAuthCode(ctx.Request().Context(), repository.OAuthCodeFilter{
+			CodeHash: &hash,
+		}, repository.OAuthCodePatch{
+			ConsumedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		res, err = s.issueOAuthTokens(ctx, repo, client, &resGetCode[0].UserId, resGetCode[0].Scope, resGetCode[0].Nonce)
+		return err
+	})
+	return
+}
+
+// refreshToken exchanges a refresh token for new tokens. The old refresh
+// token and the access token issued with it are revoked, so a refresh
+// token can be used once.
+func (s *Server) refreshToken(ctx echo.Context, client repository.OAuthClient) (res generated.OAuthTokenResponse, err error) {
+	refreshToken := ctx.FormValue("refresh_token")
+	if refreshToken == "" {
+		return res, invalidRequest("refresh_token is required")
+	}
+
+	hash := utils.HashSecret(refreshToken)
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetToken, err := repo.GetOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+			ClientID:    &client.ClientId,
+			RefreshHash: &hash,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(resGetToken) == 0 || !refreshTokenUsable(resGetToken[0]) {
+			return invalidGrant("the refresh token is invalid, expired or revoked")
+		}
+		token := resGetToken[0]
+
+		// A refreshed token can narrow its scopes but never widen them.
+		scope, ok := grantedScope(ctx.FormValue("scope"), token.Scope)
+		if !ok {
+			return newOAuthError(http.StatusBadRequest, "invalid_scope", "the scopes exceed those of the refresh token")
+		}
+
+		now := time.Now().Format(utils.TimestampLayout)
+		err = repo.UpdateOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+			TokenID: &token.TokenId,
+		}, repository.OAuthTokenPatch{
+			RevokedAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+
+		res, err = s.issueOAuthTokens(ctx, repo, client, token.UserId, scope, "")
+		return err
+	})
+	return
+}
+
+// clientCredentials issues an access token to the client itself.
+func (s *Server) clientCredentials(ctx echo.Context, client repository.OAuthClient) (res generated.OAuthTokenResponse, err error) {
+	if client.SecretHash == nil {
+		return res, newOAuthError(http.StatusBadRequest, "unauthorized_client", "public clients cannot use the client_credentials grant")
+	}
+
+	scope, ok := grantedScope(ctx.FormValue("scope"), client.Scopes)
+	if !ok {
+		return res, newOAuthError(http.StatusBadRequest, "invalid_scope", "the client cannot request these scopes")
+	}
+
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		res, err = s.issueOAuthTokens(ctx, repo, client, nil, scope, "")
+		return err
+	})
+	return
+}
+
+// issueOAuthTokens signs an access token and records it. Tokens issued on
+// behalf of a user come with a refresh token when the client may use the
+// refresh_token grant, and with an id_token carrying nonce when the openid
+// scope is granted. It must run inside RunInTx.
+func (s *Server) issueOAuthTokens(ctx echo.Context, repo repository.RepositoryInterface, client repository.OAuthClient, userID *int64, scope, nonce string) (res generated.OAuthTokenResponse, err error) {
+	tokenID, _, err := utils.NewSecret(16)
+	if err != nil {
+		return
+	}
+
+	subject := client.ClientId
+	if userID != nil {
+		subject = strconv.FormatInt(*userID, 10)
+	}
+
+	now := time.Now()
+	accessToken, err := utils.GenerateAccessToken(utils.AccessTokenClaims{
+		ClientID: client.ClientId,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ClientId},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oauthAccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	token := repository.OAuthToken{
+		TokenId:   tokenID,
+		ClientId:  client.ClientId,
+		UserId:    userID,
+		Scope:     scope,
+		Expires:   now.Add(oauthAccessTokenTTL).Format(utils.TimestampLayout),
+		CreatedAt: now.Format(utils.TimestampLayout),
+	}
+	res = generated.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenTTL.Seconds()),
+	}
+	if scope != "" {
+		res.Scope = &scope
+	}
+
+	if userID != nil && hasField(scope, scopeOpenID) {
+		idToken, err := s.issueIDToken(ctx, repo, client, *userID, scope, nonce, now)
+		if err != nil {
+			return res, err
+		}
+		res.IdToken = &idToken
+	}
+
+	if userID != nil && hasField(client.GrantTypes, grantRefreshToken) {
+		refreshToken, refreshHash, err := utils.NewSecret(32)
+		if err != nil {
+			return res, err
+		}
+
+		refreshExpires := now.Add(oauthRefreshTokenTTL).Format(utils.TimestampLayout)
+		token.RefreshHash = &refreshHash
+		token.RefreshExpires = &refreshExpires
+		res.RefreshToken = &refreshToken
+	}
+
+	_, err = repo.CreateOAuthToken(ctx.Request().Context(), token)
+	return
+}
+
+// authenticateClient identifies the client of a token or revocation
+// request, which must be form encoded. Confidential clients authenticate
+// with HTTP Basic or with client_secret in the body, and public clients
+// only send their client_id.
+func (s *Server) authenticateClient(ctx echo.Context) (repository.OAuthClient, error) {
+	if err := requireForm(ctx); err != nil {
+		return repository.OAuthClient{}, err
+	}
+
+	clientID, secret, basic := ctx.Request().BasicAuth()
+	if basic {
+		// Basic credentials are form encoded first (RFC 6749 2.3.1).
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = ctx.FormValue("client_id")
+		secret = ctx.FormValue("client_secret")
+	}
+
+	invalidClient := newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	if clientID == "" {
+		return repository.OAuthClient{}, invalidClient
+	}
+
+	resGetClient, err := s.Repository.GetOAuthClient(ctx.Request().Context(), repository.OAuthClientFilter{
+		ClientID: &clientID,
+	})
+	if err != nil {
+		return repository.OAuthClient{}, err
+	}
+
+	if len(resGetClient) == 0 {
+		return repository.OAuthClient{}, invalidClient
+	}
+
+	client := resGetClient[0]
+	if client.SecretHash != nil && subtle.ConstantTimeCompare([]byte(utils.HashSecret(secret)), []byte(*client.SecretHash)) != 1 {
+		return repository.OAuthClient{}, invalidClient
+	}
+	return client, nil
+}
+
+func requireForm(ctx echo.Context) error {
+	mediaType, _, _ := mime.ParseMediaType(ctx.Request().Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return invalidRequest("the body must be application/x-www-form-urlencoded")
+	}
+	return nil
+}
+
+// writeOAuthError writes an oauthError in the format of RFC 6749 5.2. Other
+// errors are left to the error handler.
+func writeOAuthError(ctx echo.Context, err error) error {
+	var oauthErr *oauthError
+	if !errors.As(err, &oauthErr) {
+		return err
+	}
+
+	if oauthErr.status == http.StatusUnauthorized {
+		ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+	return ctx.JSON(oauthErr.status, generated.OAuthErrorResponse{
+		Error:            oauthErr.code,
+		ErrorDescription: &oauthErr.description,
+	})
+}
+
+// oauthCodeUsable reports whether code can be redeemed by client with the
+// redirect URI and PKCE verifier of the token request.
+func oauthCodeUsable(code repository.OAuthCode, client repository.OAuthClient, redirectURI, verifier string) bool {
+	if code.ConsumedAt != nil || code.ClientId != client.ClientId || code.RedirectUri != redirectURI {
+		return false
+	}
+
+	expires, err := utils.ParseTimestamp(code.Expires)
+	if err != nil || time.Now().After(expires) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) == 1
+}
+
+func refreshTokenUsable(token repository.OAuthToken) bool {
+	if token.RevokedAt != nil || token.RefreshExpires == nil {
+		return false
+	}
+
+	expires, err := utils.ParseTimestamp(*token.RefreshExpires)
+	return err == nil && time.Now().Before(expires)
+}
+
+// grantedScope returns the space separated scopes of a request, defaulting
+// to all of allowed. ok is false when a scope is not in allowed.
+func grantedScope(requested, allowed string) (scope string, ok bool) {
+	if strings.TrimSpace(requested) == "" {
+		return allowed, true
+	}
+
+	var output []string
+	for _, field := range strings.Fields(requested) {
+		if !hasField(allowed, field) {
+			return "", false
+		}
+		if !hasField(strings.Join(output, " "), field) {
+			output = append(output, field)
+		}
+	}
+	return strings.Join(output, " "), true
+}
+
+// hasField reports whether the space separated list contains value.
+func hasField(list, value string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) CreateOAuthClient(ctx echo.Context, params generated.CreateOAuthClientParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	var req *generated.OAuthClientRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	public := req.Public != nil && *req.Public
+	grantTypes := strings.Join(req.GrantTypes, " ")
+	var redirectURIs, scopes string
+	if req.RedirectUris != nil {
+		redirectURIs = strings.Join(*req.RedirectUris, " ")
+	}
+	if req.Scopes != nil {
+		scopes = strings.Join(*req.Scopes, " ")
+	}
+
+	if hasField(grantTypes, grantAuthorizationCode) && redirectURIs == "" {
+		return apperrors.ErrRedirectURIRequired
+	}
+	if public && hasField(grantTypes, grantClientCredentials) {
+		return apperrors.ErrPublicClientCredentials
+	}
+
+	clientID, _, err := utils.NewSecret(16)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	client := repository.OAuthClient{
+		ClientId:     clientID,
+		Name:         req.Name,
+		RedirectUris: redirectURIs,
+		GrantTypes:   grantTypes,
+		Scopes:       scopes,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// Only the hash of the secret is stored, so this response is the only
+	// place it can be read.
+	var secret string
+	if !public {
+		var secretHash string
+		secret, secretHash, err = utils.NewSecret(32)
+		if err != nil {
+			return err
+		}
+		client.SecretHash = &secretHash
+	}
+
+	resCreateClient, err := s.Repository.CreateOAuthClient(ctx.Request().Context(), client)
+	if err != nil {
+		return err
+	}
+
+	res := oauthClientResponse(resCreateClient)
+	if !public {
+		res.ClientSecret = &secret
+	}
+	return ctx.JSON(201, res)
+}
+
+func (s *Server) ListOAuthClients(ctx echo.Context, params generated.ListOAuthClientsParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	resGetClient, err := s.Repository.GetOAuthClient(ctx.Request().Context(), repository.OAuthClientFilter{})
+	if err != nil {
+		return err
+	}
+
+	clients := make([]generated.OAuthClientResponse, 0, len(resGetClient))
+	for _, client := range resGetClient {
+		clients = append(clients, oauthClientResponse(client))
+	}
+
+	return ctx.JSON(200, generated.OAuthClientListResponse{
+		Clients: clients,
+	})
+}
+
+func (s *Server) GetOAuthClient(ctx echo.Context, clientId string, params generated.GetOAuthClientParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	resGetClient, err := s.Repository.GetOAuthClient(ctx.Request().Context(), repository.OAuthClientFilter{
+		ClientID: &clientId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetClient) == 0 {
+		return apperrors.ErrOAuthClientNotFound
+	}
+
+	return ctx.JSON(200, oauthClientResponse(resGetClient[0]))
+}
+
+func (s *Server) DeleteOAuthClient(ctx echo.Context, clientId string, params generated.DeleteOAuthClientParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	filter := repository.OAuthClientFilter{
+		ClientID: &clientId,
+	}
+	resGetClient, err := s.Repository.GetOAuthClient(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetClient) == 0 {
+		return apperrors.ErrOAuthClientNotFound
+	}
+
+	err = s.Repository.DeleteOAuthClient(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return ctx.NoContent(204)
+}
+
+func oauthClientResponse(client repository.OAuthClient) generated.OAuthClientResponse {
+	return generated.OAuthClientResponse{
+		ClientId:     client.ClientId,
+		Name:         client.Name,
+		RedirectUris: fields(client.RedirectUris),
+		GrantTypes:   fields(client.GrantTypes),
+		Scopes:       fields(client.Scopes),
+		Public:       client.SecretHash == nil,
+		CreatedAt:    *rfc3339(&client.CreatedAt),
+	}
+}
+
+// fields splits a space separated list, returning an empty slice rather
+// than nil so that it is encoded as [] in JSON.
+func fields(list string) []string {
+	output := strings.Fields(list)
+	if output == nil {
+		output = []string{}
+	}
+	return output
+}
@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth(t *testing.T) {
+	const (
+		redirectURI = "https://partner.example/callback"
+		verifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	type fixture struct {
+		server     *Server
+		adminToken string
+		userToken  string
+	}
+
+	newFixture := func() fixture {
+		server := newTestServer(NewServerOptions{})
+		_, adminToken := createTestUser(t, server, repository.Profile{FullName: "Admin", Phone: "+6281234567890", Role: repository.RoleAdmin})
+		_, userToken := createTestUser(t, server, repository.Profile{FullName: "Field Worker", Phone: "+6281234567891", Role: repository.RoleUser})
+		return fixture{
+			server:     server,
+			adminToken: adminToken,
+			userToken:  userToken,
+		}
+	}
+
+	createClient := func(f fixture, body string) generated.OAuthClientResponse {
+		req := httptest.NewRequest(echo.POST, "http://localhost:1323/admin/oauth/clients", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		err := f.server.CreateOAuthClient(echo.New().NewContext(req, rec), generated.CreateOAuthClientParams{
+			Authorization: f.adminToken,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var res generated.OAuthClientResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+
+	authorize := func(f fixture, clientID, scope string) url.Values {
+		req := httptest.NewRequest(echo.GET, "http://localhost:1323/oauth/authorize", nil)
+		rec := httptest.NewRecorder()
+		state := "xyz"
+		nonce := "n-0S6_WzA2Mj"
+		err := f.server.OauthAuthorize(echo.New().NewContext(req, rec), generated.OauthAuthorizeParams{
+			Authorization: f.userToken,
+			ResponseType:  "code",
+			ClientId:      clientID,
+			RedirectUri:   redirectURI,
+			Scope:         &scope,
+			State:         &state,
+			CodeChallenge: challenge,
+			Nonce:         &nonce,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		location, err := url.Parse(rec.Header().Get(echo.HeaderLocation))
+		assert.NoError(t, err)
+		assert.Equal(t, "xyz", location.Query().Get("state"))
+		return location.Query()
+	}
+
+	post := func(endpoint func(echo.Context) error, form url.Values, clientID, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, "http://localhost:1323/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		if secret != "" {
+			req.SetBasicAuth(clientID, secret)
+		}
+		rec := httptest.NewRecorder()
+		assert.NoError(t, endpoint(echo.New().NewContext(req, rec)))
+		return rec
+	}
+
+	tokenResponse := func(rec *httptest.ResponseRecorder) generated.OAuthTokenResponse {
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res generated.OAuthTokenResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+
+	oauthErrorCode := func(rec *httptest.ResponseRecorder) string {
+		var res generated.OAuthErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Error
+	}
+
+	t.Run("Positive Scenario, Authorization code with PKCE, refresh and revocation", func(t *testing.T) {
+		f := newFixture()
+		client := createClient(f, `{"name": "Partner", "redirectUris": ["`+redirectURI+`"], "grantTypes": ["authorization_code", "refresh_token"], "scopes": ["profile", "phone"]}`)
+		assert.NotNil(t, client.ClientSecret)
+		assert.False(t, client.Public)
+
+		code := authorize(f, client.ClientId, "profile").Get("code")
+		assert.NotEmpty(t, code)
+
+		exchange := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		}
+		tokens := tokenResponse(post(f.server.OauthToken, exchange, client.ClientId, *client.ClientSecret))
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Equal(t, "profile", *tokens.Scope)
+		assert.NotNil(t, tokens.RefreshToken)
+
+		// A code can be redeemed once.
+		rec := post(f.server.OauthToken, exchange, client.ClientId, *client.ClientSecret)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid_grant", oauthErrorCode(rec))
+
+		refresh := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {*tokens.RefreshToken},
+		}
+		refreshed := tokenResponse(post(f.server.OauthToken, refresh, client.ClientId, *client.ClientSecret))
+		assert.NotEqual(t, tokens.AccessToken, refreshed.AccessToken)
+
+		// Refresh tokens are replaced on every use.
+		rec = post(f.server.OauthToken, refresh, client.ClientId, *client.ClientSecret)
+		assert.Equal(t, "invalid_grant", oauthErrorCode(rec))
+
+		rec = post(f.server.OauthRevoke, url.Values{"token": {*refreshed.RefreshToken}}, client.ClientId, *client.ClientSecret)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = post(f.server.OauthToken, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {*refreshed.RefreshToken},
+		}, client.ClientId, *client.ClientSecret)
+		assert.Equal(t, "invalid_grant", oauthErrorCode(rec))
+	})
+
+	t.Run("Negative Scenario, Wrong PKCE verifier", func(t *testing.T) {
+		f := newFixture()
+		client := createClient(f, `{"name": "Mobile App", "redirectUris": ["`+redirectURI+`"], "grantTypes": ["authorization_code"], "scopes": ["profile"], "public": true}`)
+		assert.Nil(t, client.ClientSecret)
+
+		code := authorize(f, client.ClientId, "profile").Get("code")
+		rec := post(f.server.OauthToken, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {client.ClientId},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {strings.Repeat("a", 43)},
+		}, "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid_grant", oauthErrorCode(rec))
+	})
+
+	t.Run("Negative Scenario, Scope outside of the client is redirected as an error", func(t *testing.T) {
+		f := newFixture()
+		client := createClient(f, `{"name": "Partner", "redirectUris": ["`+redirectURI+`"], "grantTypes": ["authorization_code"], "scopes": ["phone"]}`)
+
+		query := authorize(f, client.ClientId, "profile")
+		assert.Equal(t, "invalid_scope", query.Get("error"))
+		assert.Empty(t, query.Get("code"))
+	})
+
+	t.Run("Positive Scenario, Client credentials", func(t *testing.T) {
+		f := newFixture()
+		client := createClient(f, `{"name": "Reporting Job", "grantTypes": ["client_credentials"]}`)
+
+		tokens := tokenResponse(post(f.server.OauthToken, url.Values{"grant_type": {"client_credentials"}}, client.ClientId, *client.ClientSecret))
+		assert.Nil(t, tokens.RefreshToken)
+
+		// An access token does not act as the session of a user.
+		req := httptest.NewRequest(echo.GET, "http://localhost:1323/profile", nil)
+		err := f.server.GetProfile(echo.New().NewContext(req, httptest.NewRecorder()), generated.GetProfileParams{
+			Authorization: "Bearer " + tokens.AccessToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+
+		rec := post(f.server.OauthToken, url.Values{"grant_type": {"client_credentials"}}, client.ClientId, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "invalid_client", oauthErrorCode(rec))
+	})
+
+	t.Run("Positive Scenario, OpenID Connect id_token and userinfo", func(t *testing.T) {
+		f := newFixture()
+		client := createClient(f, `{"name": "Internal App", "redirectUris": ["`+redirectURI+`"], "grantTypes": ["authorization_code"], "scopes": ["openid", "profile", "phone"]}`)
+
+		code := authorize(f, client.ClientId, "openid profile").Get("code")
+		tokens := tokenResponse(post(f.server.OauthToken, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		}, client.ClientId, *client.ClientSecret))
+		if !assert.NotNil(t, tokens.IdToken) {
+			return
+		}
+
+		claims, err := utils.ValidateToken(*tokens.IdToken)
+		assert.NoError(t, err)
+		idToken := claims.(jwt.MapClaims)
+		assert.Equal(t, "n-0S6_WzA2Mj", idToken["nonce"])
+		assert.Equal(t, "Field Worker", idToken["name"])
+		assert.Equal(t, "2", idToken["sub"])
+		assert.NotContains(t, idToken, "phone_number")
+
+		req := httptest.NewRequest(echo.GET, "http://localhost:1323/userinfo", nil)
+		rec := httptest.NewRecorder()
+		err = f.server.GetUserInfo(echo.New().NewContext(req, rec), generated.GetUserInfoParams{
+			Authorization: "Bearer " + tokens.AccessToken,
+		})
+		assert.NoError(t, err)
+
+		var userInfo generated.UserInfoResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &userInfo))
+		assert.Equal(t, "2", userInfo.Sub)
+		assert.Equal(t, "Field Worker", *userInfo.Name)
+		assert.Nil(t, userInfo.PhoneNumber)
+
+		// Session tokens of /login are not access tokens.
+		req = httptest.NewRequest(echo.GET, "http://localhost:1323/userinfo", nil)
+		rec = httptest.NewRecorder()
+		err = f.server.GetUserInfo(echo.New().NewContext(req, rec), generated.GetUserInfoParams{
+			Authorization: f.userToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+		assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), "invalid_token")
+	})
+
+	t.Run("Positive Scenario, Introspection follows revocation and logins", func(t *testing.T) {
+		f := newFixture()
+		f.server.IntrospectionSecret = "resource-server-secret"
+		client := createClient(f, `{"name": "Partner", "redirectUris": ["`+redirectURI+`"], "grantTypes": ["authorization_code", "refresh_token"], "scopes": ["profile"]}`)
+
+		code := authorize(f, client.ClientId, "profile").Get("code")
+		tokens := tokenResponse(post(f.server.OauthToken, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		}, client.ClientId, *client.ClientSecret))
+
+		introspect := func(rec *httptest.ResponseRecorder) generated.IntrospectionResponse {
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var res generated.IntrospectionResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			return res
+		}
+
+		res := introspect(post(f.server.Introspect, url.Values{"token": {tokens.AccessToken}}, client.ClientId, *client.ClientSecret))
+		assert.True(t, res.Active)
+		assert.Equal(t, "2", *res.Sub)
+		assert.Equal(t, "profile", *res.Scope)
+		assert.Equal(t, client.ClientId, *res.ClientId)
+		assert.NotNil(t, res.Exp)
+		assert.NotNil(t, res.UserStatus)
+
+		res = introspect(post(f.server.Introspect, url.Values{"token": {*tokens.RefreshToken}}, client.ClientId, *client.ClientSecret))
+		assert.True(t, res.Active)
+		assert.Equal(t, "refresh_token", *res.TokenType)
+
+		post(f.server.OauthRevoke, url.Values{"token": {tokens.AccessToken}}, client.ClientId, *client.ClientSecret)
+		res = introspect(post(f.server.Introspect, url.Values{"token": {tokens.AccessToken}}, client.ClientId, *client.ClientSecret))
+		assert.Equal(t, generated.IntrospectionResponse{Active: false}, res)
+
+		// Session tokens are active while they are the last login, as they
+		// are accepted by the API.
+		sessionToken := strings.TrimPrefix(f.userToken, "Bearer ")
+		withSecret := func(secret string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(echo.POST, "http://localhost:1323/introspect", strings.NewReader(url.Values{"token": {sessionToken}}.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+secret)
+			rec := httptest.NewRecorder()
+			assert.NoError(t, f.server.Introspect(echo.New().NewContext(req, rec)))
+			return rec
+		}
+
+		res = introspect(withSecret("resource-server-secret"))
+		assert.True(t, res.Active)
+		assert.Equal(t, "session", *res.TokenType)
+		assert.Nil(t, res.ClientId)
+
+		userID := int64(2)
+		laterToken := "a later token"
+		err := f.server.Repository.UpdateLogin(context.Background(), repository.LoginFilter{UserID: &userID}, repository.LoginPatch{
+			Token: &laterToken,
+		})
+		assert.NoError(t, err)
+		res = introspect(withSecret("resource-server-secret"))
+		assert.False(t, res.Active)
+		c, _ := newTestContext(echo.GET, "http://localhost:1323/profile", "")
+		err = f.server.GetProfile(c, generated.GetProfileParams{Authorization: f.userToken})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+
+		rec := withSecret("wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "invalid_client", oauthErrorCode(rec))
+	})
+
+	t.Run("Negative Scenario, Only admins manage clients", func(t *testing.T) {
+		f := newFixture()
+		req := httptest.NewRequest(echo.GET, "http://localhost:1323/admin/oauth/clients", nil)
+		err := f.server.ListOAuthClients(echo.New().NewContext(req, httptest.NewRecorder()), generated.ListOAuthClientsParams{
+			Authorization: f.userToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
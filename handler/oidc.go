@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Scopes of the OAuth clients. openid makes the token endpoint issue an
+// id_token, and the others release the user claims of the id_token and of
+// /userinfo.
+const (
+	scopeOpenID  = "openid"
+	scopeProfile = "profile"
+	scopePhone   = "phone"
+)
+
+func (s *Server) GetOpenIDConfiguration(ctx echo.Context) error {
+	return ctx.JSON(200, generated.OpenIDConfiguration{
+		Issuer:                            s.Issuer,
+		AuthorizationEndpoint:             s.Issuer + "/oauth/authorize",
+		TokenEndpoint:                     s.Issuer + "/oauth/token",
+		UserinfoEndpoint:                  s.Issuer + "/userinfo",
+		JwksUri:                           s.Issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:                s.Issuer + "/oauth/revoke",
+		IntrospectionEndpoint:             s.Issuer + "/introspect",
+		ScopesSupported:                   []string{scopeOpenID, scopeProfile, scopePhone},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantAuthorizationCode, grantRefreshToken, grantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "name", "phone_number", "phone_number_verified"},
+	})
+}
+
+func (s *Server) GetJwks(ctx echo.Context) error {
+	key, err := utils.PublicJWK()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, generated.JSONWebKeySet{
+		Keys: []generated.JSONWebKey{{
+			Kty: key.Kty,
+			Use: key.Use,
+			Alg: key.Alg,
+			Kid: key.Kid,
+			N:   key.N,
+			E:   key.E,
+		}},
+	})
+}
+
+func (s *Server) GetUserInfo(ctx echo.Context, params generated.GetUserInfoParams) error {
+	token, err := s.accessToken(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	// Tokens the client requested for itself have no user.
+	if token.UserId == nil || !hasField(token.Scope, scopeOpenID) {
+		ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="openid"`)
+		return apperrors.ErrInsufficientScope
+	}
+
+	resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID: token.UserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetProfile) == 0 {
+		ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		return apperrors.ErrInvalidAccessToken
+	}
+	middlewares.SetLocale(ctx, resGetProfile[0].Locale)
+
+	return ctx.JSON(200, userClaims(resGetProfile[0], token.Scope))
+}
+
+// accessToken returns the record of a valid access token issued to an OAuth
+// client, failing as RFC 6750 describes when it is invalid, expired or
+// revoked.
+func (s *Server) accessToken(ctx echo.Context, authorization string) (repository.OAuthToken, error) {
+	invalidToken := func(cause error) (repository.OAuthToken, error) {
+		ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		if cause != nil {
+			return repository.OAuthToken{}, apperrors.ErrInvalidAccessToken.Wrap(cause)
+		}
+		return repository.OAuthToken{}, apperrors.ErrInvalidAccessToken
+	}
+
+	claims, err := utils.ValidateToken(authorization)
+	if err != nil {
+		return invalidToken(err)
+	}
+
+	// Session tokens of /login have no jti and are not access tokens.
+	mapClaims := claims.(jwt.MapClaims)
+	tokenID, _ := mapClaims["jti"].(string)
+	if _, ok := mapClaims["client_id"]; !ok || tokenID == "" {
+		return invalidToken(nil)
+	}
+
+	resGetToken, err := s.Repository.GetOAuthToken(ctx.Request().Context(), repository.OAuthTokenFilter{
+		TokenID: &tokenID,
+	})
+	if err != nil {
+		return repository.OAuthToken{}, err
+	}
+
+	if len(resGetToken) == 0 || resGetToken[0].RevokedAt != nil {
+		return invalidToken(nil)
+	}
+	return resGetToken[0], nil
+}
+
+// issueIDToken signs the id_token of a user for client. It must run inside
+// RunInTx.
+func (s *Server) issueIDToken(ctx echo.Context, repo repository.RepositoryInterface, client repository.OAuthClient, userID int64, scope, nonce string, now time.Time) (string, error) {
+	resGetProfile, err := repo.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(resGetProfile) == 0 {
+		return "", invalidGrant("the user no longer exists")
+	}
+
+	claims := userClaims(resGetProfile[0], scope)
+	idToken := utils.IDTokenClaims{
+		Nonce:               nonce,
+		PhoneNumberVerified: claims.PhoneNumberVerified,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.Issuer,
+			Subject:   claims.Sub,
+			Audience:  jwt.ClaimStrings{client.ClientId},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oauthAccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	if claims.Name != nil {
+		idToken.Name = *claims.Name
+	}
+	if claims.PhoneNumber != nil {
+		idToken.PhoneNumber = *claims.PhoneNumber
+	}
+
+	return utils.GenerateIDToken(idToken)
+}
+
+// userClaims returns the claims of profile released by the scopes.
+func userClaims(profile repository.Profile, scope string) generated.UserInfoResponse {
+	claims := generated.UserInfoResponse{
+		Sub: strconv.FormatInt(profile.UserId, 10),
+	}
+
+	if hasField(scope, scopeProfile) {
+		claims.Name = &profile.FullName
+	}
+	if hasField(scope, scopePhone) {
+		verified := profile.PhoneVerifiedAt != nil
+		claims.PhoneNumber = &profile.Phone
+		claims.PhoneNumberVerified = &verified
+	}
+	return claims
+}
@@ -0,0 +1,433 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// orgRoleRank orders the organization roles. A role includes the rights of
+// the roles ranked below it.
+var orgRoleRank = map[string]int{
+	repository.OrgRoleMember: 1,
+	repository.OrgRoleAdmin:  2,
+	repository.OrgRoleOwner:  3,
+}
+
+// orgParentTypes maps each type of organization to the type of its parent.
+var orgParentTypes = map[string]string{
+	repository.OrgTypeCompany:  "",
+	repository.OrgTypeEstate:   repository.OrgTypeCompany,
+	repository.OrgTypeDivision: repository.OrgTypeEstate,
+}
+
+// orgSession is the user of a session token and the organization the
+// token is limited to, if any.
+type orgSession struct {
+	userID    int64
+	activeOrg *int64
+}
+
+func (s *Server) CreateOrganization(ctx echo.Context, params generated.CreateOrganizationParams) error {
+	var req *generated.OrganizationRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	session, tokenErr := s.orgSession(ctx, params.Authorization)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	parentType := orgParentTypes[req.Type]
+	if (parentType == "") != (req.ParentId == nil) {
+		return apperrors.ErrInvalidParent
+	}
+
+	if req.ParentId != nil {
+		parent, _, err := s.orgAccess(ctx, session, *req.ParentId, repository.OrgRoleAdmin)
+		if err != nil {
+			return err
+		}
+		if parent.Type != parentType {
+			return apperrors.ErrInvalidParent
+		}
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	var org repository.Organization
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		org, err = repo.CreateOrganization(ctx.Request().Context(), repository.Organization{
+			ParentId:  req.ParentId,
+			Name:      req.Name,
+			Type:      req.Type,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = repo.CreateOrganizationMember(ctx.Request().Context(), repository.OrganizationMember{
+			OrgId:     org.OrgId,
+			UserId:    session.userID,
+			Role:      repository.OrgRoleOwner,
+			CreatedAt: now,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(201, organizationResponse(org, repository.OrgRoleOwner))
+}
+
+func (s *Server) ListOrganizations(ctx echo.Context, params generated.ListOrganizationsParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	resGetMember, err := s.Repository.GetOrganizationMember(ctx.Request().Context(), repository.OrganizationMemberFilter{
+		UserID: &session.userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	organizations := make([]generated.OrganizationResponse, 0, len(resGetMember))
+	if len(resGetMember) > 0 {
+		roles := make(map[int64]string, len(resGetMember))
+		orgIDs := make([]int64, 0, len(resGetMember))
+		for _, member := range resGetMember {
+			roles[member.OrgId] = member.Role
+			orgIDs = append(orgIDs, member.OrgId)
+		}
+
+		resGetOrg, err := s.Repository.GetOrganization(ctx.Request().Context(), repository.OrganizationFilter{
+			OrgIDs: orgIDs,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, org := range resGetOrg {
+			organizations = append(organizations, organizationResponse(org, roles[org.OrgId]))
+		}
+	}
+
+	return ctx.JSON(200, generated.OrganizationListResponse{
+		Organizations: organizations,
+	})
+}
+
+func (s *Server) GetOrganization(ctx echo.Context, orgId int64, params generated.GetOrganizationParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	org, role, err := s.orgAccess(ctx, session, orgId, repository.OrgRoleMember)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, organizationResponse(org, role))
+}
+
+func (s *Server) ListOrganizationMembers(ctx echo.Context, orgId int64, params generated.ListOrganizationMembersParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = s.orgAccess(ctx, session, orgId, repository.OrgRoleMember); err != nil {
+		return err
+	}
+
+	resGetMember, err := s.Repository.GetOrganizationMember(ctx.Request().Context(), repository.OrganizationMemberFilter{
+		OrgID: &orgId,
+	})
+	if err != nil {
+		return err
+	}
+
+	members := make([]generated.OrganizationMemberResponse, 0, len(resGetMember))
+	for _, member := range resGetMember {
+		resGetProfile, err := s.Repository.GetProfile(ctx.Request().Context(), repository.ProfileFilter{
+			UserID: &member.UserId,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(resGetProfile) > 0 {
+			members = append(members, organizationMemberResponse(member, resGetProfile[0]))
+		}
+	}
+
+	return ctx.JSON(200, generated.OrganizationMemberListResponse{
+		Members: members,
+	})
+}
+
+func (s *Server) AddOrganizationMember(ctx echo.Context, orgId int64, params generated.AddOrganizationMemberParams) error {
+	var req *generated.OrganizationMemberRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	session, tokenErr := s.orgSession(ctx, params.Authorization)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	// The user becomes a member by accepting the invitation.
+	invitation, err := s.invite(ctx, session, orgId, req.PhoneNumber, req.Role)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(202, invitationResponse(invitation))
+}
+
+func (s *Server) RemoveOrganizationMember(ctx echo.Context, orgId int64, userId int64, params generated.RemoveOrganizationMemberParams) error {
+	session, err := s.orgSession(ctx, params.Authorization)
+	if err != nil {
+		return err
+	}
+
+	// Members can always leave.
+	minRole := repository.OrgRoleAdmin
+	if userId == session.userID {
+		minRole = repository.OrgRoleMember
+	}
+
+	org, role, err := s.orgAccess(ctx, session, orgId, minRole)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.OrganizationMemberFilter{
+		OrgID:  &orgId,
+		UserID: &userId,
+	}
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		resGetMember, err := repo.GetOrganizationMember(ctx.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+
+		if len(resGetMember) == 0 {
+			return apperrors.ErrMemberNotFound
+		}
+
+		if resGetMember[0].Role == repository.OrgRoleOwner {
+			if userId != session.userID && role != repository.OrgRoleOwner {
+				return apperrors.ErrForbidden
+			}
+
+			// Estates and divisions are still run by the owners above them.
+			if org.ParentId == nil {
+				owners, err := countOwners(ctx, repo, orgId)
+				if err != nil {
+					return err
+				}
+				if owners <= 1 {
+					return apperrors.ErrLastOwner
+				}
+			}
+		}
+
+		return repo.DeleteOrganizationMember(ctx.Request().Context(), filter)
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.NoContent(204)
+}
+
+func (s *Server) SwitchOrganization(ctx echo.Context, params generated.SwitchOrganizationParams) error {
+	var req *generated.SwitchOrganizationRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	session, tokenErr := s.orgSession(ctx, params.Authorization)
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	// Any organization of the user can be chosen, whichever the token is
+	// limited to now.
+	if req.OrganizationId != nil {
+		_, _, err = s.orgAccess(ctx, orgSession{userID: session.userID}, *req.OrganizationId, repository.OrgRoleMember)
+		if err != nil {
+			return err
+		}
+	}
+
+	user := repository.Profile{
+		UserId: session.userID,
+	}
+	var token, expiresAt string
+	if req.OrganizationId != nil {
+		token, expiresAt, err = utils.GenerateOrganizationToken(user, *req.OrganizationId)
+	} else {
+		token, expiresAt, err = utils.GenerateToken(user)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		return s.replaceToken(ctx, repo, session.userID, token, expiresAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, generated.LoginResponse{
+		Message: "success",
+		Token:   token,
+		UserID:  int(session.userID),
+	})
+}
+
+// orgSession reads a session token of /login. The organization endpoints
+// do not accept API keys.
+func (s *Server) orgSession(ctx echo.Context, authorization string) (orgSession, error) {
+	user, mapClaims, err := s.sessionUser(ctx, authorization)
+	if err != nil {
+		return orgSession{}, err
+	}
+
+	middlewares.SetLocale(ctx, user.Locale)
+
+	session := orgSession{
+		userID: user.UserId,
+	}
+	if orgID, ok := mapClaims["OrganizationId"].(float64); ok {
+		activeOrg := int64(orgID)
+		session.activeOrg = &activeOrg
+	}
+	return session, nil
+}
+
+// orgAccess returns the organization orgID and the role of the user in it,
+// the highest of the roles in the organization and those above it. It
+// fails unless the role is at least minRole and, when the token carries an
+// active organization, orgID is that organization or one below it.
+func (s *Server) orgAccess(ctx echo.Context, session orgSession, orgID int64, minRole string) (repository.Organization, string, error) {
+	// The hierarchy is at most three levels deep.
+	var chain []repository.Organization
+	for id := &orgID; id != nil; id = chain[len(chain)-1].ParentId {
+		resGetOrg, err := s.Repository.GetOrganization(ctx.Request().Context(), repository.OrganizationFilter{
+			OrgID: id,
+		})
+		if err != nil {
+			return repository.Organization{}, "", err
+		}
+
+		if len(resGetOrg) == 0 {
+			return repository.Organization{}, "", apperrors.ErrOrganizationNotFound
+		}
+		chain = append(chain, resGetOrg[0])
+	}
+
+	resGetMember, err := s.Repository.GetOrganizationMember(ctx.Request().Context(), repository.OrganizationMemberFilter{
+		UserID: &session.userID,
+	})
+	if err != nil {
+		return repository.Organization{}, "", err
+	}
+
+	roles := make(map[int64]string, len(resGetMember))
+	for _, member := range resGetMember {
+		roles[member.OrgId] = member.Role
+	}
+
+	var role string
+	inActiveOrg := session.activeOrg == nil
+	for _, org := range chain {
+		if orgRoleRank[roles[org.OrgId]] > orgRoleRank[role] {
+			role = roles[org.OrgId]
+		}
+		if session.activeOrg != nil && org.OrgId == *session.activeOrg {
+			inActiveOrg = true
+		}
+	}
+
+	if !inActiveOrg || orgRoleRank[role] < orgRoleRank[minRole] {
+		return repository.Organization{}, "", apperrors.ErrForbidden
+	}
+	return chain[0], role, nil
+}
+
+// countOwners returns the number of direct owners of an organization. It
+// must run inside RunInTx.
+func countOwners(ctx echo.Context, repo repository.RepositoryInterface, orgID int64) (int, error) {
+	resGetMember, err := repo.GetOrganizationMember(ctx.Request().Context(), repository.OrganizationMemberFilter{
+		OrgID: &orgID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	owners := 0
+	for _, member := range resGetMember {
+		if member.Role == repository.OrgRoleOwner {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func organizationResponse(org repository.Organization, role string) generated.OrganizationResponse {
+	return generated.OrganizationResponse{
+		Id:        org.OrgId,
+		ParentId:  org.ParentId,
+		Name:      org.Name,
+		Type:      org.Type,
+		Role:      role,
+		CreatedAt: *rfc3339(&org.CreatedAt),
+	}
+}
+
+func organizationMemberResponse(member repository.OrganizationMember, profile repository.Profile) generated.OrganizationMemberResponse {
+	return generated.OrganizationMemberResponse{
+		UserId:    member.UserId,
+		FullName:  profile.FullName,
+		Role:      member.Role,
+		CreatedAt: *rfc3339(&member.CreatedAt),
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizations(t *testing.T) {
+	type fixture struct {
+		server      *Server
+		smsSender   *sms.FakeSender
+		ownerToken  string
+		workerToken string
+	}
+
+	newFixture := func() fixture {
+		smsSender := sms.NewFakeSender()
+		server := newTestServer(NewServerOptions{SMSSender: smsSender})
+		_, ownerToken := createTestUser(t, server, repository.Profile{FullName: "Estate Owner", Phone: "+6281234567890"})
+		_, workerToken := createTestUser(t, server, repository.Profile{FullName: "Field Worker", Phone: "+6281234567891"})
+		return fixture{
+			server:      server,
+			smsSender:   smsSender,
+			ownerToken:  ownerToken,
+			workerToken: workerToken,
+		}
+	}
+
+	newContext := func(method, body string) (echo.Context, *httptest.ResponseRecorder) {
+		return newTestContext(method, "http://localhost:1323/organizations", body)
+	}
+
+	createOrganization := func(f fixture, body string) generated.OrganizationResponse {
+		ctx, rec := newContext(echo.POST, body)
+		err := f.server.CreateOrganization(ctx, generated.CreateOrganizationParams{
+			Authorization: f.ownerToken,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var res generated.OrganizationResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+
+	getOrganization := func(f fixture, orgID int64, token string) (generated.OrganizationResponse, error) {
+		ctx, rec := newContext(echo.GET, "")
+		err := f.server.GetOrganization(ctx, orgID, generated.GetOrganizationParams{
+			Authorization: token,
+		})
+
+		var res generated.OrganizationResponse
+		if err == nil {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		}
+		return res, err
+	}
+
+	t.Run("Positive Scenario, Roles apply to the organizations below", func(t *testing.T) {
+		f := newFixture()
+		company := createOrganization(f, `{"name": "Sawit Nusantara", "type": "company"}`)
+		estate := createOrganization(f, `{"name": "Estate Riau", "type": "estate", "parentId": `+jsonInt(company.Id)+`}`)
+		assert.Equal(t, repository.OrgRoleOwner, estate.Role)
+
+		addMember := func(phoneNumber string) generated.InvitationResponse {
+			ctx, rec := newContext(echo.POST, `{"phoneNumber": "`+phoneNumber+`", "role": "member"}`)
+			err := f.server.AddOrganizationMember(ctx, estate.Id, generated.AddOrganizationMemberParams{
+				Authorization: f.ownerToken,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+
+			var res generated.InvitationResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			return res
+		}
+
+		// Registered and unknown numbers are invited alike, and nobody is
+		// a member before accepting.
+		invitation := addMember("081234567891")
+		assert.Equal(t, invitationPending, invitation.Status)
+		assert.Equal(t, invitationPending, addMember("081234567899").Status)
+		_, err := getOrganization(f, estate.Id, f.workerToken)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		body := f.smsSender.Messages()[0].Body
+		ctx, _ := newContext(echo.POST, `{"token": "`+body[strings.LastIndex(body, " ")+1:]+`"}`)
+		err = f.server.JoinInvitation(ctx, generated.JoinInvitationParams{
+			Authorization: f.workerToken,
+		})
+		assert.NoError(t, err)
+
+		res, err := getOrganization(f, estate.Id, f.workerToken)
+		assert.NoError(t, err)
+		assert.Equal(t, repository.OrgRoleMember, res.Role)
+
+		_, err = getOrganization(f, company.Id, f.workerToken)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		// Members cannot create divisions of the estate.
+		ctx, _ = newContext(echo.POST, `{"name": "Division A", "type": "division", "parentId": `+jsonInt(estate.Id)+`}`)
+		err = f.server.CreateOrganization(ctx, generated.CreateOrganizationParams{
+			Authorization: f.workerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		ctx, _ = newContext(echo.POST, `{"name": "Division A", "type": "division", "parentId": `+jsonInt(company.Id)+`}`)
+		err = f.server.CreateOrganization(ctx, generated.CreateOrganizationParams{
+			Authorization: f.ownerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidParent)
+	})
+
+	t.Run("Positive Scenario, Active organization limits the token", func(t *testing.T) {
+		f := newFixture()
+		company := createOrganization(f, `{"name": "Sawit Nusantara", "type": "company"}`)
+		estate := createOrganization(f, `{"name": "Estate Riau", "type": "estate", "parentId": `+jsonInt(company.Id)+`}`)
+
+		ctx, rec := newContext(echo.POST, `{"organizationId": `+jsonInt(estate.Id)+`}`)
+		err := f.server.SwitchOrganization(ctx, generated.SwitchOrganizationParams{
+			Authorization: f.ownerToken,
+		})
+		assert.NoError(t, err)
+
+		var login generated.LoginResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &login))
+		scopedToken := "Bearer " + login.Token
+
+		_, err = getOrganization(f, estate.Id, scopedToken)
+		assert.NoError(t, err)
+		_, err = getOrganization(f, company.Id, scopedToken)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+		ctx, _ = newContext(echo.POST, "null")
+		err = f.server.SwitchOrganization(ctx, generated.SwitchOrganizationParams{
+			Authorization: f.ownerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrEmptyRequest)
+
+		// The worker has no role in the estate to switch to.
+		ctx, _ = newContext(echo.POST, `{"organizationId": `+jsonInt(estate.Id)+`}`)
+		err = f.server.SwitchOrganization(ctx, generated.SwitchOrganizationParams{
+			Authorization: f.workerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("Negative Scenario, Replaced session stops working with the cache enabled", func(t *testing.T) {
+		f := fixture{server: newTestServer(NewServerOptions{
+			Repository: repository.NewCachedRepository(repository.NewCachedRepositoryOptions{
+				Repository: repository.NewMemoryRepository(),
+				Store:      cache.NewLRUStore(cache.NewLRUStoreOptions{}),
+			}),
+		})}
+		_, f.ownerToken = createTestUser(t, f.server, repository.Profile{FullName: "Estate Owner", Phone: "+6281234567890"})
+		company := createOrganization(f, `{"name": "Sawit Nusantara", "type": "company"}`)
+
+		// Caches the login of the session.
+		_, err := getOrganization(f, company.Id, f.ownerToken)
+		assert.NoError(t, err)
+
+		ctx, rec := newContext(echo.POST, `{"organizationId": `+jsonInt(company.Id)+`}`)
+		err = f.server.SwitchOrganization(ctx, generated.SwitchOrganizationParams{
+			Authorization: f.ownerToken,
+		})
+		assert.NoError(t, err)
+
+		var login generated.LoginResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &login))
+
+		_, err = getOrganization(f, company.Id, f.ownerToken)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidToken)
+		_, err = getOrganization(f, company.Id, "Bearer "+login.Token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Negative Scenario, Company keeps its last owner", func(t *testing.T) {
+		f := newFixture()
+		company := createOrganization(f, `{"name": "Sawit Nusantara", "type": "company"}`)
+
+		ctx, _ := newContext(echo.DELETE, "")
+		err := f.server.RemoveOrganizationMember(ctx, company.Id, 1, generated.RemoveOrganizationMemberParams{
+			Authorization: f.ownerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrLastOwner)
+
+		ctx, _ = newContext(echo.DELETE, "")
+		err = f.server.RemoveOrganizationMember(ctx, company.Id, 1, generated.RemoveOrganizationMemberParams{
+			Authorization: f.workerToken,
+		})
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func jsonInt(value int64) string {
+	output, _ := json.Marshal(value)
+	return string(output)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// otpDigits is the length of a one-time login code.
+	otpDigits = 6
+	// otpTTL is how long a code can be used.
+	otpTTL = 5 * time.Minute
+	// otpMaxAttempts is how many wrong guesses a code survives.
+	otpMaxAttempts = 5
+	// otpRateLimit codes can be requested for a phone number every
+	// otpRateWindow, whether or not it is registered.
+	otpRateLimit  = 3
+	otpRateWindow = 15 * time.Minute
+)
+
+// newOTPCode returns a random numeric code of otpDigits digits.
+func newOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n.Int64()), nil
+}
+
+// otpUsable reports whether otp can still be exchanged for a token.
+func otpUsable(otp repository.LoginOTP) bool {
+	if otp.ConsumedAt != nil || otp.Attempts >= otpMaxAttempts {
+		return false
+	}
+
+	expires, err := utils.ParseTimestamp(otp.Expires)
+	return err == nil && time.Now().Before(expires)
+}
+
+// hashOTP hashes a code with the OTP key. Six digits are too few for a
+// plain hash: anyone reading the table could try them all.
+func (s *Server) hashOTP(code string) string {
+	return utils.HMACSecret(s.OTPKey, code)
+}
+
+func (s *Server) otpMatches(otp repository.LoginOTP, code string) bool {
+	return subtle.ConstantTimeCompare([]byte(s.hashOTP(code)), []byte(otp.CodeHash)) == 1
+}
+
+// smsLocale is the language of a text to profile: the saved locale, or the
+// request's when none is saved. The response itself stays in the request's
+// locale.
+func smsLocale(ctx echo.Context, profile repository.Profile) string {
+	if i18n.Supported(profile.Locale) {
+		return profile.Locale
+	}
+	return middlewares.GetLocale(ctx)
+}
+
+// smsDisabled reports whether the server was started without an SMS
+// gateway, so no code can be texted.
+func (s *Server) smsDisabled() bool {
+	_, ok := s.SMSSender.(sms.DisabledSender)
+	return ok
+}
+
+// sendOTP texts the code to phoneNumber in locale.
+func (s *Server) sendOTP(ctx context.Context, locale, phoneNumber, code string) error {
+	body, _ := i18n.Lookup(locale, "sms.otp.body", map[string]string{
+		"code": code,
+	})
+
+	return s.SMSSender.Send(ctx, sms.Message{
+		To:   phoneNumber,
+		Body: body,
+	})
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var otpCodeRegex = regexp.MustCompile(`\d{6}`)
+
+func TestOtpLogin(t *testing.T) {
+	newServer := func() (*Server, *sms.FakeSender) {
+		smsSender := sms.NewFakeSender()
+		server := newTestServer(NewServerOptions{SMSSender: smsSender})
+		createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567890",
+		})
+		return server, smsSender
+	}
+
+	call := func(endpoint func(echo.Context) error, body string) (*httptest.ResponseRecorder, error) {
+		c, rec := newTestContext(echo.POST, "http://localhost:1323/login/otp", body)
+		return rec, endpoint(c)
+	}
+
+	lastCode := func(smsSender *sms.FakeSender) string {
+		messages := smsSender.Messages()
+		if !assert.NotEmpty(t, messages) {
+			return ""
+		}
+		return otpCodeRegex.FindString(messages[len(messages)-1].Body)
+	}
+
+	t.Run("Positive Scenario, Code is exchanged for a token once", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "0812-3456-7890"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "+6281234567890", smsSender.Messages()[0].To)
+
+		code := lastCode(smsSender)
+		rec, err := call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "`+code+`"}`)
+		assert.NoError(t, err)
+
+		var res generated.LoginResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.NotEmpty(t, res.Token)
+
+		phoneNumber := "+6281234567890"
+		profiles, err := server.Repository.GetProfile(context.Background(), repository.ProfileFilter{Phone: &phoneNumber})
+		assert.NoError(t, err)
+		assert.NotNil(t, profiles[0].PhoneVerifiedAt)
+
+		_, err = call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "`+code+`"}`)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+	})
+
+	t.Run("Positive Scenario, Codes are stored as an HMAC with the OTP key", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.NoError(t, err)
+		code := lastCode(smsSender)
+
+		userID := int64(1)
+		otps, err := server.Repository.GetLoginOTP(context.Background(), repository.LoginOTPFilter{UserID: &userID})
+		assert.NoError(t, err)
+		if assert.Len(t, otps, 1) {
+			assert.Equal(t, utils.HMACSecret(server.OTPKey, code), otps[0].CodeHash)
+			assert.NotEqual(t, utils.HashSecret(code), otps[0].CodeHash)
+		}
+	})
+
+	t.Run("Positive Scenario, Unknown phone number gets the same response", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281299999999"}`)
+		assert.NoError(t, err)
+		assert.Empty(t, smsSender.Messages())
+	})
+
+	t.Run("Negative Scenario, Code is locked after too many wrong attempts", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.NoError(t, err)
+		code := lastCode(smsSender)
+
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		for i := 0; i < otpMaxAttempts; i++ {
+			_, err = call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "`+wrong+`"}`)
+			assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		}
+
+		_, err = call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "`+code+`"}`)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+	})
+
+	t.Run("Negative Scenario, Only the latest code is valid", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.NoError(t, err)
+		first := lastCode(smsSender)
+
+		_, err = call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.NoError(t, err)
+		if first == lastCode(smsSender) {
+			t.Skip("both codes are equal")
+		}
+
+		_, err = call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "`+first+`"}`)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+	})
+
+	t.Run("Negative Scenario, Codes are rate limited per phone number", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		for i := 0; i < otpRateLimit; i++ {
+			_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+			assert.NoError(t, err)
+		}
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.ErrorIs(t, err, apperrors.ErrTooManyRequests)
+		assert.Len(t, smsSender.Messages(), otpRateLimit)
+	})
+
+	t.Run("Negative Scenario, Unknown phone numbers are rate limited the same way", func(t *testing.T) {
+		server, smsSender := newServer()
+
+		for i := 0; i < otpRateLimit; i++ {
+			_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281299999999"}`)
+			assert.NoError(t, err)
+		}
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281299999999"}`)
+		assert.ErrorIs(t, err, apperrors.ErrTooManyRequests)
+		assert.Empty(t, smsSender.Messages())
+	})
+
+	t.Run("Positive Scenario, The response is in the request's locale and the text in the user's", func(t *testing.T) {
+		server, smsSender := newServer()
+		locale := "id"
+		userID := int64(1)
+		assert.NoError(t, server.Repository.UpdateProfile(context.Background(), repository.ProfileFilter{UserID: &userID}, repository.ProfilePatch{Locale: &locale}))
+
+		c, _ := newTestContext(echo.POST, "http://localhost:1323/login/otp", `{"phoneNumber": "+6281234567890"}`)
+		assert.NoError(t, server.StartOtpLogin(c))
+
+		assert.Equal(t, i18n.Default, middlewares.GetLocale(c))
+		if assert.Len(t, smsSender.Messages(), 1) {
+			body, _ := i18n.Lookup("id", "sms.otp.body", map[string]string{"code": lastCode(smsSender)})
+			assert.Equal(t, body, smsSender.Messages()[0].Body)
+		}
+	})
+
+	t.Run("Negative Scenario, Invalid code format", func(t *testing.T) {
+		server, _ := newServer()
+
+		_, err := call(server.VerifyOtpLogin, `{"phoneNumber": "+6281234567890", "code": "12ab"}`)
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+	})
+
+	t.Run("Negative Scenario, Codes are refused alike without an SMS gateway", func(t *testing.T) {
+		server := newTestServer(NewServerOptions{})
+		createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567890",
+		})
+
+		_, err := call(server.StartOtpLogin, `{"phoneNumber": "+6281234567890"}`)
+		assert.ErrorIs(t, err, apperrors.ErrSMSDisabled)
+		_, err = call(server.StartOtpLogin, `{"phoneNumber": "+6281299999999"}`)
+		assert.ErrorIs(t, err, apperrors.ErrSMSDisabled)
+	})
+}
@@ -0,0 +1,657 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// attributeDateLayout is the form of the values of date attributes.
+const attributeDateLayout = "2006-01-02"
+
+// attributeRules lists the validation rules each type of profile attribute
+// supports. They are the ones that can also be described in the OpenAPI
+// document.
+var attributeRules = map[string]map[string]bool{
+	repository.AttrTypeString: {
+		"min": true, "max": true, "len": true, "oneof": true, "alphanum": true,
+		"numeric": true, "email": true, "url": true, "startswith": true,
+	},
+	repository.AttrTypeInteger: {"gte": true, "lte": true, "oneof": true},
+	repository.AttrTypeNumber:  {"gte": true, "lte": true},
+	repository.AttrTypeBoolean: {},
+	repository.AttrTypeDate:    {},
+}
+
+func (s *Server) ListProfileAttributes(ctx echo.Context, params generated.ListProfileAttributesParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	resGetAttribute, err := s.Repository.GetProfileAttribute(ctx.Request().Context(), repository.ProfileAttributeFilter{})
+	if err != nil {
+		return err
+	}
+
+	attributes := make([]generated.ProfileAttributeResponse, 0, len(resGetAttribute))
+	for _, attribute := range resGetAttribute {
+		attributes = append(attributes, profileAttributeResponse(attribute))
+	}
+
+	return ctx.JSON(200, generated.ProfileAttributeListResponse{
+		Attributes: attributes,
+	})
+}
+
+func (s *Server) CreateProfileAttribute(ctx echo.Context, params generated.CreateProfileAttributeParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	var req *generated.ProfileAttributeRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	var rules string
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+	if err := s.checkAttributeRules(req.Type, rules); err != nil {
+		return err
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	attribute, err := s.Repository.CreateProfileAttribute(ctx.Request().Context(), repository.ProfileAttribute{
+		Name:       req.Name,
+		Label:      req.Label,
+		Type:       req.Type,
+		Rules:      rules,
+		Visibility: req.Visibility,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return apperrors.ErrAttributeExists
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(201, profileAttributeResponse(attribute))
+}
+
+// UpdateProfileAttribute changes the label, rules or visibility of an
+// attribute. Values saved under the previous rules are kept as they are.
+func (s *Server) UpdateProfileAttribute(ctx echo.Context, attributeId int64, params generated.UpdateProfileAttributeParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	var req *generated.ProfileAttributeUpdateRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	filter := repository.ProfileAttributeFilter{
+		AttributeID: &attributeId,
+	}
+	resGetAttribute, err := s.Repository.GetProfileAttribute(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetAttribute) == 0 {
+		return apperrors.ErrAttributeNotFound
+	}
+
+	if req.Rules != nil {
+		if err := s.checkAttributeRules(resGetAttribute[0].Type, *req.Rules); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	err = s.Repository.UpdateProfileAttribute(ctx.Request().Context(), filter, repository.ProfileAttributePatch{
+		Label:      req.Label,
+		Rules:      req.Rules,
+		Visibility: req.Visibility,
+		UpdatedAt:  &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	resGetAttribute, err = s.Repository.GetProfileAttribute(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetAttribute) == 0 {
+		return apperrors.ErrAttributeNotFound
+	}
+
+	return ctx.JSON(200, profileAttributeResponse(resGetAttribute[0]))
+}
+
+func (s *Server) DeleteProfileAttribute(ctx echo.Context, attributeId int64, params generated.DeleteProfileAttributeParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	filter := repository.ProfileAttributeFilter{
+		AttributeID: &attributeId,
+	}
+	resGetAttribute, err := s.Repository.GetProfileAttribute(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(resGetAttribute) == 0 {
+		return apperrors.ErrAttributeNotFound
+	}
+
+	err = s.Repository.DeleteProfileAttribute(ctx.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return ctx.NoContent(204)
+}
+
+func (s *Server) GetUserAttributes(ctx echo.Context, userId int64, params generated.GetUserAttributesParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	if err := s.requireUser(ctx.Request().Context(), userId); err != nil {
+		return err
+	}
+
+	attributes, err := profileAttributes(ctx.Request().Context(), s.Repository, userId, true)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, generated.UserAttributes{
+		Attributes: attributes,
+	})
+}
+
+// UpdateUserAttributes sets attributes of a user like PATCH /profile, but
+// also the read-only and hidden ones.
+func (s *Server) UpdateUserAttributes(ctx echo.Context, userId int64, params generated.UpdateUserAttributesParams) error {
+	if _, err := s.requireAdmin(ctx, params.Authorization); err != nil {
+		return err
+	}
+
+	var req *generated.UserAttributes
+	decoder := json.NewDecoder(ctx.Request().Body)
+	decoder.UseNumber()
+	err := decoder.Decode(&req)
+	if err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	err = s.Validator.Validate(req)
+	if err != nil {
+		return validationError(err)
+	}
+
+	if err := s.requireUser(ctx.Request().Context(), userId); err != nil {
+		return err
+	}
+
+	var attributes map[string]interface{}
+	err = s.Repository.RunInTx(ctx.Request().Context(), func(repo repository.RepositoryInterface) error {
+		err := s.setProfileAttributes(ctx.Request().Context(), repo, userId, req.Attributes, true)
+		if err != nil {
+			return err
+		}
+
+		attributes, err = profileAttributes(ctx.Request().Context(), repo, userId, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(200, generated.UserAttributes{
+		Attributes: attributes,
+	})
+}
+
+// GetOpenApiSpec serves the OpenAPI document of the service, with the
+// ProfileAttributes schema replaced by the attributes defined at the time
+// of the request.
+func (s *Server) GetOpenApiSpec(ctx echo.Context) error {
+	spec, err := generated.GetSwagger()
+	if err != nil {
+		return err
+	}
+
+	resGetAttribute, err := s.Repository.GetProfileAttribute(ctx.Request().Context(), repository.ProfileAttributeFilter{})
+	if err != nil {
+		return err
+	}
+
+	schema := openapi3.NewObjectSchema()
+	schema.Description = "The custom attributes of the profile defined by admins, by name. " +
+		"Setting an attribute to null removes its value, and read-only attributes cannot be changed."
+	additionalProperties := false
+	schema.AdditionalProperties = openapi3.AdditionalProperties{Has: &additionalProperties}
+	for _, attribute := range resGetAttribute {
+		if attribute.Visibility == repository.AttrVisibilityHidden {
+			continue
+		}
+		schema.Properties[attribute.Name] = openapi3.NewSchemaRef("", attributeSchema(attribute))
+	}
+	spec.Components.Schemas["ProfileAttributes"] = openapi3.NewSchemaRef("", schema)
+
+	return ctx.JSON(200, spec)
+}
+
+// requireUser returns ErrNotFound when the user does not exist.
+func (s *Server) requireUser(ctx context.Context, userID int64) error {
+	resGetProfile, err := s.Repository.GetProfile(ctx, repository.ProfileFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetProfile) == 0 {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// checkAttributeRules returns ErrInvalidAttributeRules unless rules only
+// use rules supported by the type, with valid parameters. The validator
+// panics on invalid parameters, which ValidateVar reports as an error
+// other than a violation.
+func (s *Server) checkAttributeRules(attrType, rules string) error {
+	// Alternatives cannot be described in the OpenAPI document.
+	if strings.Contains(rules, "|") {
+		return apperrors.ErrInvalidAttributeRules
+	}
+	for _, rule := range splitAttributeRules(rules) {
+		if !attributeRules[attrType][rule.name] {
+			return apperrors.ErrInvalidAttributeRules
+		}
+	}
+	if rules == "" {
+		return nil
+	}
+
+	err := s.Validator.ValidateVar("rules", attributeZero(attrType), rules)
+	var violations middlewares.ValidationErrors
+	if err != nil && !errors.As(err, &violations) {
+		return apperrors.ErrInvalidAttributeRules.Wrap(err)
+	}
+	return nil
+}
+
+type attributeRule struct {
+	name  string
+	param string
+}
+
+// splitAttributeRules splits rules into the name and parameter of each
+// rule.
+func splitAttributeRules(rules string) []attributeRule {
+	var output []attributeRule
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "" {
+			continue
+		}
+		parts := strings.SplitN(rule, "=", 2)
+		parsed := attributeRule{name: parts[0]}
+		if len(parts) == 2 {
+			parsed.param = parts[1]
+		}
+		output = append(output, parsed)
+	}
+	return output
+}
+
+// attributeZero returns the zero value of the Go type attribute values of
+// attrType are validated as.
+func attributeZero(attrType string) interface{} {
+	switch attrType {
+	case repository.AttrTypeInteger:
+		return int64(0)
+	case repository.AttrTypeNumber:
+		return float64(0)
+	case repository.AttrTypeBoolean:
+		return false
+	default:
+		return ""
+	}
+}
+
+// profileAttributes returns the attribute values of a user by attribute
+// name, as JSON values of their type. Users do not see hidden attributes.
+func profileAttributes(ctx context.Context, repo repository.RepositoryInterface, userID int64, admin bool) (map[string]interface{}, error) {
+	resGetAttribute, err := repo.GetProfileAttribute(ctx, repository.ProfileAttributeFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	resGetValue, err := repo.GetProfileAttributeValue(ctx, repository.ProfileAttributeValueFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[int64]string{}
+	for _, value := range resGetValue {
+		values[value.AttributeId] = value.Value
+	}
+
+	output := map[string]interface{}{}
+	for _, attribute := range resGetAttribute {
+		if attribute.Visibility == repository.AttrVisibilityHidden && !admin {
+			continue
+		}
+		if value, ok := values[attribute.AttributeId]; ok {
+			output[attribute.Name] = attributeJSON(attribute, value)
+		}
+	}
+	return output, nil
+}
+
+// setProfileAttributes validates the attribute values of a request, all of
+// them before saving any, and saves them for the user with repo. Numbers
+// must be decoded as json.Number. A null value removes the value. Users
+// can only set editable attributes.
+func (s *Server) setProfileAttributes(ctx context.Context, repo repository.RepositoryInterface, userID int64, values map[string]interface{}, admin bool) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	resGetAttribute, err := repo.GetProfileAttribute(ctx, repository.ProfileAttributeFilter{})
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]repository.ProfileAttribute{}
+	for _, attribute := range resGetAttribute {
+		byName[attribute.Name] = attribute
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations middlewares.ValidationErrors
+	texts := map[int64]*string{}
+	for _, name := range names {
+		field := "attributes." + name
+		attribute, ok := byName[name]
+		if !ok || (attribute.Visibility == repository.AttrVisibilityHidden && !admin) {
+			violations = append(violations, attributeViolation(field, "unknown"))
+			continue
+		}
+		if attribute.Visibility == repository.AttrVisibilityReadOnly && !admin {
+			violations = append(violations, attributeViolation(field, "readonly"))
+			continue
+		}
+
+		if values[name] == nil {
+			texts[attribute.AttributeId] = nil
+			continue
+		}
+
+		text, value, ok := attributeValue(attribute, values[name])
+		if !ok {
+			violations = append(violations, attributeViolation(field, "type", attribute.Type))
+			continue
+		}
+
+		if attribute.Rules != "" {
+			err := s.Validator.ValidateVar(field, value, attribute.Rules)
+			var ruleViolations middlewares.ValidationErrors
+			if errors.As(err, &ruleViolations) {
+				violations = append(violations, ruleViolations...)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		texts[attribute.AttributeId] = &text
+	}
+
+	if len(violations) > 0 {
+		return apperrors.ErrValidation.WithMessage(violations.Error()).Wrap(violations)
+	}
+
+	resGetValue, err := repo.GetProfileAttributeValue(ctx, repository.ProfileAttributeValueFilter{
+		UserID: &userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	saved := map[int64]bool{}
+	for _, value := range resGetValue {
+		saved[value.AttributeId] = true
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	for attributeID, text := range texts {
+		attributeID := attributeID
+		filter := repository.ProfileAttributeValueFilter{
+			UserID:      &userID,
+			AttributeID: &attributeID,
+		}
+
+		switch {
+		case text == nil && saved[attributeID]:
+			err = repo.DeleteProfileAttributeValue(ctx, filter)
+		case text == nil:
+			err = nil
+		case saved[attributeID]:
+			err = repo.UpdateProfileAttributeValue(ctx, filter, repository.ProfileAttributeValuePatch{
+				Value:     text,
+				UpdatedAt: &now,
+			})
+		default:
+			_, err = repo.CreateProfileAttributeValue(ctx, repository.ProfileAttributeValue{
+				UserId:      userID,
+				AttributeId: attributeID,
+				Value:       *text,
+				UpdatedAt:   now,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attributeViolation returns the violation of a rule the handlers check
+// themselves, with its English message.
+func attributeViolation(field, rule string, params ...string) middlewares.FieldViolation {
+	message, _ := i18n.Lookup(i18n.Default, "validation."+rule, map[string]string{
+		"field": field,
+		"param": strings.Join(params, " "),
+	})
+	return middlewares.FieldViolation{
+		Field:   field,
+		Rule:    rule,
+		Params:  params,
+		Message: message,
+	}
+}
+
+// attributeValue converts a JSON value of a request to the text stored for
+// the attribute and the Go value its rules are checked against. ok is
+// false when the value does not have the type of the attribute.
+func attributeValue(attribute repository.ProfileAttribute, raw interface{}) (text string, value interface{}, ok bool) {
+	switch attribute.Type {
+	case repository.AttrTypeString:
+		value, ok := raw.(string)
+		return value, value, ok
+	case repository.AttrTypeInteger:
+		number, ok := raw.(json.Number)
+		if !ok {
+			return "", nil, false
+		}
+		value, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		return strconv.FormatInt(value, 10), value, true
+	case repository.AttrTypeNumber:
+		number, ok := raw.(json.Number)
+		if !ok {
+			return "", nil, false
+		}
+		value, err := number.Float64()
+		if err != nil {
+			return "", nil, false
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64), value, true
+	case repository.AttrTypeBoolean:
+		value, ok := raw.(bool)
+		return strconv.FormatBool(value), value, ok
+	case repository.AttrTypeDate:
+		value, ok := raw.(string)
+		if !ok {
+			return "", nil, false
+		}
+		if _, err := time.Parse(attributeDateLayout, value); err != nil {
+			return "", nil, false
+		}
+		return value, value, true
+	}
+	return "", nil, false
+}
+
+// attributeJSON converts a stored value back to a JSON value of the type of
+// the attribute.
+func attributeJSON(attribute repository.ProfileAttribute, text string) interface{} {
+	switch attribute.Type {
+	case repository.AttrTypeInteger:
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return value
+		}
+	case repository.AttrTypeNumber:
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	case repository.AttrTypeBoolean:
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value
+		}
+	}
+	return text
+}
+
+// attributeSchema describes the values of an attribute and its rules in
+// the OpenAPI document. Rules without an equivalent are left out.
+func attributeSchema(attribute repository.ProfileAttribute) *openapi3.Schema {
+	var schema *openapi3.Schema
+	switch attribute.Type {
+	case repository.AttrTypeInteger:
+		schema = openapi3.NewInt64Schema()
+	case repository.AttrTypeNumber:
+		schema = openapi3.NewFloat64Schema()
+	case repository.AttrTypeBoolean:
+		schema = openapi3.NewBoolSchema()
+	case repository.AttrTypeDate:
+		schema = openapi3.NewStringSchema()
+		schema.Format = "date"
+	default:
+		schema = openapi3.NewStringSchema()
+	}
+	schema.Title = attribute.Label
+	schema.Nullable = true
+	schema.ReadOnly = attribute.Visibility == repository.AttrVisibilityReadOnly
+
+	for _, rule := range splitAttributeRules(attribute.Rules) {
+		switch rule.name {
+		case "min", "max", "len":
+			length, err := strconv.ParseUint(rule.param, 10, 64)
+			if err != nil {
+				continue
+			}
+			if rule.name != "max" {
+				schema.MinLength = length
+			}
+			if rule.name != "min" {
+				schema.MaxLength = &length
+			}
+		case "gte", "lte":
+			bound, err := strconv.ParseFloat(rule.param, 64)
+			if err != nil {
+				continue
+			}
+			if rule.name == "gte" {
+				schema.Min = &bound
+			} else {
+				schema.Max = &bound
+			}
+		case "oneof":
+			for _, option := range strings.Fields(rule.param) {
+				schema.Enum = append(schema.Enum, attributeJSON(attribute, option))
+			}
+		case "alphanum":
+			schema.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			schema.Pattern = `^[-+]?[0-9]+(\.[0-9]+)?$`
+		case "startswith":
+			schema.Pattern = "^" + regexp.QuoteMeta(rule.param)
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		}
+	}
+	return schema
+}
+
+func profileAttributeResponse(attribute repository.ProfileAttribute) generated.ProfileAttributeResponse {
+	return generated.ProfileAttributeResponse{
+		Id:         attribute.AttributeId,
+		Name:       attribute.Name,
+		Label:      attribute.Label,
+		Type:       attribute.Type,
+		Rules:      attribute.Rules,
+		Visibility: attribute.Visibility,
+		CreatedAt:  *rfc3339(&attribute.CreatedAt),
+		UpdatedAt:  *rfc3339(&attribute.UpdatedAt),
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileAttributes(t *testing.T) {
+	type fixture struct {
+		echo         *echo.Echo
+		adminSession string
+		userSession  string
+		user         repository.Profile
+	}
+
+	newFixture := func() fixture {
+		server := newTestServer(NewServerOptions{})
+		_, adminSession := createTestUser(t, server, repository.Profile{
+			FullName: "Head Office",
+			Phone:    "+6281234567890",
+			Role:     repository.RoleAdmin,
+		})
+		user, userSession := createTestUser(t, server, repository.Profile{
+			FullName: "Field Worker",
+			Phone:    "+6281234567891",
+			Role:     repository.RoleUser,
+		})
+
+		return fixture{
+			echo:         newTestRouter(server),
+			adminSession: adminSession,
+			userSession:  userSession,
+			user:         user,
+		}
+	}
+
+	call := func(f fixture, method, path, authorization, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		rec := httptest.NewRecorder()
+		f.echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	define := func(f fixture) {
+		for _, body := range []string{
+			`{"name": "employee_number", "label": "Employee number", "type": "string", "rules": "alphanum,max=10", "visibility": "readonly"}`,
+			`{"name": "shoe_size", "label": "Shoe size", "type": "integer", "rules": "gte=35,lte=48", "visibility": "editable"}`,
+			`{"name": "date_of_birth", "label": "Date of birth", "type": "date", "visibility": "editable"}`,
+			`{"name": "risk_score", "label": "Risk score", "type": "number", "visibility": "hidden"}`,
+		} {
+			rec := call(f, echo.POST, "/admin/profile-attributes", f.adminSession, body)
+			assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	t.Run("Positive Scenario, Attributes are set and returned with their types", func(t *testing.T) {
+		f := newFixture()
+		define(f)
+
+		rec := call(f, echo.PATCH, "/profile", f.userSession,
+			`{"attributes": {"shoe_size": 42, "date_of_birth": "1990-05-17"}}`)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = call(f, echo.PATCH, "/admin/users/2/attributes", f.adminSession,
+			`{"attributes": {"employee_number": "E1234", "risk_score": 0.25}}`)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var profile generated.ProfileResponse
+		rec = call(f, echo.GET, "/profile", f.userSession, "")
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+		if assert.NotNil(t, profile.Attributes) {
+			assert.Equal(t, generated.ProfileAttributes{
+				"employee_number": "E1234",
+				"shoe_size":       float64(42),
+				"date_of_birth":   "1990-05-17",
+			}, *profile.Attributes)
+		}
+
+		// Null removes a value; admins also see hidden attributes.
+		rec = call(f, echo.PATCH, "/profile", f.userSession, `{"attributes": {"shoe_size": null}}`)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var attributes generated.UserAttributes
+		rec = call(f, echo.GET, "/admin/users/2/attributes", f.adminSession, "")
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attributes))
+		assert.Equal(t, map[string]interface{}{
+			"employee_number": "E1234",
+			"date_of_birth":   "1990-05-17",
+			"risk_score":      0.25,
+		}, attributes.Attributes)
+	})
+
+	t.Run("Negative Scenario, Invalid attributes are all reported and none are saved", func(t *testing.T) {
+		f := newFixture()
+		define(f)
+
+		rec := call(f, echo.PATCH, "/profile", f.userSession, `{"fullName": "New Name", "attributes": {
+			"employee_number": "E1",
+			"shoe_size": 50,
+			"date_of_birth": "17-05-1990",
+			"risk_score": 1,
+			"nickname": "Budi"
+		}}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var res generated.ValidationErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		if assert.NotNil(t, res.Errors) && assert.Len(t, *res.Errors, 5) {
+			rules := map[string]string{}
+			for _, violation := range *res.Errors {
+				rules[violation.Field] = violation.Rule
+			}
+			assert.Equal(t, map[string]string{
+				"attributes.date_of_birth":   "type",
+				"attributes.employee_number": "readonly",
+				"attributes.nickname":        "unknown",
+				"attributes.risk_score":      "unknown",
+				"attributes.shoe_size":       "lte",
+			}, rules)
+		}
+
+		rec = call(f, echo.PATCH, "/profile", f.userSession, `{"fullName": "New Name", "attributes": {
+			"shoe_size": 40,
+			"date_of_birth": 1990
+		}}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var profile generated.ProfileResponse
+		rec = call(f, echo.GET, "/profile", f.userSession, "")
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+		assert.Equal(t, "Field Worker", profile.FullName)
+		assert.Empty(t, *profile.Attributes)
+	})
+
+	t.Run("Negative Scenario, Definitions are checked", func(t *testing.T) {
+		f := newFixture()
+		define(f)
+
+		for _, body := range []string{
+			`{"name": "estate_code", "label": "Estate", "type": "string", "rules": "required", "visibility": "editable"}`,
+			`{"name": "estate_code", "label": "Estate", "type": "string", "rules": "min=abc", "visibility": "editable"}`,
+			`{"name": "estate_code", "label": "Estate", "type": "boolean", "rules": "gte=1", "visibility": "editable"}`,
+			`{"name": "estate_code", "label": "Estate", "type": "string", "rules": "min=1|max=3", "visibility": "editable"}`,
+			`{"name": "EstateCode", "label": "Estate", "type": "string", "visibility": "editable"}`,
+		} {
+			rec := call(f, echo.POST, "/admin/profile-attributes", f.adminSession, body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+
+		rec := call(f, echo.POST, "/admin/profile-attributes", f.adminSession,
+			`{"name": "shoe_size", "label": "Shoe size", "type": "string", "visibility": "editable"}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = call(f, echo.PATCH, "/admin/profile-attributes/2", f.adminSession, `{"rules": "email"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = call(f, echo.POST, "/admin/profile-attributes", f.userSession,
+			`{"name": "estate_code", "label": "Estate", "type": "string", "visibility": "editable"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Positive Scenario, The OpenAPI document describes the attributes", func(t *testing.T) {
+		f := newFixture()
+		define(f)
+
+		rec := call(f, echo.PATCH, "/admin/profile-attributes/2", f.adminSession, `{"rules": "oneof=38 40 42"}`)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = call(f, echo.GET, "/swagger/openapi.json", "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var spec struct {
+			Components struct {
+				Schemas map[string]struct {
+					Properties           map[string]map[string]interface{} `json:"properties"`
+					AdditionalProperties interface{}                       `json:"additionalProperties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+
+		schema := spec.Components.Schemas["ProfileAttributes"]
+		assert.Equal(t, false, schema.AdditionalProperties)
+		assert.NotContains(t, schema.Properties, "risk_score")
+		assert.Equal(t, "Employee number", schema.Properties["employee_number"]["title"])
+		assert.Equal(t, true, schema.Properties["employee_number"]["readOnly"])
+		assert.Equal(t, float64(10), schema.Properties["employee_number"]["maxLength"])
+		assert.Equal(t, []interface{}{float64(38), float64(40), float64(42)}, schema.Properties["shoe_size"]["enum"])
+		assert.Equal(t, "date", schema.Properties["date_of_birth"]["format"])
+	})
+
+	t.Run("Positive Scenario, Every attribute rule has a message", func(t *testing.T) {
+		for _, rules := range attributeRules {
+			for rule := range rules {
+				_, ok := i18n.Lookup(i18n.Indonesian, "validation."+rule, nil)
+				assert.True(t, ok, "no message for %q", rule)
+			}
+		}
+		for _, rule := range []string{"type", "unknown", "readonly"} {
+			_, ok := i18n.Lookup(i18n.Default, "validation."+rule, nil)
+			assert.True(t, ok, "no message for %q", rule)
+		}
+	})
+}
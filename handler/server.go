@@ -1,23 +1,105 @@
 package handler
 
 import (
+	"crypto/rand"
+	"log"
+
+	"github.com/SawitProRecruitment/UserService/importer"
+	"github.com/SawitProRecruitment/UserService/mail"
 	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/phone"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/SawitProRecruitment/UserService/storage"
 )
 
 type Server struct {
-	Repository repository.RepositoryInterface
-	Validator  middlewares.CustomValidatorInterface
+	Repository          repository.RepositoryInterface
+	Validator           middlewares.CustomValidatorInterface
+	PhoneNormalizer     *phone.Normalizer
+	Mailer              mail.Sender
+	SMSSender           sms.Sender
+	Issuer              string
+	IntrospectionSecret string
+	OTPKey              []byte
+	Importer            *importer.Importer
+	BlobStore           storage.BlobStore
 }
 
 type NewServerOptions struct {
 	Repository repository.RepositoryInterface
 	Validator  middlewares.CustomValidatorInterface
+	// PhoneNormalizer defaults to Indonesian numbers.
+	PhoneNormalizer *phone.Normalizer
+	// Mailer sends verification emails. Defaults to writing them to the log.
+	Mailer mail.Sender
+	// SMSSender sends one-time login codes and invitations. Defaults to
+	// sms.DisabledSender, which fails every send.
+	SMSSender sms.Sender
+	// Issuer is the iss claim of OAuth access tokens, the public URL of the
+	// service. Defaults to http://localhost:1323.
+	Issuer string
+	// IntrospectionSecret is a shared secret resource servers can send as a
+	// bearer token to /introspect instead of OAuth client credentials.
+	// Empty disables it.
+	IntrospectionSecret string
+	// OTPKey is the HMAC key of one-time login codes, shared by every
+	// instance. Defaults to a random key, which invalidates the codes sent
+	// by other instances and before a restart.
+	OTPKey []byte
+	// BlobStore stores avatars. Defaults to keeping them in memory, which
+	// loses them on restart.
+	BlobStore storage.BlobStore
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	phoneNormalizer := opts.PhoneNormalizer
+	if phoneNormalizer == nil {
+		phoneNormalizer = phone.NewNormalizer(phone.NewNormalizerOptions{})
+	}
+
+	mailer := opts.Mailer
+	if mailer == nil {
+		mailer = mail.NewWriterSender(log.Writer(), "no-reply@localhost")
+	}
+
+	smsSender := opts.SMSSender
+	if smsSender == nil {
+		smsSender = sms.NewDisabledSender()
+	}
+
+	issuer := opts.Issuer
+	if issuer == "" {
+		issuer = "http://localhost:1323"
+	}
+
+	otpKey := opts.OTPKey
+	if len(otpKey) == 0 {
+		otpKey = make([]byte, 32)
+		if _, err := rand.Read(otpKey); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	blobStore := opts.BlobStore
+	if blobStore == nil {
+		blobStore = storage.NewMemoryStore()
+	}
+
 	return &Server{
-		Repository: opts.Repository,
-		Validator:  opts.Validator,
+		Repository:          opts.Repository,
+		Validator:           opts.Validator,
+		PhoneNormalizer:     phoneNormalizer,
+		Mailer:              mailer,
+		SMSSender:           smsSender,
+		Issuer:              issuer,
+		IntrospectionSecret: opts.IntrospectionSecret,
+		OTPKey:              otpKey,
+		BlobStore:           blobStore,
+		Importer: importer.NewImporter(importer.NewImporterOptions{
+			Repository:      opts.Repository,
+			Validator:       opts.Validator,
+			PhoneNormalizer: phoneNormalizer,
+		}),
 	}
 }
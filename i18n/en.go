@@ -0,0 +1,87 @@
+package i18n
+
+var en = Catalog{
+	// Domain errors, keyed by apperrors code or message key.
+	"BAD_REQUEST":                "the request is malformed",
+	"VALIDATION_FAILED":          "the request is invalid",
+	"EMPTY_REQUEST":              "request body is required",
+	"INVALID_CREDENTIALS":        "invalid phone number, email or password",
+	"UNAUTHORIZED":               "authentication is required",
+	"INVALID_TOKEN":              "the token is invalid or expired",
+	"FORBIDDEN":                  "you are not allowed to perform this action",
+	"NOT_FOUND":                  "the resource was not found",
+	"METHOD_NOT_ALLOWED":         "the method is not allowed on this resource",
+	"CONFLICT":                   "the resource conflicts with existing data",
+	"PHONE_NUMBER_EXISTS":        "phone number already exists",
+	"EMAIL_EXISTS":               "email already exists",
+	"PHONE_OR_EMAIL_EXISTS":      "phone number or email already exists",
+	"INVALID_VERIFICATION_TOKEN": "the verification token is invalid or expired",
+	"INVALID_OTP":                "the code is invalid or expired",
+	"UNSUPPORTED_MEDIA_TYPE":     "the content type is not supported",
+	"INVALID_CONTENT_TYPE":       "Invalid Content-Type, only application/json is supported",
+	"OAUTH_CLIENT_NOT_FOUND":     "the OAuth client was not found",
+	"INVALID_AUTHORIZE_REQUEST":  "the client or redirect URI is invalid",
+	"REDIRECT_URI_REQUIRED":      "the authorization_code grant requires at least one redirect URI",
+	"PUBLIC_CLIENT_CREDENTIALS":  "public clients cannot use the client_credentials grant",
+	"INVALID_ACCESS_TOKEN":       "the access token is invalid, expired or revoked",
+	"INSUFFICIENT_SCOPE":         "the access token does not have the required scope",
+	"API_KEY_NOT_FOUND":          "the API key was not found",
+	"API_KEY_SCOPE":              "the API key does not have the required scope",
+	"ORGANIZATION_NOT_FOUND":     "the organization was not found",
+	"INVALID_PARENT":             "companies have no parent, estates belong to a company and divisions to an estate",
+	"MEMBER_EXISTS":              "the user is already a member of the organization",
+	"MEMBER_NOT_FOUND":           "the user is not a member of the organization",
+	"LAST_OWNER":                 "a company must keep at least one owner",
+	"INVITATION_NOT_FOUND":       "the invitation was not found",
+	"INVITATION_EXISTS":          "the phone number already has a pending invitation to the organization",
+	"INVITATION_CLOSED":          "the invitation was already accepted or revoked",
+	"INVALID_INVITATION":         "the invitation is invalid, expired, revoked or already accepted",
+	"INVALID_CSV":                "the CSV file is malformed or lacks the full_name, phone, role and organization columns",
+	"INCOMPLETE_ROW":             "the row has fewer columns than the header",
+	"DUPLICATE_ROW":              "the phone number is on an earlier row of the file",
+	"IMPORT_TOO_LARGE":           "the file must be at most 10 MB",
+	"INVALID_EXPORT_FORMAT":      "the format must be csv or jsonl",
+	"IMPERSONATION_FORBIDDEN":    "this action is not allowed while impersonating a user",
+	"ATTRIBUTE_NOT_FOUND":        "the profile attribute was not found",
+	"ATTRIBUTE_EXISTS":           "a profile attribute with this name already exists",
+	"INVALID_ATTRIBUTE_RULES":    "the rules are not supported for the type of the attribute",
+	"AVATAR_REQUIRED":            "the avatar field must contain an image file",
+	"AVATAR_TOO_LARGE":           "the image must be at most 5 MB",
+	"IMAGE_TOO_LARGE":            "the image must be at most 25 megapixels",
+	"UNSUPPORTED_IMAGE":          "the image must be a JPEG or PNG file",
+	"SMS_DISABLED":               "login codes cannot be sent, as no SMS gateway is configured",
+	"LOCKED":                     "the account is locked",
+	"TOO_MANY_REQUESTS":          "too many requests, please try again later",
+	"PAYLOAD_TOO_LARGE":          "the request is too large",
+	"INTERNAL":                   "internal server error",
+	"SERVICE_UNAVAILABLE":        "the service is unavailable",
+
+	// Email and SMS templates.
+	"email.verification.subject": "Verify your email address",
+	"email.verification.body":    "Use this token to verify your email address: {token}\n\nIt expires in 24 hours. If you did not add this address to your account, ignore this message.",
+	"sms.otp.body":               "Your login code is {code}. It expires in 5 minutes. Never share it with anyone.",
+	"sms.invitation.body":        "You are invited to join {organization} on SawitPro. Accept the invitation within 7 days with this token: {token}",
+
+	// Validation rules, keyed by validator tag.
+	"validation.required":         "required field '{field}'",
+	"validation.min":              "invalid field '{field}' with issue minimum char is {param}",
+	"validation.max":              "invalid field '{field}' with issue maximum char is {param}",
+	"validation.startswith":       "invalid field '{field}' with issue must be starts with {param}",
+	"validation.oneof":            "invalid field '{field}' with issue at least contains {param}",
+	"validation.phone":            "invalid field '{field}', must be a valid phone number",
+	"validation.email":            "invalid field '{field}', must be a valid email address",
+	"validation.required_without": "required field '{field}' when {param} is empty",
+	"validation.excluded_with":    "invalid field '{field}', must be empty when {param} is set",
+	"validation.len":              "invalid field '{field}', must be exactly {param} characters",
+	"validation.numeric":          "invalid field '{field}', must be numeric",
+	"validation.url":              "invalid field '{field}', must be a valid URL",
+	"validation.redirecturi":      "invalid field '{field}', must be an https URL, or an http URL of a loopback IP address, without a fragment",
+	"validation.validpasswd":      "invalid field '{field}', please use combination of alphanumeric and special character with lowercase and uppercase",
+	"validation.identifier":       "invalid field '{field}', must start with a lowercase letter followed by lowercase letters, digits or underscores",
+	"validation.alphanum":         "invalid field '{field}', must contain only letters and digits",
+	"validation.gte":              "invalid field '{field}', must be at least {param}",
+	"validation.lte":              "invalid field '{field}', must be at most {param}",
+	"validation.type":             "invalid field '{field}', must be a {param}",
+	"validation.unknown":          "unknown field '{field}'",
+	"validation.readonly":         "field '{field}' cannot be changed",
+}
@@ -0,0 +1,95 @@
+// Package i18n contains the message catalogs of the service. Messages are
+// keyed by error code (e.g. INVALID_CREDENTIALS) or by validation rule
+// (e.g. validation.required) and may contain {placeholders}.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	English    = "en"
+	Indonesian = "id"
+
+	// Default is used when a request asks for no supported locale.
+	Default = English
+)
+
+// Catalog maps message keys to the message in one language.
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	English:    en,
+	Indonesian: id,
+}
+
+// Locales returns the supported locales in a stable order.
+func Locales() []string {
+	output := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		output = append(output, locale)
+	}
+	sort.Strings(output)
+	return output
+}
+
+// Supported reports whether there is a catalog for locale.
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Lookup returns the message for key in locale, falling back to the default
+// locale. ok is false when neither catalog has the key.
+func Lookup(locale, key string, args map[string]string) (message string, ok bool) {
+	message, ok = catalogs[locale][key]
+	if !ok {
+		message, ok = catalogs[Default][key]
+	}
+	if !ok {
+		return "", false
+	}
+
+	for name, value := range args {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message, true
+}
+
+// Negotiate picks the supported locale preferred by an Accept-Language
+// header such as "id-ID,id;q=0.9,en;q=0.8". It returns an empty string when
+// the header names no supported locale.
+func Negotiate(acceptLanguage string) string {
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		locale := strings.SplitN(tag, "-", 2)[0]
+		if !Supported(locale) {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+
+		// Ties keep the earlier entry, as the header lists them in order
+		// of preference.
+		if q > bestQ {
+			best, bestQ = locale, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{\w+\}`)
+
+func TestCatalogs(t *testing.T) {
+	t.Run("Positive Scenario, Every key is translated in every catalog", func(t *testing.T) {
+		for _, locale := range Locales() {
+			for _, other := range Locales() {
+				for key, message := range catalogs[other] {
+					translated, ok := catalogs[locale][key]
+					if assert.True(t, ok, "%s has no translation for %q", locale, key) {
+						assert.NotEmpty(t, translated)
+						assert.ElementsMatch(t, placeholderRegex.FindAllString(message, -1), placeholderRegex.FindAllString(translated, -1),
+							"%s and %s use different placeholders for %q", locale, other, key)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Positive Scenario, Every domain error has a message", func(t *testing.T) {
+		for _, appErr := range apperrors.All() {
+			_, ok := en[appErr.Key]
+			assert.True(t, ok, "no message for %q", appErr.Key)
+		}
+	})
+
+	t.Run("Positive Scenario, Every validation rule of the API has a message", func(t *testing.T) {
+		for _, request := range []interface{}{
+			generated.LoginRequest{},
+			generated.RegisterRequest{},
+			generated.UpdateProfileRequest{},
+			generated.VerifyEmailRequest{},
+			generated.OtpStartRequest{},
+			generated.OtpVerifyRequest{},
+			generated.OAuthClientRequest{},
+			generated.APIKeyRequest{},
+			generated.OrganizationRequest{},
+			generated.OrganizationMemberRequest{},
+			generated.InvitationRequest{},
+			generated.AcceptInvitationRequest{},
+			generated.ImpersonationRequest{},
+			generated.ProfileAttributeRequest{},
+			generated.ProfileAttributeUpdateRequest{},
+		} {
+			requestType := reflect.TypeOf(request)
+			for i := 0; i < requestType.NumField(); i++ {
+				for _, rule := range strings.Split(requestType.Field(i).Tag.Get("validate"), ",") {
+					rule = strings.SplitN(rule, "=", 2)[0]
+					if rule == "" || rule == "omitempty" || rule == "dive" {
+						continue
+					}
+					_, ok := en["validation."+rule]
+					assert.True(t, ok, "no message for validation rule %q", rule)
+				}
+			}
+		}
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	t.Run("Positive Scenario, Highest quality supported locale wins", func(t *testing.T) {
+		assert.Equal(t, Indonesian, Negotiate("fr-FR,id;q=0.9,en;q=0.8"))
+		assert.Equal(t, English, Negotiate("en-US,id;q=0.5"))
+		assert.Equal(t, English, Negotiate("id;q=0.2,en-GB;q=0.7"))
+	})
+
+	t.Run("Negative Scenario, No supported locale", func(t *testing.T) {
+		assert.Equal(t, "", Negotiate(""))
+		assert.Equal(t, "", Negotiate("fr,de;q=0.5"))
+	})
+}
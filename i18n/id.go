@@ -0,0 +1,87 @@
+package i18n
+
+var id = Catalog{
+	// Domain errors, keyed by apperrors code or message key.
+	"BAD_REQUEST":                "format permintaan tidak valid",
+	"VALIDATION_FAILED":          "permintaan tidak valid",
+	"EMPTY_REQUEST":              "isi permintaan wajib diisi",
+	"INVALID_CREDENTIALS":        "nomor telepon, email, atau kata sandi salah",
+	"UNAUTHORIZED":               "autentikasi diperlukan",
+	"INVALID_TOKEN":              "token tidak valid atau sudah kedaluwarsa",
+	"FORBIDDEN":                  "Anda tidak diizinkan melakukan tindakan ini",
+	"NOT_FOUND":                  "data tidak ditemukan",
+	"METHOD_NOT_ALLOWED":         "metode tidak diizinkan untuk sumber ini",
+	"CONFLICT":                   "data bertentangan dengan data yang sudah ada",
+	"PHONE_NUMBER_EXISTS":        "nomor telepon sudah terdaftar",
+	"EMAIL_EXISTS":               "email sudah terdaftar",
+	"PHONE_OR_EMAIL_EXISTS":      "nomor telepon atau email sudah terdaftar",
+	"INVALID_VERIFICATION_TOKEN": "token verifikasi tidak valid atau sudah kedaluwarsa",
+	"INVALID_OTP":                "kode tidak valid atau sudah kedaluwarsa",
+	"UNSUPPORTED_MEDIA_TYPE":     "tipe konten tidak didukung",
+	"INVALID_CONTENT_TYPE":       "Content-Type tidak valid, hanya application/json yang didukung",
+	"OAUTH_CLIENT_NOT_FOUND":     "klien OAuth tidak ditemukan",
+	"INVALID_AUTHORIZE_REQUEST":  "klien atau URI pengalihan tidak valid",
+	"REDIRECT_URI_REQUIRED":      "grant authorization_code membutuhkan setidaknya satu URI pengalihan",
+	"PUBLIC_CLIENT_CREDENTIALS":  "klien publik tidak dapat menggunakan grant client_credentials",
+	"INVALID_ACCESS_TOKEN":       "token akses tidak valid, kedaluwarsa, atau sudah dicabut",
+	"INSUFFICIENT_SCOPE":         "token akses tidak memiliki cakupan yang diperlukan",
+	"API_KEY_NOT_FOUND":          "kunci API tidak ditemukan",
+	"API_KEY_SCOPE":              "kunci API tidak memiliki cakupan yang diperlukan",
+	"ORGANIZATION_NOT_FOUND":     "organisasi tidak ditemukan",
+	"INVALID_PARENT":             "perusahaan tidak memiliki induk, kebun berada di bawah perusahaan dan divisi di bawah kebun",
+	"MEMBER_EXISTS":              "pengguna sudah menjadi anggota organisasi",
+	"MEMBER_NOT_FOUND":           "pengguna bukan anggota organisasi",
+	"LAST_OWNER":                 "perusahaan harus memiliki setidaknya satu pemilik",
+	"INVITATION_NOT_FOUND":       "undangan tidak ditemukan",
+	"INVITATION_EXISTS":          "nomor telepon sudah memiliki undangan yang tertunda ke organisasi ini",
+	"INVITATION_CLOSED":          "undangan sudah diterima atau dibatalkan",
+	"INVALID_INVITATION":         "undangan tidak valid, kedaluwarsa, dibatalkan, atau sudah diterima",
+	"INVALID_CSV":                "file CSV rusak atau tidak memiliki kolom full_name, phone, role, dan organization",
+	"INCOMPLETE_ROW":             "baris memiliki kolom lebih sedikit dari header",
+	"DUPLICATE_ROW":              "nomor telepon sudah ada di baris sebelumnya dalam file",
+	"IMPORT_TOO_LARGE":           "ukuran file maksimal 10 MB",
+	"INVALID_EXPORT_FORMAT":      "format harus csv atau jsonl",
+	"IMPERSONATION_FORBIDDEN":    "tindakan ini tidak diizinkan saat menyamar sebagai pengguna",
+	"ATTRIBUTE_NOT_FOUND":        "atribut profil tidak ditemukan",
+	"ATTRIBUTE_EXISTS":           "atribut profil dengan nama ini sudah ada",
+	"INVALID_ATTRIBUTE_RULES":    "aturan tidak didukung untuk tipe atribut ini",
+	"AVATAR_REQUIRED":            "kolom avatar harus berisi file gambar",
+	"AVATAR_TOO_LARGE":           "ukuran gambar maksimal 5 MB",
+	"IMAGE_TOO_LARGE":            "gambar maksimal 25 megapiksel",
+	"UNSUPPORTED_IMAGE":          "gambar harus berupa file JPEG atau PNG",
+	"SMS_DISABLED":               "kode login tidak dapat dikirim karena gateway SMS belum dikonfigurasi",
+	"LOCKED":                     "akun terkunci",
+	"TOO_MANY_REQUESTS":          "terlalu banyak permintaan, silakan coba lagi nanti",
+	"PAYLOAD_TOO_LARGE":          "permintaan terlalu besar",
+	"INTERNAL":                   "terjadi kesalahan pada server",
+	"SERVICE_UNAVAILABLE":        "layanan tidak tersedia",
+
+	// Email and SMS templates.
+	"email.verification.subject": "Verifikasi alamat email Anda",
+	"email.verification.body":    "Gunakan token ini untuk memverifikasi alamat email Anda: {token}\n\nToken berlaku selama 24 jam. Jika Anda tidak menambahkan alamat ini ke akun Anda, abaikan pesan ini.",
+	"sms.otp.body":               "Kode masuk Anda adalah {code}. Berlaku selama 5 menit. Jangan berikan kode ini kepada siapa pun.",
+	"sms.invitation.body":        "Anda diundang untuk bergabung dengan {organization} di SawitPro. Terima undangan dalam 7 hari dengan token ini: {token}",
+
+	// Validation rules, keyed by validator tag.
+	"validation.required":         "kolom '{field}' wajib diisi",
+	"validation.min":              "kolom '{field}' tidak valid, minimal {param} karakter",
+	"validation.max":              "kolom '{field}' tidak valid, maksimal {param} karakter",
+	"validation.startswith":       "kolom '{field}' tidak valid, harus diawali dengan {param}",
+	"validation.oneof":            "kolom '{field}' tidak valid, harus salah satu dari {param}",
+	"validation.phone":            "kolom '{field}' tidak valid, harus berupa nomor telepon yang valid",
+	"validation.email":            "kolom '{field}' tidak valid, harus berupa alamat email yang valid",
+	"validation.required_without": "kolom '{field}' wajib diisi jika {param} kosong",
+	"validation.excluded_with":    "kolom '{field}' harus kosong jika {param} diisi",
+	"validation.len":              "kolom '{field}' tidak valid, harus tepat {param} karakter",
+	"validation.numeric":          "kolom '{field}' tidak valid, harus berupa angka",
+	"validation.url":              "kolom '{field}' tidak valid, harus berupa URL yang valid",
+	"validation.redirecturi":      "kolom '{field}' tidak valid, harus berupa URL https, atau URL http dari alamat IP loopback, tanpa fragmen",
+	"validation.validpasswd":      "kolom '{field}' tidak valid, gunakan kombinasi huruf kecil, huruf besar, angka, dan karakter khusus",
+	"validation.identifier":       "kolom '{field}' tidak valid, harus diawali huruf kecil dan hanya berisi huruf kecil, angka, atau garis bawah",
+	"validation.alphanum":         "kolom '{field}' tidak valid, hanya boleh berisi huruf dan angka",
+	"validation.gte":              "kolom '{field}' tidak valid, minimal {param}",
+	"validation.lte":              "kolom '{field}' tidak valid, maksimal {param}",
+	"validation.type":             "kolom '{field}' tidak valid, harus berupa {param}",
+	"validation.unknown":          "kolom '{field}' tidak dikenal",
+	"validation.readonly":         "kolom '{field}' tidak dapat diubah",
+}
@@ -0,0 +1,362 @@
+// Package importer registers users in bulk from CSV files, one row per
+// worker with the organization they join. It backs the admin endpoint and
+// the admin command, so both validate rows the same way as /regis.
+//
+// Importing is idempotent: rows whose phone number is already registered
+// only add the missing membership, so a file can be imported again after
+// fixing the rows that failed.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/phone"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/utils"
+)
+
+// Statuses of the rows of a report.
+const (
+	StatusCreated   = "created"
+	StatusJoined    = "joined"
+	StatusUnchanged = "unchanged"
+	StatusFailed    = "failed"
+)
+
+// MaxBytes is the largest file accepted by the admin endpoint. The admin
+// command reads files of any size.
+const MaxBytes = 10 << 20
+
+// Columns lists the columns a file must have, in any order. Other columns
+// are ignored.
+var Columns = []string{"full_name", "phone", "role", "organization"}
+
+// errDryRun rolls back the transaction of a batch in a dry run.
+var errDryRun = errors.New("dry run")
+
+// record is a row of the file, validated like the requests of the API.
+type record struct {
+	FullName     string `json:"full_name" validate:"required,min=3,max=60"`
+	PhoneNumber  string `json:"phone" validate:"required,phone"`
+	Role         string `json:"role" validate:"required,oneof=owner admin member"`
+	Organization string `json:"organization" validate:"required,numeric"`
+}
+
+// Result is the outcome of a row. Line is the line of the row in the file,
+// counting the header as line 1. UserID is zero in dry runs and for failed
+// rows. A failed row has Violations when its fields are invalid, and Err
+// otherwise.
+type Result struct {
+	Line        int
+	PhoneNumber string
+	Status      string
+	UserID      int64
+	Violations  middlewares.ValidationErrors
+	Err         *apperrors.Error
+}
+
+// Report lists the result of every row of a file, in order.
+type Report struct {
+	DryRun  bool
+	Results []Result
+}
+
+// Count returns the number of rows with status.
+func (r Report) Count(status string) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+type Importer struct {
+	Repository      repository.RepositoryInterface
+	Validator       middlewares.CustomValidatorInterface
+	PhoneNormalizer *phone.Normalizer
+	BatchSize       int
+}
+
+type NewImporterOptions struct {
+	Repository repository.RepositoryInterface
+	Validator  middlewares.CustomValidatorInterface
+	// PhoneNormalizer defaults to Indonesian numbers.
+	PhoneNormalizer *phone.Normalizer
+	// BatchSize is the number of rows imported per transaction. Defaults
+	// to 100.
+	BatchSize int
+}
+
+func NewImporter(opts NewImporterOptions) *Importer {
+	phoneNormalizer := opts.PhoneNormalizer
+	if phoneNormalizer == nil {
+		phoneNormalizer = phone.NewNormalizer(phone.NewNormalizerOptions{})
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &Importer{
+		Repository:      opts.Repository,
+		Validator:       opts.Validator,
+		PhoneNormalizer: phoneNormalizer,
+		BatchSize:       batchSize,
+	}
+}
+
+// row is a valid record ready to be imported. result is the index of its
+// result in the report.
+type row struct {
+	result   int
+	fullName string
+	role     string
+	orgID    int64
+}
+
+// Import reads a CSV file and imports its valid rows in batches, each in
+// its own transaction. A dry run checks every row against the database
+// the same way but rolls the transactions back. Invalid rows are reported
+// and skipped, while an error of the database stops the import; the
+// batches imported before it are kept.
+func (i *Importer) Import(ctx context.Context, file io.Reader, dryRun bool) (Report, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	// Short rows are reported like invalid ones.
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return Report{}, apperrors.ErrInvalidCSV.Wrap(err)
+	}
+	indexes, err := columnIndexes(header)
+	if err != nil {
+		return Report{}, apperrors.ErrInvalidCSV.Wrap(err)
+	}
+
+	report := Report{DryRun: dryRun}
+	var rows []row
+	seen := map[string]int{}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Report{}, apperrors.ErrInvalidCSV.Wrap(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		report.Results = append(report.Results, Result{
+			Line: line,
+		})
+		result := &report.Results[len(report.Results)-1]
+
+		if len(fields) < len(header) {
+			fail(result, apperrors.ErrIncompleteRow)
+			continue
+		}
+
+		rec := record{
+			FullName:     strings.TrimSpace(fields[indexes["full_name"]]),
+			PhoneNumber:  strings.TrimSpace(fields[indexes["phone"]]),
+			Role:         strings.TrimSpace(fields[indexes["role"]]),
+			Organization: strings.TrimSpace(fields[indexes["organization"]]),
+		}
+		result.PhoneNumber = rec.PhoneNumber
+
+		if err := i.Validator.Validate(rec); err != nil {
+			fail(result, err)
+			continue
+		}
+
+		phoneNumber, err := i.PhoneNormalizer.Normalize(rec.PhoneNumber)
+		if err != nil {
+			fail(result, middlewares.ValidationErrors{{
+				Field:   "phone",
+				Rule:    "phone",
+				Message: "invalid field 'phone', must be a valid phone number",
+			}})
+			continue
+		}
+		result.PhoneNumber = phoneNumber
+
+		if first, ok := seen[phoneNumber]; ok {
+			fail(result, apperrors.ErrDuplicateRow.Wrap(fmt.Errorf("phone number of line %d", first)))
+			continue
+		}
+		seen[phoneNumber] = line
+
+		orgID, _ := strconv.ParseInt(rec.Organization, 10, 64)
+		rows = append(rows, row{
+			result:   len(report.Results) - 1,
+			fullName: rec.FullName,
+			role:     rec.Role,
+			orgID:    orgID,
+		})
+	}
+
+	for start := 0; start < len(rows); start += i.BatchSize {
+		end := start + i.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		err := i.importBatch(ctx, report.Results, rows[start:end], dryRun)
+		if err != nil {
+			return Report{}, err
+		}
+	}
+
+	return report, nil
+}
+
+func (i *Importer) importBatch(ctx context.Context, results []Result, batch []row, dryRun bool) error {
+	// Results are only final once the transaction commits, so they are
+	// written to copies first.
+	batchResults := make([]Result, len(batch))
+	err := i.Repository.RunInTx(ctx, func(repo repository.RepositoryInterface) error {
+		for index, r := range batch {
+			batchResults[index] = results[r.result]
+			err := importRow(ctx, repo, r, &batchResults[index])
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	for index, r := range batch {
+		if dryRun {
+			batchResults[index].UserID = 0
+		}
+		results[r.result] = batchResults[index]
+	}
+	return nil
+}
+
+// importRow creates the user of r unless the phone number is registered,
+// and adds the user to the organization unless they already are a member.
+// It must run inside RunInTx.
+func importRow(ctx context.Context, repo repository.RepositoryInterface, r row, result *Result) error {
+	resGetOrg, err := repo.GetOrganization(ctx, repository.OrganizationFilter{
+		OrgID: &r.orgID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resGetOrg) == 0 {
+		fail(result, apperrors.ErrOrganizationNotFound)
+		return nil
+	}
+
+	resGetProfile, err := repo.GetProfile(ctx, repository.ProfileFilter{
+		Phone: &result.PhoneNumber,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Format(utils.TimestampLayout)
+	status := StatusJoined
+	var profile repository.Profile
+	if len(resGetProfile) > 0 {
+		profile = resGetProfile[0]
+	} else {
+		// Imported users have no password and log in with one-time codes.
+		status = StatusCreated
+		profile, err = repo.CreateProfile(ctx, repository.Profile{
+			FullName:  r.fullName,
+			Phone:     result.PhoneNumber,
+			Role:      repository.RoleUser,
+			Status:    1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	result.UserID = profile.UserId
+
+	resGetMember, err := repo.GetOrganizationMember(ctx, repository.OrganizationMemberFilter{
+		OrgID:  &r.orgID,
+		UserID: &profile.UserId,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The role of existing members is left as it is.
+	if len(resGetMember) > 0 {
+		result.Status = StatusUnchanged
+		return nil
+	}
+
+	_, err = repo.CreateOrganizationMember(ctx, repository.OrganizationMember{
+		OrgId:     r.orgID,
+		UserId:    profile.UserId,
+		Role:      r.role,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Status = status
+	return nil
+}
+
+// fail marks result as failed because of err, an error of the validator or
+// a domain error.
+func fail(result *Result, err error) {
+	result.Status = StatusFailed
+
+	var violations middlewares.ValidationErrors
+	if errors.As(err, &violations) {
+		result.Violations = violations
+		return
+	}
+
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) {
+		appErr = apperrors.ErrValidation
+	}
+	result.Err = appErr
+}
+
+// columnIndexes maps each of Columns to its index in header, regardless of
+// case.
+func columnIndexes(header []string) (map[string]int, error) {
+	indexes := map[string]int{}
+	for index, name := range header {
+		indexes[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+
+	for _, column := range Columns {
+		if _, ok := indexes[column]; !ok {
+			return nil, errors.New("missing column " + column)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/middlewares"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImport(t *testing.T) {
+	newImporter := func() (*Importer, *repository.MemoryRepository) {
+		repo := repository.NewMemoryRepository()
+		_, err := repo.CreateOrganization(context.Background(), repository.Organization{
+			Name: "Estate Riau",
+			Type: repository.OrgTypeCompany,
+		})
+		assert.NoError(t, err)
+
+		return NewImporter(NewImporterOptions{
+			Repository: repo,
+			Validator:  middlewares.NewValidator(middlewares.NewValidatorOptions{}),
+			BatchSize:  2,
+		}), repo
+	}
+
+	file := strings.Join([]string{
+		"Full_Name,Phone,Role,Organization",
+		"Budi Santoso,0812-3456-7890,member,1",
+		"Siti Aminah,+6281234567891,admin,1",
+		"Ab,12,boss,x",
+		"Budi Again,6281234567890,member,1",
+		"Rudi Hartono,081234567892,member,9",
+	}, "\n")
+
+	statuses := func(report Report) []string {
+		var output []string
+		for _, result := range report.Results {
+			output = append(output, result.Status)
+		}
+		return output
+	}
+
+	t.Run("Positive Scenario, Dry run reports without saving", func(t *testing.T) {
+		imp, repo := newImporter()
+		report, err := imp.Import(context.Background(), strings.NewReader(file), true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{StatusCreated, StatusCreated, StatusFailed, StatusFailed, StatusFailed}, statuses(report))
+		assert.Zero(t, report.Results[0].UserID)
+
+		profiles, err := repo.GetProfile(context.Background(), repository.ProfileFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, profiles)
+	})
+
+	t.Run("Positive Scenario, Importing again changes nothing", func(t *testing.T) {
+		imp, repo := newImporter()
+		report, err := imp.Import(context.Background(), strings.NewReader(file), false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{StatusCreated, StatusCreated, StatusFailed, StatusFailed, StatusFailed}, statuses(report))
+		assert.Equal(t, "+6281234567890", report.Results[0].PhoneNumber)
+		assert.Equal(t, 2, report.Results[0].Line)
+		assert.Len(t, report.Results[2].Violations, 4)
+		assert.ErrorIs(t, report.Results[3].Err, apperrors.ErrDuplicateRow)
+		assert.ErrorIs(t, report.Results[4].Err, apperrors.ErrOrganizationNotFound)
+
+		orgID := int64(1)
+		members, err := repo.GetOrganizationMember(context.Background(), repository.OrganizationMemberFilter{OrgID: &orgID})
+		assert.NoError(t, err)
+		assert.Len(t, members, 2)
+
+		report, err = imp.Import(context.Background(), strings.NewReader(file), false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{StatusUnchanged, StatusUnchanged, StatusFailed, StatusFailed, StatusFailed}, statuses(report))
+	})
+
+	t.Run("Negative Scenario, File without the columns", func(t *testing.T) {
+		imp, _ := newImporter()
+		_, err := imp.Import(context.Background(), strings.NewReader("name,phone\nBudi,0812"), false)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCSV)
+	})
+}
@@ -0,0 +1,133 @@
+// Package mail sends transactional email such as address verification.
+// Sender is the extension point: SMTPSender delivers real mail,
+// WriterSender writes messages to a log or file for local development, and
+// FakeSender records them for tests.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// SMTPSender delivers messages through an SMTP relay, authenticating with
+// PLAIN auth when a username is set.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+type NewSMTPSenderOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPSender(opts NewSMTPSenderOptions) *SMTPSender {
+	var auth smtp.Auth
+	if opts.Username != "" {
+		auth = smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)
+	}
+
+	return &SMTPSender{
+		addr: net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)),
+		auth: auth,
+		from: opts.From,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, format(s.from, message))
+}
+
+// WriterSender writes every message to w instead of delivering it, e.g. to
+// the server log or a file the developer can read verification links from.
+type WriterSender struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewWriterSender(w io.Writer, from string) *WriterSender {
+	return &WriterSender{
+		w:    w,
+		from: from,
+	}
+}
+
+func (s *WriterSender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.w, "%s\n", format(s.from, message))
+	return err
+}
+
+// FakeSender records the messages it is asked to send, and fails with Err
+// when it is set.
+type FakeSender struct {
+	mu       sync.Mutex
+	messages []Message
+
+	Err error
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+func (s *FakeSender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (s *FakeSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// format renders message as a plain text RFC 5322 message.
+func format(from string, message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
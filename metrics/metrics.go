@@ -0,0 +1,141 @@
+// Package metrics defines the Prometheus metrics of the service.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "userservice"
+
+// Registry holds the metrics of the service, along with those of the Go
+// runtime and of the process.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Login methods, and the reasons logins fail for.
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOTP      = "otp"
+
+	LoginReasonInvalidRequest  = "invalid_request"
+	LoginReasonUnknownUser     = "unknown_user"
+	LoginReasonWrongPassword   = "wrong_password"
+	LoginReasonUnverifiedEmail = "unverified_email"
+	LoginReasonInvalidCode     = "invalid_code"
+	LoginReasonError           = "error"
+)
+
+// Types of tokens, and the results of validating them.
+const (
+	TokenTypeSession = "session"
+	TokenTypeAPIKey  = "api_key"
+
+	TokenResultValid             = "valid"
+	TokenResultInvalid           = "invalid"
+	TokenResultInsufficientScope = "insufficient_scope"
+)
+
+// Operations on passwords.
+const (
+	PasswordHashOperationHash    = "hash"
+	PasswordHashOperationCompare = "compare"
+)
+
+var (
+	// HTTPRequestDuration is labelled with the operationId of the route in
+	// api.yml, other for requests matching none, and the status code.
+	HTTPRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by operationId and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation_id", "code"})
+
+	LoginSuccesses = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_successes_total",
+		Help:      "Successful logins by method.",
+	}, []string{"method"})
+
+	LoginFailures = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed logins by method and reason.",
+	}, []string{"method", "reason"})
+
+	Registrations = promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Users who registered themselves.",
+	})
+
+	TokenValidations = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_validations_total",
+		Help:      "Validations of session tokens and API keys by result.",
+	}, []string{"type", "result"})
+
+	// PasswordHashDuration is labelled with the operation,
+	// PasswordHashOperationHash or PasswordHashOperationCompare. Both take
+	// about as long, by design of bcrypt.
+	PasswordHashDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Duration of hashing passwords and comparing them with hashes.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 10),
+	}, []string{"operation"})
+)
+
+// Handler serves the metrics of Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB adds the connection pool statistics of db to Registry.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterCache adds the lookups counted by cacheMetrics to Registry.
+func RegisterCache(cacheMetrics *cache.Metrics) {
+	Registry.MustRegister(&cacheCollector{
+		metrics: cacheMetrics,
+		requests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "requests_total"),
+			"Cache lookups by kind of entry and result, hit, miss or error.",
+			[]string{"kind", "result"}, nil,
+		),
+	})
+}
+
+// cacheCollector reads the counts of a cache.Metrics when scraped, so the
+// cache does not depend on Prometheus.
+type cacheCollector struct {
+	metrics  *cache.Metrics
+	requests *prometheus.Desc
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for kind, counts := range c.metrics.Snapshot() {
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(counts.Hits), kind, "hit")
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(counts.Misses), kind, "miss")
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(counts.Errors), kind, "error")
+	}
+}
@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler is the central echo error handler. It maps domain errors
+// to their status and code, and every other error to a generic 500, so the
+// text of internal errors is logged but never sent to clients. Messages are
+// translated to the locale chosen by the Locale middleware.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	appErr := toAppError(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		c.Logger().Errorf("request %s failed: %v", requestID(c), err)
+	}
+
+	c.Response().Header().Set("Content-Language", GetLocale(c))
+
+	var violations ValidationErrors
+	switch {
+	case c.Request().Method == http.MethodHead:
+		err = c.NoContent(appErr.Status)
+	case errors.As(err, &violations):
+		err = c.JSON(appErr.Status, newValidationErrorResponse(c, appErr, violations))
+	default:
+		err = c.JSON(appErr.Status, newErrorResponse(c, appErr))
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func toAppError(err error) *apperrors.Error {
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return apperrors.FromStatus(httpErr.Code)
+	}
+
+	return apperrors.ErrInternal
+}
+
+func newErrorResponse(c echo.Context, appErr *apperrors.Error) generated.ErrorResponse {
+	code := appErr.Code
+	reqID := requestID(c)
+	return generated.ErrorResponse{
+		Code:      &code,
+		Message:   LocalizeError(GetLocale(c), appErr),
+		RequestID: &reqID,
+	}
+}
+
+func newValidationErrorResponse(c echo.Context, appErr *apperrors.Error, violations ValidationErrors) generated.ValidationErrorResponse {
+	code := appErr.Code
+	reqID := requestID(c)
+	locale := GetLocale(c)
+	messages := make([]string, 0, len(violations))
+	fieldErrors := make([]generated.FieldViolation, 0, len(violations))
+	for _, violation := range violations {
+		message := LocalizeViolation(locale, violation)
+		messages = append(messages, message)
+
+		params := violation.Params
+		if params == nil {
+			params = []string{}
+		}
+		fieldErrors = append(fieldErrors, generated.FieldViolation{
+			Field:   violation.Field,
+			Rule:    violation.Rule,
+			Params:  params,
+			Message: message,
+		})
+	}
+
+	return generated.ValidationErrorResponse{
+		Code:      &code,
+		Errors:    &fieldErrors,
+		Message:   strings.Join(messages, "; "),
+		RequestID: &reqID,
+	}
+}
+
+// LocalizeError translates the message of appErr, keeping its own message
+// when it has no key in the catalogs.
+func LocalizeError(locale string, appErr *apperrors.Error) string {
+	if message, ok := i18n.Lookup(locale, appErr.Key, nil); ok {
+		return message
+	}
+	return appErr.Message
+}
+
+// LocalizeViolation translates a validation message by its rule, keeping
+// the validator's message for rules the catalogs do not know.
+func LocalizeViolation(locale string, violation FieldViolation) string {
+	message, ok := i18n.Lookup(locale, "validation."+violation.Rule, map[string]string{
+		"field": violation.Field,
+		"param": strings.Join(violation.Params, " "),
+	})
+	if !ok {
+		return violation.Message
+	}
+	return message
+}
+
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	handle := func(err error) (*httptest.ResponseRecorder, generated.ErrorResponse) {
+		req := httptest.NewRequest(echo.POST, "http://localhost:1323/login", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Response().Header().Set(echo.HeaderXRequestID, "req-123")
+
+		HTTPErrorHandler(err, c)
+
+		var body generated.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	t.Run("Positive Scenario, Domain error keeps its status, code and message", func(t *testing.T) {
+		rec, body := handle(apperrors.ErrConflict.WithMessage("phone number already exists"))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "CONFLICT", *body.Code)
+		assert.Equal(t, "phone number already exists", body.Message)
+		assert.Equal(t, "req-123", *body.RequestID)
+	})
+
+	t.Run("Positive Scenario, Wrapped cause is not sent to the client", func(t *testing.T) {
+		rec, body := handle(apperrors.ErrInvalidToken.Wrap(errors.New("crypto/rsa: verification error")))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "crypto/rsa")
+		assert.Equal(t, "INVALID_TOKEN", *body.Code)
+	})
+
+	t.Run("Negative Scenario, Internal error text never leaks", func(t *testing.T) {
+		rec, body := handle(errors.New(`ERROR: relation "users" does not exist (SQLSTATE 42P01)`))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "SQLSTATE")
+		assert.Equal(t, "INTERNAL", *body.Code)
+		assert.Equal(t, "internal server error", body.Message)
+	})
+
+	t.Run("Positive Scenario, Echo errors are mapped by status", func(t *testing.T) {
+		rec, body := handle(echo.ErrNotFound)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "NOT_FOUND", *body.Code)
+	})
+
+	violations := ValidationErrors{
+		{Field: "fullName", Rule: "min", Params: []string{"3"}, Message: "fullName too short"},
+		{Field: "password", Rule: "required", Message: "password required"},
+		{Field: "reference", Rule: "uuid", Message: "reference is invalid"},
+	}
+	handleViolations := func(acceptLanguage string) (*httptest.ResponseRecorder, generated.ValidationErrorResponse) {
+		req := httptest.NewRequest(echo.POST, "http://localhost:1323/regis", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := Locale()(func(c echo.Context) error {
+			return apperrors.ErrValidation.WithMessage(violations.Error()).Wrap(violations)
+		})(c)
+		HTTPErrorHandler(err, c)
+
+		var body generated.ValidationErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	t.Run("Positive Scenario, Validation errors list every field", func(t *testing.T) {
+		rec, body := handleViolations("")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+		assert.Equal(t, "invalid field 'fullName' with issue minimum char is 3; required field 'password'; reference is invalid", body.Message)
+		assert.Len(t, *body.Errors, 3)
+		assert.Equal(t, "fullName", (*body.Errors)[0].Field)
+		assert.Equal(t, []string{}, (*body.Errors)[1].Params)
+	})
+
+	t.Run("Positive Scenario, Messages follow Accept-Language", func(t *testing.T) {
+		rec, body := handleViolations("id-ID,id;q=0.9,en;q=0.8")
+
+		assert.Equal(t, "id", rec.Header().Get("Content-Language"))
+		assert.Equal(t, "kolom 'fullName' tidak valid, minimal 3 karakter", (*body.Errors)[0].Message)
+		assert.Equal(t, "kolom 'password' wajib diisi", (*body.Errors)[1].Message)
+		assert.Equal(t, "reference is invalid", (*body.Errors)[2].Message)
+	})
+
+	t.Run("Positive Scenario, Domain errors are translated by key", func(t *testing.T) {
+		req := httptest.NewRequest(echo.POST, "http://localhost:1323/regis", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		SetLocale(c, "id")
+
+		HTTPErrorHandler(apperrors.ErrPhoneNumberExists, c)
+
+		var body generated.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "CONFLICT", *body.Code)
+		assert.Equal(t, "nomor telepon sudah terdaftar", body.Message)
+	})
+}
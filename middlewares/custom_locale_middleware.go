@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+const localeContextKey = "locale"
+
+// Locale picks the language of the response messages from the
+// Accept-Language header. Handlers may override it with the user's saved
+// preference through SetLocale.
+func Locale() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			SetLocale(c, i18n.Negotiate(c.Request().Header.Get("Accept-Language")))
+			return next(c)
+		}
+	}
+}
+
+// SetLocale sets the language of the response messages, ignoring locales
+// without a catalog so an empty or stale preference keeps the current one.
+func SetLocale(c echo.Context, locale string) {
+	if i18n.Supported(locale) {
+		c.Set(localeContextKey, locale)
+	}
+}
+
+// GetLocale returns the language of the response messages.
+func GetLocale(c echo.Context) string {
+	if locale, ok := c.Get(localeContextKey).(string); ok {
+		return locale
+	}
+	return i18n.Default
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// otherOperation labels the requests matching no operation of api.yml, such
+// as unknown paths and blobs.
+const otherOperation = "other"
+
+// RequestMetrics observes the duration of every request in
+// metrics.HTTPRequestDuration, by the operationId of its route in api.yml
+// and the status it is sent with.
+func RequestMetrics() echo.MiddlewareFunc {
+	operations := operationIDs()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			// The error is handled here to know the status it is sent with.
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			operationID, ok := operations[c.Request().Method+" "+c.Path()]
+			if !ok {
+				operationID = otherOperation
+			}
+			metrics.HTTPRequestDuration.
+				WithLabelValues(operationID, strconv.Itoa(c.Response().Status)).
+				Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// operationIDs maps the method and echo route of each operation of api.yml,
+// such as "GET /admin/users/:id", to its operationId. The generator
+// capitalizes the operationIds of the embedded spec, so the first letter is
+// lowered back to match api.yml.
+func operationIDs() map[string]string {
+	spec, err := generated.GetSwagger()
+	if err != nil {
+		panic(err)
+	}
+
+	operations := make(map[string]string)
+	for path, item := range spec.Paths.Map() {
+		route := strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method, operation := range item.Operations() {
+			operationID := []rune(operation.OperationID)
+			if len(operationID) > 0 {
+				operationID[0] = unicode.ToLower(operationID[0])
+			}
+			operations[method+" "+route] = string(operationID)
+		}
+	}
+	return operations
+}
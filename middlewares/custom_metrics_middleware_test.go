@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestMetrics())
+	e.GET("/organizations/:orgId", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST("/login", func(c echo.Context) error {
+		return apperrors.ErrInvalidCredentials
+	})
+
+	serve := func(method, target string) {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+	}
+
+	// count returns how many requests were observed with the labels.
+	count := func(operationID, code string) uint64 {
+		families, err := metrics.Registry.Gather()
+		assert.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() != "userservice_http_request_duration_seconds" {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				labels := map[string]string{}
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				if labels["operation_id"] == operationID && labels["code"] == code {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+
+	t.Run("Positive Scenario, Requests are labelled with the operationId of their route", func(t *testing.T) {
+		serve(echo.GET, "/organizations/7")
+		serve(echo.GET, "/organizations/8")
+
+		assert.Equal(t, uint64(2), count("getOrganization", "200"))
+	})
+
+	t.Run("Negative Scenario, Errors are observed with the status they are sent with", func(t *testing.T) {
+		serve(echo.POST, "/login")
+		serve(echo.GET, "/unknown")
+
+		assert.Equal(t, uint64(1), count("login", "401"))
+		assert.Equal(t, uint64(1), count("other", "404"))
+	})
+}
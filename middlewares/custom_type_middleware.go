@@ -1,27 +1,50 @@
 package middlewares
 
 import (
-	"github.com/SawitProRecruitment/UserService/constants"
-	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/apperrors"
 	"github.com/labstack/echo/v4"
-	"net/http"
 	"strings"
 )
 
+// skipContentType lists the path prefixes exempt from the JSON check. The
+// OAuth, introspection and OpenID Connect endpoints follow their RFCs,
+// which use form-encoded bodies, browser redirects and plain GET requests,
+// and check their own content. Blobs such as avatars are downloaded by
+// browsers and image loaders.
+var skipContentType = map[string]bool{
+	"swagger":     true,
+	"oauth":       true,
+	"introspect":  true,
+	"userinfo":    true,
+	".well-known": true,
+	"blobs":       true,
+}
+
+// fileContentType lists the paths that take a file instead of JSON, with
+// the media type of the file.
+var fileContentType = map[string]string{
+	"/admin/users/import": "text/csv",
+	"/profile/avatar":     "multipart/form-data",
+}
+
 func ValidateContentType() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			URI := c.Request().RequestURI
 			prefix := strings.Split(URI, "/")[1]
 
-			if prefix == "swagger" {
+			if skipContentType[prefix] {
+				return next(c)
+			}
+
+			contentType := c.Request().Header.Get("Content-Type")
+			mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+			if fileContentType[c.Request().URL.Path] == mediaType && mediaType != "" {
 				return next(c)
 			}
 
-			if contentType := c.Request().Header.Get("Content-Type"); contentType != "application/json" {
-				return c.JSON(http.StatusBadRequest, generated.ErrorResponse{
-					Message: constants.ContentTypeJson,
-				})
+			if contentType != "application/json" {
+				return apperrors.ErrInvalidContent
 			}
 			return next(c)
 		}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateContentType(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(ValidateContentType())
+	e.POST("/login", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST("/admin/users/import", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(target, contentType string) int {
+		req := httptest.NewRequest(echo.POST, target, nil)
+		req.Header.Set(echo.HeaderContentType, contentType)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Positive Scenario, JSON and the file types of file endpoints are accepted", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/login", echo.MIMEApplicationJSON))
+		assert.Equal(t, http.StatusOK, serve("/admin/users/import", "text/csv; charset=utf-8"))
+	})
+
+	t.Run("Negative Scenario, Other content types are a bad request", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("/login", echo.MIMEApplicationForm))
+		assert.Equal(t, http.StatusBadRequest, serve("/login", "text/csv"))
+	})
+}
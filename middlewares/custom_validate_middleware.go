@@ -1,10 +1,16 @@
 package middlewares
 
 import (
-	"errors"
 	"fmt"
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"github.com/SawitProRecruitment/UserService/i18n"
+	"github.com/SawitProRecruitment/UserService/phone"
 	"github.com/go-playground/validator/v10"
+	"net"
+	"net/url"
+	"reflect"
 	"regexp"
+	"strings"
 )
 
 type ValidationHandler struct {
@@ -15,6 +21,7 @@ type ValidationHandler struct {
 
 type CustomValidatorInterface interface {
 	Validate(interface{}) error
+	ValidateVar(field string, value interface{}, tag string) error
 }
 
 // CustomValidator is struct used to create request validator
@@ -23,11 +30,81 @@ type CustomValidator struct {
 	ValidatorHandlerMap map[string]ValidationHandler
 }
 
+type NewValidatorOptions struct {
+	// PhoneNormalizer backs the phone tag. Defaults to Indonesian numbers.
+	PhoneNormalizer *phone.Normalizer
+}
+
 // NewValidator is function to create custom validator struct
-func NewValidator() *CustomValidator {
+func NewValidator(opts NewValidatorOptions) *CustomValidator {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	normalizer := opts.PhoneNormalizer
+	if normalizer == nil {
+		normalizer = phone.NewNormalizer(phone.NewNormalizerOptions{})
+	}
+	v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return normalizer.Valid(fl.Field().String())
+	})
+	v.RegisterValidation("identifier", func(fl validator.FieldLevel) bool {
+		return identifierRegex.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("redirecturi", func(fl validator.FieldLevel) bool {
+		return validRedirectURI(fl.Field().String())
+	})
+
 	return &CustomValidator{
-		Validator: validator.New(),
+		Validator: v,
+	}
+}
+
+var ErrEmptyRequest = apperrors.ErrEmptyRequest
+
+// identifierRegex matches the names clients use as JSON keys, such as the
+// names of profile attributes.
+var identifierRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validRedirectURI reports whether an OAuth client may redirect to uri:
+// https URLs, or http URLs of a loopback IP address, which native apps
+// listen on (RFC 8252, section 7.3). Other schemes such as javascript: or
+// data: would run in the browser of the user, and fragments are not
+// allowed by RFC 6749.
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && ip.IsLoopback()
+	default:
+		return false
+	}
+}
+
+// FieldViolation describes one validation rule that a field failed.
+type FieldViolation struct {
+	Field   string
+	Rule    string
+	Params  []string
+	Message string
+}
+
+// ValidationErrors is returned by Validate and lists every violation of the
+// request, not only the first one.
+type ValidationErrors []FieldViolation
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, violation := range v {
+		messages = append(messages, violation.Message)
 	}
+	return strings.Join(messages, "; ")
 }
 
 // Validate is function to validate all request based on struct
@@ -35,50 +112,94 @@ func NewValidator() *CustomValidator {
 // https://github.com/go-playground/validator
 func (c *CustomValidator) Validate(i interface{}) error {
 	customValidatePassword(c)
-	if err := c.Validator.Struct(i); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			for _, err := range validationErrors {
-				var message string
-				if handler, ok := c.ValidatorHandlerMap[err.Tag()]; ok {
-					message = fmt.Sprintf("invalid fields '%s' with message: %s", err.Field(), handler.ErrorMessage)
-				} else {
-					message = buildMessageWithTag(err)
-				}
-
-				return errors.New(message)
-			}
-		}
+	err := c.Validator.Struct(i)
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*validator.InvalidValidationError); ok {
+		// A JSON body of null decodes into a nil request.
+		return ErrEmptyRequest
 	}
 
-	return nil
+	return c.violations(err, "")
 }
 
-func buildMessageWithTag(v validator.FieldError) string {
-	if v.Param() != "" {
-		switch v.Tag() {
-		case "min":
-			return fmt.Sprintf("invalid field '%s' with issue minimum char is %s", v.Field(), v.Param())
-		case "max":
-			return fmt.Sprintf("invalid field '%s' with issue maximum char is %s", v.Field(), v.Param())
-		case "startswith":
-			return fmt.Sprintf("invalid field '%s' with issue must be starts with %s", v.Field(), v.Param())
-		case "oneof":
-			return fmt.Sprintf("invalid field '%s' with issue at least contains %s", v.Field(), v.Param())
-		default:
-			return fmt.Sprintf("%s", v.Error())
+// ValidateVar validates a single value against a tag of validation rules,
+// reporting violations of field. Tags may come from the data, like the
+// rules of profile attributes, so an invalid tag is returned as an error
+// rather than panicking.
+func (c *CustomValidator) ValidateVar(field string, value interface{}, tag string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid validation rules %q: %v", tag, r)
 		}
-	} else {
-		switch v.Tag() {
-		case "required":
-			return fmt.Sprintf("required field '%s'", v.Field())
-		case "numeric":
-			return fmt.Sprintf("invalid field '%s', must be numeric", v.Field())
-		case "validpasswd":
-			return fmt.Sprintf("invalid field '%s', please use combination of alphanumeric and special character with lowercase and uppercase", v.Field())
-		default:
-			return fmt.Sprintf("%s", v.Error())
+	}()
+
+	err = c.Validator.Var(value, tag)
+	if err == nil {
+		return nil
+	}
+	return c.violations(err, field)
+}
+
+// violations converts the errors of the validator. field replaces the
+// field names, which are empty when validating a single value.
+func (c *CustomValidator) violations(err error, field string) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	violations := make(ValidationErrors, 0, len(validationErrors))
+	for _, err := range validationErrors {
+		name := err.Field()
+		if field != "" {
+			name = field
+		}
+
+		var message string
+		if handler, ok := c.ValidatorHandlerMap[err.Tag()]; ok {
+			message = fmt.Sprintf("invalid fields '%s' with message: %s", name, handler.ErrorMessage)
+		} else {
+			message = buildMessageWithTag(err, name)
 		}
+
+		violations = append(violations, FieldViolation{
+			Field:   name,
+			Rule:    err.Tag(),
+			Params:  strings.Fields(err.Param()),
+			Message: message,
+		})
+	}
+
+	return violations
+}
+
+// jsonFieldName makes validation errors report fields by the JSON name
+// clients send rather than the Go field name.
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// buildMessageWithTag returns the English message of a violation. The
+// error handler translates it again for the locale of the request.
+func buildMessageWithTag(v validator.FieldError, field string) string {
+	message, ok := i18n.Lookup(i18n.Default, "validation."+v.Tag(), map[string]string{
+		"field": field,
+		"param": v.Param(),
+	})
+	if !ok {
+		return v.Error()
 	}
+	return message
 }
 
 func customValidatePassword(c *CustomValidator) {
@@ -1,7 +1,7 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: middlewares/custom_validate_middleware.go
 
-// Package mocks is a generated GoMock package.
+// Package middlewares is a generated GoMock package.
 package middlewares
 
 import (
@@ -46,3 +46,17 @@ func (mr *MockCustomValidatorInterfaceMockRecorder) Validate(arg0 interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockCustomValidatorInterface)(nil).Validate), arg0)
 }
+
+// ValidateVar mocks base method.
+func (m *MockCustomValidatorInterface) ValidateVar(field string, value interface{}, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateVar", field, value, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateVar indicates an expected call of ValidateVar.
+func (mr *MockCustomValidatorInterfaceMockRecorder) ValidateVar(field, value, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateVar", reflect.TypeOf((*MockCustomValidatorInterface)(nil).ValidateVar), field, value, tag)
+}
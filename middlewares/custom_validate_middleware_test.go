@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	validator := NewValidator(NewValidatorOptions{})
+
+	t.Run("Positive Scenario, Valid request", func(t *testing.T) {
+		phoneNumber := "+6281234567890"
+		err := validator.Validate(&generated.LoginRequest{
+			PhoneNumber: &phoneNumber,
+			Password:    "secret",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Positive Scenario, Login with an email", func(t *testing.T) {
+		email := "staff@example.com"
+		err := validator.Validate(&generated.LoginRequest{
+			Email:    &email,
+			Password: "secret",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Negative Scenario, Login needs exactly one identifier", func(t *testing.T) {
+		err := validator.Validate(&generated.LoginRequest{
+			Password: "secret",
+		})
+		violations := err.(ValidationErrors)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "required_without", violations[0].Rule)
+
+		phoneNumber := "+6281234567890"
+		email := "staff@example.com"
+		err = validator.Validate(&generated.LoginRequest{
+			PhoneNumber: &phoneNumber,
+			Email:       &email,
+			Password:    "secret",
+		})
+		violations = err.(ValidationErrors)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "excluded_with", violations[0].Rule)
+	})
+
+	t.Run("Negative Scenario, Invalid email", func(t *testing.T) {
+		email := "not-an-email"
+		err := validator.Validate(&generated.UpdateProfileRequest{
+			Email: &email,
+		})
+		violations := err.(ValidationErrors)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "email", violations[0].Rule)
+	})
+
+	t.Run("Negative Scenario, Every violation is reported", func(t *testing.T) {
+		err := validator.Validate(&generated.RegisterRequest{
+			FullName:    "ab",
+			Password:    "short",
+			PhoneNumber: "0812",
+		})
+
+		violations, ok := err.(ValidationErrors)
+		assert.True(t, ok)
+
+		rules := map[string]string{}
+		for _, violation := range violations {
+			rules[violation.Field] = violation.Rule
+			assert.NotEmpty(t, violation.Message)
+		}
+		assert.Equal(t, "min", rules["fullName"])
+		assert.Equal(t, "min", rules["password"])
+		assert.Equal(t, "phone", rules["phoneNumber"])
+	})
+
+	t.Run("Positive Scenario, Local phone formats are accepted", func(t *testing.T) {
+		phoneNumber := "0812-3456-7890"
+		err := validator.Validate(&generated.UpdateProfileRequest{
+			PhoneNumber: &phoneNumber,
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Negative Scenario, Phone number of a country that is not allowed", func(t *testing.T) {
+		phoneNumber := "+14155552671"
+		err := validator.Validate(&generated.UpdateProfileRequest{
+			PhoneNumber: &phoneNumber,
+		})
+
+		violations := err.(ValidationErrors)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, "phone", violations[0].Rule)
+	})
+
+	t.Run("Negative Scenario, Params are reported", func(t *testing.T) {
+		fullName := "ab"
+		err := validator.Validate(&generated.UpdateProfileRequest{
+			FullName: &fullName,
+		})
+
+		violations := err.(ValidationErrors)
+		assert.Len(t, violations, 1)
+		assert.Equal(t, []string{"3"}, violations[0].Params)
+	})
+
+	t.Run("Negative Scenario, Redirect URIs must be https or loopback http", func(t *testing.T) {
+		for uri, valid := range map[string]bool{
+			"https://app.example.com/callback":  true,
+			"http://127.0.0.1:8080/callback":    true,
+			"http://[::1]/callback":             true,
+			"http://app.example.com/callback":   false,
+			"https://app.example.com/#fragment": false,
+			"javascript:alert(document.cookie)": false,
+			"data:text/html,<script></script>":  false,
+		} {
+			redirectUris := []string{uri}
+			err := validator.Validate(&generated.OAuthClientRequest{
+				Name:         "Mobile app",
+				RedirectUris: &redirectUris,
+				GrantTypes:   []string{"authorization_code"},
+			})
+			if valid {
+				assert.NoError(t, err, uri)
+			} else if assert.Error(t, err, uri) {
+				assert.Equal(t, "redirecturi", err.(ValidationErrors)[0].Rule, uri)
+			}
+		}
+	})
+
+	t.Run("Negative Scenario, Empty request", func(t *testing.T) {
+		var req *generated.LoginRequest
+		assert.ErrorIs(t, validator.Validate(req), ErrEmptyRequest)
+	})
+}
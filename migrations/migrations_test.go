@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Positive Scenario, Versions are contiguous and complete", func(t *testing.T) {
+		for _, dialect := range []string{"postgres", "sqlite"} {
+			migrations, err := Load(dialect)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, migrations)
+
+			for i, migration := range migrations {
+				assert.Equal(t, uint(i+1), migration.Version)
+				assert.NotEmpty(t, migration.Up)
+				assert.NotEmpty(t, migration.Down)
+			}
+		}
+	})
+
+	t.Run("Positive Scenario, Every dialect has the same versions", func(t *testing.T) {
+		postgresMigrations, err := Load("postgres")
+		assert.NoError(t, err)
+		sqliteMigrations, err := Load("sqlite")
+		assert.NoError(t, err)
+
+		assert.Equal(t, len(postgresMigrations), len(sqliteMigrations))
+		for i := range postgresMigrations {
+			if i < len(sqliteMigrations) {
+				assert.Equal(t, postgresMigrations[i].Name, sqliteMigrations[i].Name)
+			}
+		}
+	})
+
+	t.Run("Negative Scenario, Unknown dialect", func(t *testing.T) {
+		_, err := Load("oracle")
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrations.db")), &gorm.Config{})
+	assert.NoError(t, err)
+
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("Negative Scenario, Pending migrations on an empty database", func(t *testing.T) {
+		assert.ErrorIs(t, migrator.Check(ctx), ErrPendingMigrations)
+	})
+
+	t.Run("Positive Scenario, Up applies every migration", func(t *testing.T) {
+		assert.NoError(t, migrator.Up(ctx))
+
+		version, err := migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, migrator.Latest(), version)
+		assert.NoError(t, migrator.Check(ctx))
+	})
+
+	t.Run("Positive Scenario, Down reverts one migration", func(t *testing.T) {
+		assert.NoError(t, migrator.Down(ctx))
+
+		version, err := migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, migrator.Latest()-1, version)
+	})
+
+	t.Run("Positive Scenario, To 0 reverts everything", func(t *testing.T) {
+		assert.NoError(t, migrator.To(ctx, 0))
+
+		statuses, err := migrator.Status(ctx)
+		assert.NoError(t, err)
+		for _, status := range statuses {
+			assert.False(t, status.Applied)
+		}
+	})
+
+	t.Run("Negative Scenario, Unknown version in the database", func(t *testing.T) {
+		assert.NoError(t, db.Create(&schemaMigration{Version: 999999}).Error)
+		assert.ErrorIs(t, migrator.Check(ctx), ErrUnknownVersion)
+		assert.ErrorIs(t, migrator.Up(ctx), ErrUnknownVersion)
+	})
+}
@@ -0,0 +1,279 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownVersion    = errors.New("database schema version is unknown to this binary")
+	ErrPendingMigrations = errors.New("database schema has pending migrations")
+)
+
+// advisoryLockKey identifies the Postgres advisory lock held while
+// migrating. It is an arbitrary constant shared by every migrate process.
+const advisoryLockKey int64 = 7_142_026
+
+type schemaMigration struct {
+	Version   uint      `gorm:"column:version;PRIMARY_KEY"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes one embedded migration and whether it has been applied.
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the embedded migrations to a database and records the
+// applied versions in the schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations matching the dialect of db.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns the highest version known to this binary.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest version applied to the database, or 0 when
+// the database has no migrations yet.
+func (m *Migrator) Version(ctx context.Context) (uint, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version uint
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Status lists every embedded migration together with its applied state.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+		}
+		output = append(output, status)
+	}
+	return output, nil
+}
+
+// Check verifies that the database schema was produced by migrations this
+// binary knows about. It returns ErrUnknownVersion when the database has a
+// version that is not embedded (typically a newer deployment) and
+// ErrPendingMigrations when known migrations have not been applied yet.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = m.checkKnown(applied); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			return fmt.Errorf("%w: version %d_%s", ErrPendingMigrations, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+
+		var target uint
+		for _, migration := range m.migrations {
+			if migration.Version < version {
+				target = migration.Version
+			}
+		}
+		return m.to(ctx, target)
+	})
+}
+
+// To migrates the database up or down until the given version is the
+// highest applied one. Version 0 reverts every migration.
+func (m *Migrator) To(ctx context.Context, version uint) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func() error {
+		return m.to(ctx, version)
+	})
+}
+
+// withLock runs fn while holding a Postgres advisory lock, so migrate
+// commands started by several processes at once (e.g. one per replica)
+// apply each migration only once. SQLite serializes writers itself, so
+// there fn runs without a lock.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if m.db.Dialector.Name() != "postgres" {
+		return fn()
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+
+	// Session-level advisory locks belong to a connection, so the lock is
+	// taken and released on one connection set aside from the pool.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	return fn()
+}
+
+func (m *Migrator) to(ctx context.Context, version uint) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = m.checkKnown(applied); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			if err = m.apply(ctx, migration, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			if err = m.apply(ctx, migration, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration, up bool) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if up {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, AppliedAt: time.Now()}).Error
+		}
+
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint]schemaMigration, error) {
+	tx := m.db.WithContext(ctx)
+	err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err = tx.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	output := make(map[uint]schemaMigration, len(rows))
+	for _, row := range rows {
+		output[row.Version] = row
+	}
+	return output, nil
+}
+
+func (m *Migrator) checkKnown(applied map[uint]schemaMigration) error {
+	for version := range applied {
+		if m.find(version) == nil {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version uint) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+-- IF NOT EXISTS lets databases that were initialised from the old
+-- database.sql adopt the migration history without being recreated.
+CREATE TABLE IF NOT EXISTS users (
+    user_id SERIAL PRIMARY KEY,
+    full_name VARCHAR(100) NOT NULL,
+    password TEXT NOT NULL,
+    phone VARCHAR(25) NOT NULL,
+    status INTEGER DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT phone_unique UNIQUE (phone)
+);
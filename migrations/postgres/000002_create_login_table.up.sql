@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login (
+    login_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    ip VARCHAR(255) NOT NULL,
+    token TEXT NOT NULL,
+    expires TIMESTAMP NOT NULL,
+    requests INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
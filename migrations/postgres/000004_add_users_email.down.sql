@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS users_email_verification_hash;
+DROP INDEX IF EXISTS users_email_unique;
+
+ALTER TABLE users DROP COLUMN email_verification_expires;
+ALTER TABLE users DROP COLUMN email_verification_hash;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(254);
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN email_verification_hash VARCHAR(64);
+ALTER TABLE users ADD COLUMN email_verification_expires TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_unique ON users (LOWER(email));
+CREATE INDEX IF NOT EXISTS users_email_verification_hash ON users (email_verification_hash);
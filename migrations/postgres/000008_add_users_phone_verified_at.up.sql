@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMP;
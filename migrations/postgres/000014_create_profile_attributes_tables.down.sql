@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS profile_attribute_values;
+DROP TABLE IF EXISTS profile_attributes;
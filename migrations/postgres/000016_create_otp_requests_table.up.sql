@@ -0,0 +1,9 @@
+-- Requests for one-time login codes by phone number, registered or not, so
+-- that the rate limit does not tell which numbers are registered.
+CREATE TABLE IF NOT EXISTS otp_requests (
+    request_id SERIAL PRIMARY KEY,
+    phone VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS otp_requests_phone_created_at ON otp_requests (phone, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_email;
+DROP INDEX IF EXISTS users_verified_email_unique;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_unique ON users (LOWER(email));
@@ -0,0 +1 @@
+ALTER TABLE oauth_codes DROP COLUMN nonce;
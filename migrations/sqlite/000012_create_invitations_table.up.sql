@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    invitation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id INTEGER NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    phone VARCHAR(25) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    invited_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL ON UPDATE CASCADE,
+    token_id VARCHAR(64) NOT NULL UNIQUE,
+    expires TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS invitations_org_id ON invitations (org_id);
+CREATE INDEX IF NOT EXISTS invitations_phone ON invitations (phone);
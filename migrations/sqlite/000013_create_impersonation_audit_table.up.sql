@@ -0,0 +1,17 @@
+-- The user IDs have no foreign keys, so the audit outlives the users.
+CREATE TABLE IF NOT EXISTS impersonation_audit (
+    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id VARCHAR(64) NOT NULL,
+    actor_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    request_id VARCHAR(64) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS impersonation_audit_actor_id ON impersonation_audit (actor_id);
+CREATE INDEX IF NOT EXISTS impersonation_audit_user_id ON impersonation_audit (user_id);
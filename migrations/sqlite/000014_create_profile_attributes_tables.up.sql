@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS profile_attributes (
+    attribute_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(40) NOT NULL UNIQUE,
+    label VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    rules VARCHAR(200) NOT NULL DEFAULT '',
+    visibility VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Values are stored as text in the canonical form of the attribute type.
+CREATE TABLE IF NOT EXISTS profile_attribute_values (
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    attribute_id INTEGER NOT NULL REFERENCES profile_attributes(attribute_id) ON DELETE CASCADE ON UPDATE CASCADE,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, attribute_id)
+);
+
+CREATE INDEX IF NOT EXISTS profile_attribute_values_attribute_id ON profile_attribute_values (attribute_id);
@@ -0,0 +1,9 @@
+-- Requests for one-time login codes by phone number, registered or not, so
+-- that the rate limit does not tell which numbers are registered.
+CREATE TABLE IF NOT EXISTS otp_requests (
+    request_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    phone VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS otp_requests_phone_created_at ON otp_requests (phone, created_at);
@@ -0,0 +1,7 @@
+-- Only verified addresses are unique, so an address typed by someone who
+-- does not own it can still be claimed by its owner. Lookups compare the
+-- normalized address as it is stored, which the plain index serves.
+DROP INDEX IF EXISTS users_email_unique;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_verified_email_unique ON users (LOWER(email)) WHERE email_verified_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS users_email ON users (email);
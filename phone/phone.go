@@ -0,0 +1,143 @@
+// Package phone parses phone numbers in the formats users type them, such as
+// 0812-3456-7890, 62812 3456 7890 or +6281234567890, and normalizes them to
+// E.164 so every number is stored and looked up in one canonical form.
+package phone
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidNumber     = errors.New("invalid phone number")
+	ErrCountryNotAllowed = errors.New("phone number country code is not allowed")
+)
+
+const (
+	// maxE164Digits is the maximum number of digits of an E.164 number,
+	// including the country code.
+	maxE164Digits = 15
+	// minNationalNumberDigits rejects numbers too short to be a subscriber.
+	minNationalNumberDigits = 6
+)
+
+var (
+	defaultCountryCodes = []string{"62"}
+	separatorReplacer   = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+// Normalizer converts phone numbers to E.164, accepting only the configured
+// country codes.
+type Normalizer struct {
+	countryCodes       []string
+	defaultCountryCode string
+}
+
+type NewNormalizerOptions struct {
+	// AllowedCountryCodes are the calling codes without "+", e.g. "62".
+	// Defaults to Indonesia only.
+	AllowedCountryCodes []string
+	// DefaultCountryCode is used for national numbers starting with the
+	// trunk prefix 0. Defaults to the first allowed country code.
+	DefaultCountryCode string
+}
+
+func NewNormalizer(opts NewNormalizerOptions) *Normalizer {
+	countryCodes := opts.AllowedCountryCodes
+	if len(countryCodes) == 0 {
+		countryCodes = defaultCountryCodes
+	}
+
+	defaultCountryCode := opts.DefaultCountryCode
+	if defaultCountryCode == "" {
+		defaultCountryCode = countryCodes[0]
+	}
+
+	// Longest codes first, so "+1268" matches 1268 before 1.
+	sorted := append([]string(nil), countryCodes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	return &Normalizer{
+		countryCodes:       sorted,
+		defaultCountryCode: defaultCountryCode,
+	}
+}
+
+// NewNormalizerFromEnv reads the allowed country calling codes from
+// PHONE_COUNTRY_CODES, e.g. "62,65", and the one assumed for local numbers
+// such as 0812... from PHONE_DEFAULT_COUNTRY_CODE.
+func NewNormalizerFromEnv() *Normalizer {
+	var countryCodes []string
+	for _, code := range strings.Split(os.Getenv("PHONE_COUNTRY_CODES"), ",") {
+		if code = strings.TrimPrefix(strings.TrimSpace(code), "+"); code != "" {
+			countryCodes = append(countryCodes, code)
+		}
+	}
+
+	return NewNormalizer(NewNormalizerOptions{
+		AllowedCountryCodes: countryCodes,
+		DefaultCountryCode:  strings.TrimPrefix(os.Getenv("PHONE_DEFAULT_COUNTRY_CODE"), "+"),
+	})
+}
+
+// Normalize returns number in E.164, e.g. "+6281234567890".
+func (n *Normalizer) Normalize(number string) (string, error) {
+	digits := separatorReplacer.Replace(strings.TrimSpace(number))
+
+	international := false
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		digits, international = digits[1:], true
+	case strings.HasPrefix(digits, "00"):
+		digits, international = digits[2:], true
+	case strings.HasPrefix(digits, "0"):
+		digits = n.defaultCountryCode + digits[1:]
+	}
+
+	if digits == "" || !isDigits(digits) || len(digits) > maxE164Digits {
+		return "", ErrInvalidNumber
+	}
+
+	countryCode, ok := n.countryCode(digits)
+	if !ok {
+		if international {
+			return "", ErrCountryNotAllowed
+		}
+		return "", ErrInvalidNumber
+	}
+
+	national := digits[len(countryCode):]
+	if len(national) < minNationalNumberDigits || strings.HasPrefix(national, "0") {
+		return "", ErrInvalidNumber
+	}
+
+	return "+" + digits, nil
+}
+
+// Valid reports whether number can be normalized.
+func (n *Normalizer) Valid(number string) bool {
+	_, err := n.Normalize(number)
+	return err == nil
+}
+
+func (n *Normalizer) countryCode(digits string) (string, bool) {
+	for _, code := range n.countryCodes {
+		if strings.HasPrefix(digits, code) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer := NewNormalizer(NewNormalizerOptions{
+		AllowedCountryCodes: []string{"62", "65"},
+	})
+
+	t.Run("Positive Scenario, Common formats normalize to E.164", func(t *testing.T) {
+		for _, number := range []string{
+			"+6281234567890",
+			"081234567890",
+			"6281234567890",
+			"0812-3456-7890",
+			"+62 812 3456 7890",
+			"(0812) 3456.7890",
+			"006281234567890",
+		} {
+			normalized, err := normalizer.Normalize(number)
+			assert.NoError(t, err, number)
+			assert.Equal(t, "+6281234567890", normalized, number)
+		}
+	})
+
+	t.Run("Positive Scenario, Other allowed country codes", func(t *testing.T) {
+		normalized, err := normalizer.Normalize("+65 9123 4567")
+		assert.NoError(t, err)
+		assert.Equal(t, "+6591234567", normalized)
+	})
+
+	t.Run("Negative Scenario, Country code is not allowed", func(t *testing.T) {
+		_, err := normalizer.Normalize("+14155552671")
+		assert.ErrorIs(t, err, ErrCountryNotAllowed)
+	})
+
+	t.Run("Negative Scenario, Invalid numbers", func(t *testing.T) {
+		for _, number := range []string{
+			"",
+			"+",
+			"123",
+			"+62812",
+			"+62 0812 3456 7890",
+			"+62812345678901234",
+			"0812abc4567",
+		} {
+			_, err := normalizer.Normalize(number)
+			assert.Error(t, err, number)
+		}
+	})
+}
@@ -0,0 +1,429 @@
+// This file contains a read-through cache in front of another
+// RepositoryInterface.
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+)
+
+const (
+	// DefaultProfileTTL is how long profiles are cached unless told
+	// otherwise.
+	DefaultProfileTTL = 5 * time.Minute
+	// DefaultTokenTTL is how long logins, OAuth tokens and API keys are
+	// cached unless told otherwise. It is short because it bounds how long
+	// other instances with their own LRUStore accept a revoked token.
+	DefaultTokenTTL = time.Minute
+)
+
+// Kinds of entries, as counted in the cache metrics.
+const (
+	CacheKindProfile      = "profile"
+	CacheKindProfilePhone = "profile_phone"
+	CacheKindLogin        = "login"
+	CacheKindOAuthToken   = "oauth_token"
+	CacheKindAPIKey       = "api_key"
+)
+
+// CachedRepository is a read-through cache in front of another
+// RepositoryInterface, for the lookups made on every authenticated request:
+// profiles by user ID or phone number, logins by user ID, OAuth tokens by
+// ID and API keys by prefix. Only lookups by exactly those fields are
+// cached, and only when they find rows. The other methods go straight to
+// the wrapped repository.
+//
+// Cached profiles have no password hash nor email verification columns, so
+// that they are not copied to a shared store; lookups asking for them with
+// ProfileFilter.WithSecrets skip the cache.
+//
+// Writes made through it delete the entries they make stale, so only the
+// writes of other instances with their own LRUStore are seen late, at most
+// after the TTL. Inside RunInTx, reads skip the cache and the entries are
+// deleted once the transaction ends. Rows read while an entry is deleted
+// are not kept, so a lookup racing a replaced login cannot store the old
+// token again.
+type CachedRepository struct {
+	RepositoryInterface
+	store      cache.Store
+	metrics    *cache.Metrics
+	profileTTL time.Duration
+	tokenTTL   time.Duration
+
+	// stale collects the keys of the writes made inside a transaction. It
+	// is nil outside transactions.
+	stale *[]string
+	// invalidations counts the deletions of stale entries, shared with
+	// the copies made by RunInTx.
+	invalidations *atomic.Uint64
+}
+
+type NewCachedRepositoryOptions struct {
+	Repository RepositoryInterface
+	Store      cache.Store
+	// Metrics counts the hits and misses, a new cache.Metrics when nil.
+	Metrics *cache.Metrics
+	// ProfileTTL defaults to DefaultProfileTTL and TokenTTL to
+	// DefaultTokenTTL.
+	ProfileTTL time.Duration
+	TokenTTL   time.Duration
+}
+
+func NewCachedRepository(opts NewCachedRepositoryOptions) *CachedRepository {
+	if opts.Metrics == nil {
+		opts.Metrics = cache.NewMetrics()
+	}
+	if opts.ProfileTTL <= 0 {
+		opts.ProfileTTL = DefaultProfileTTL
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = DefaultTokenTTL
+	}
+
+	return &CachedRepository{
+		RepositoryInterface: opts.Repository,
+		store:               opts.Store,
+		metrics:             opts.Metrics,
+		profileTTL:          opts.ProfileTTL,
+		tokenTTL:            opts.TokenTTL,
+		invalidations:       &atomic.Uint64{},
+	}
+}
+
+// Metrics returns the hits and misses of the cache by kind of entry.
+func (r *CachedRepository) Metrics() *cache.Metrics {
+	return r.metrics
+}
+
+func profileCacheKey(userID int64) string {
+	return "profile:" + strconv.FormatInt(userID, 10)
+}
+
+func profilePhoneCacheKey(phone string) string {
+	return "profile:phone:" + phone
+}
+
+func loginCacheKey(userID int64) string {
+	return "login:" + strconv.FormatInt(userID, 10)
+}
+
+func oauthTokenCacheKey(tokenID string) string {
+	return "oauth_token:" + tokenID
+}
+
+func apiKeyCacheKey(prefix string) string {
+	return "api_key:" + prefix
+}
+
+func (r *CachedRepository) GetProfile(ctx context.Context, filter ProfileFilter) ([]Profile, error) {
+	switch {
+	case r.stale != nil:
+	case filter.UserID != nil && filter == (ProfileFilter{UserID: filter.UserID}):
+		return r.profileByID(ctx, *filter.UserID)
+	case filter.Phone != nil && filter == (ProfileFilter{Phone: filter.Phone}):
+		return r.profileByPhone(ctx, *filter.Phone)
+	}
+	return r.RepositoryInterface.GetProfile(ctx, filter)
+}
+
+func (r *CachedRepository) profileByID(ctx context.Context, userID int64) ([]Profile, error) {
+	return readThrough(ctx, r, CacheKindProfile, profileCacheKey(userID), r.profileTTL, func() ([]Profile, error) {
+		output, err := r.RepositoryInterface.GetProfile(ctx, ProfileFilter{UserID: &userID})
+		return withoutSecrets(output), err
+	})
+}
+
+// withoutSecrets returns profiles without the columns that are not cached.
+func withoutSecrets(profiles []Profile) []Profile {
+	for i := range profiles {
+		profiles[i].Password = ""
+		profiles[i].EmailVerificationHash = nil
+		profiles[i].EmailVerificationExpires = nil
+	}
+	return profiles
+}
+
+// profileByPhone caches the user ID of a phone number and the profile
+// under it. The ID is stale once the user changes the number, which the
+// profile then tells.
+func (r *CachedRepository) profileByPhone(ctx context.Context, phone string) ([]Profile, error) {
+	key := profilePhoneCacheKey(phone)
+	value, ok, err := r.store.Get(ctx, key)
+	if err != nil {
+		r.metrics.Error(CacheKindProfilePhone)
+	}
+	if userID, parseErr := strconv.ParseInt(string(value), 10, 64); ok && parseErr == nil {
+		output, err := r.profileByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(output) == 1 && output[0].Phone == phone {
+			r.metrics.Hit(CacheKindProfilePhone)
+			return output, nil
+		}
+	}
+	r.metrics.Miss(CacheKindProfilePhone)
+
+	generation := r.invalidations.Load()
+	output, err := r.RepositoryInterface.GetProfile(ctx, ProfileFilter{Phone: &phone})
+	if err != nil || len(output) != 1 {
+		return output, err
+	}
+	output = withoutSecrets(output)
+
+	r.set(ctx, CacheKindProfilePhone, key, []byte(strconv.FormatInt(output[0].UserId, 10)), r.profileTTL, generation)
+	if value, err := json.Marshal(output); err == nil {
+		r.set(ctx, CacheKindProfile, profileCacheKey(output[0].UserId), value, r.profileTTL, generation)
+	}
+	return output, nil
+}
+
+func (r *CachedRepository) UpdateProfile(ctx context.Context, filter ProfileFilter, patch ProfilePatch) error {
+	var keys []string
+	if filter.UserID != nil && filter == (ProfileFilter{UserID: filter.UserID}) {
+		keys = append(keys, profileCacheKey(*filter.UserID))
+	} else if len(filter.columns()) > 0 {
+		profiles, err := r.RepositoryInterface.GetProfile(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			keys = append(keys, profileCacheKey(profile.UserId))
+		}
+	}
+
+	if err := r.RepositoryInterface.UpdateProfile(ctx, filter, patch); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, keys...)
+}
+
+func (r *CachedRepository) GetLogin(ctx context.Context, filter LoginFilter) ([]LoginModel, error) {
+	if r.stale != nil || filter.UserID == nil || filter != (LoginFilter{UserID: filter.UserID}) {
+		return r.RepositoryInterface.GetLogin(ctx, filter)
+	}
+
+	return readThrough(ctx, r, CacheKindLogin, loginCacheKey(*filter.UserID), r.tokenTTL, func() ([]LoginModel, error) {
+		return r.RepositoryInterface.GetLogin(ctx, filter)
+	})
+}
+
+func (r *CachedRepository) InsertIntoLogin(ctx context.Context, login LoginModel) (LoginModel, error) {
+	output, err := r.RepositoryInterface.InsertIntoLogin(ctx, login)
+	if err != nil {
+		return output, err
+	}
+	return output, r.invalidate(ctx, loginCacheKey(login.UserId))
+}
+
+func (r *CachedRepository) UpdateLogin(ctx context.Context, filter LoginFilter, patch LoginPatch) error {
+	var keys []string
+	if filter.UserID != nil {
+		keys = append(keys, loginCacheKey(*filter.UserID))
+	} else if len(filter.columns()) > 0 {
+		logins, err := r.RepositoryInterface.GetLogin(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, login := range logins {
+			keys = append(keys, loginCacheKey(login.UserId))
+		}
+	}
+
+	if err := r.RepositoryInterface.UpdateLogin(ctx, filter, patch); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, keys...)
+}
+
+func (r *CachedRepository) GetOAuthToken(ctx context.Context, filter OAuthTokenFilter) ([]OAuthToken, error) {
+	if r.stale != nil || filter.TokenID == nil || filter != (OAuthTokenFilter{TokenID: filter.TokenID}) {
+		return r.RepositoryInterface.GetOAuthToken(ctx, filter)
+	}
+
+	return readThrough(ctx, r, CacheKindOAuthToken, oauthTokenCacheKey(*filter.TokenID), r.tokenTTL, func() ([]OAuthToken, error) {
+		return r.RepositoryInterface.GetOAuthToken(ctx, filter)
+	})
+}
+
+func (r *CachedRepository) UpdateOAuthToken(ctx context.Context, filter OAuthTokenFilter, patch OAuthTokenPatch) error {
+	keys, err := r.oauthTokenKeys(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if err := r.RepositoryInterface.UpdateOAuthToken(ctx, filter, patch); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, keys...)
+}
+
+// DeleteOAuthClient also deletes the tokens of the client.
+func (r *CachedRepository) DeleteOAuthClient(ctx context.Context, filter OAuthClientFilter) error {
+	var keys []string
+	if filter.ClientID != nil {
+		tokenKeys, err := r.oauthTokenKeys(ctx, OAuthTokenFilter{ClientID: filter.ClientID})
+		if err != nil {
+			return err
+		}
+		keys = tokenKeys
+	}
+
+	if err := r.RepositoryInterface.DeleteOAuthClient(ctx, filter); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, keys...)
+}
+
+func (r *CachedRepository) oauthTokenKeys(ctx context.Context, filter OAuthTokenFilter) ([]string, error) {
+	if filter.TokenID != nil {
+		return []string{oauthTokenCacheKey(*filter.TokenID)}, nil
+	}
+	if len(filter.columns()) == 0 {
+		return nil, nil
+	}
+
+	tokens, err := r.RepositoryInterface.GetOAuthToken(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		keys = append(keys, oauthTokenCacheKey(token.TokenId))
+	}
+	return keys, nil
+}
+
+func (r *CachedRepository) GetAPIKey(ctx context.Context, filter APIKeyFilter) ([]APIKey, error) {
+	if r.stale != nil || filter.Prefix == nil || filter != (APIKeyFilter{Prefix: filter.Prefix}) {
+		return r.RepositoryInterface.GetAPIKey(ctx, filter)
+	}
+
+	return readThrough(ctx, r, CacheKindAPIKey, apiKeyCacheKey(*filter.Prefix), r.tokenTTL, func() ([]APIKey, error) {
+		return r.RepositoryInterface.GetAPIKey(ctx, filter)
+	})
+}
+
+// UpdateAPIKey keeps the cached keys when only their last use changes, as
+// it does on every request made with them. Keys are listed uncached, so the
+// listing still shows the last use.
+func (r *CachedRepository) UpdateAPIKey(ctx context.Context, filter APIKeyFilter, patch APIKeyPatch) error {
+	var keys []string
+	if patch != (APIKeyPatch{LastUsedAt: patch.LastUsedAt}) && len(filter.columns()) > 0 {
+		apiKeys, err := r.RepositoryInterface.GetAPIKey(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, apiKey := range apiKeys {
+			keys = append(keys, apiKeyCacheKey(apiKey.Prefix))
+		}
+	}
+
+	if err := r.RepositoryInterface.UpdateAPIKey(ctx, filter, patch); err != nil {
+		return err
+	}
+	return r.invalidate(ctx, keys...)
+}
+
+// RunInTx runs fn with a CachedRepository bound to the transaction, and
+// deletes the entries made stale by its writes once it ends. They are
+// deleted even when it is rolled back, which only costs a few misses.
+func (r *CachedRepository) RunInTx(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+	stale := r.stale
+	if stale == nil {
+		stale = &[]string{}
+	}
+
+	err := r.RepositoryInterface.RunInTx(ctx, func(repo RepositoryInterface) error {
+		tx := *r
+		tx.RepositoryInterface = repo
+		tx.stale = stale
+		return fn(&tx)
+	})
+
+	// Nested transactions leave the keys to the outermost one.
+	if r.stale != nil {
+		return err
+	}
+
+	if invalidateErr := r.invalidate(ctx, *stale...); err == nil {
+		err = invalidateErr
+	}
+	return err
+}
+
+// invalidate deletes the entries under keys, or collects them inside a
+// transaction. A failure is returned rather than counted, because the
+// entries could otherwise keep a revoked token valid until they expire.
+func (r *CachedRepository) invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if r.stale != nil {
+		*r.stale = append(*r.stale, keys...)
+		return nil
+	}
+
+	r.invalidations.Add(1)
+	if err := r.store.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("deleting stale cache entries: %w", err)
+	}
+	return nil
+}
+
+// set stores value under key. Failures are only counted, the next lookup
+// reads the database again. The value was read when invalidations was at
+// generation; it is deleted again when entries were deleted since, because
+// it may be the row they replaced.
+func (r *CachedRepository) set(ctx context.Context, kind, key string, value []byte, ttl time.Duration, generation uint64) {
+	if err := r.store.Set(ctx, key, value, ttl); err != nil {
+		r.metrics.Error(kind)
+		return
+	}
+	if r.invalidations.Load() != generation {
+		if err := r.store.Delete(ctx, key); err != nil {
+			r.metrics.Error(kind)
+		}
+	}
+}
+
+// readThrough returns the rows stored under key, or the rows of load, which
+// are then stored for ttl unless there are none. Failures of the store are
+// counted and the rows read from the database instead.
+func readThrough[T any](ctx context.Context, r *CachedRepository, kind, key string, ttl time.Duration, load func() ([]T, error)) ([]T, error) {
+	value, ok, err := r.store.Get(ctx, key)
+	if err != nil {
+		r.metrics.Error(kind)
+	}
+	if ok {
+		var output []T
+		if err := json.Unmarshal(value, &output); err == nil {
+			r.metrics.Hit(kind)
+			return output, nil
+		}
+		r.metrics.Error(kind)
+	}
+	r.metrics.Miss(kind)
+
+	generation := r.invalidations.Load()
+	output, err := load()
+	if err != nil || len(output) == 0 {
+		return output, err
+	}
+
+	value, err = json.Marshal(output)
+	if err != nil {
+		r.metrics.Error(kind)
+		return output, nil
+	}
+	r.set(ctx, kind, key, value, ttl, generation)
+	return output, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+
+	newFixture := func(store cache.Store) (*CachedRepository, Profile) {
+		repo := NewCachedRepository(NewCachedRepositoryOptions{
+			Repository: NewMemoryRepository(),
+			Store:      store,
+		})
+		profile, err := repo.CreateProfile(ctx, Profile{
+			FullName: "Cached User",
+			Password: "hash",
+			Phone:    "+6281200000001",
+			Status:   1,
+		})
+		assert.NoError(t, err)
+		return repo, profile
+	}
+
+	t.Run("Positive Scenario, Profiles are read once and updates are seen at once", func(t *testing.T) {
+		repo, profile := newFixture(cache.NewLRUStore(cache.NewLRUStoreOptions{}))
+
+		for i := 0; i < 3; i++ {
+			output, err := repo.GetProfile(ctx, ProfileFilter{UserID: &profile.UserId})
+			assert.NoError(t, err)
+			assert.Len(t, output, 1)
+		}
+		assert.Equal(t, cache.Counts{Hits: 2, Misses: 1}, repo.Metrics().Snapshot()[CacheKindProfile])
+
+		name := "Renamed User"
+		err := repo.UpdateProfile(ctx, ProfileFilter{UserID: &profile.UserId}, ProfilePatch{FullName: &name})
+		assert.NoError(t, err)
+
+		output, err := repo.GetProfile(ctx, ProfileFilter{UserID: &profile.UserId})
+		assert.NoError(t, err)
+		if assert.Len(t, output, 1) {
+			assert.Equal(t, name, output[0].FullName)
+		}
+	})
+
+	t.Run("Positive Scenario, Lookups by phone number follow a number change", func(t *testing.T) {
+		repo, profile := newFixture(cache.NewLRUStore(cache.NewLRUStoreOptions{}))
+
+		oldPhone, newPhone := profile.Phone, "+6281200000002"
+		output, err := repo.GetProfile(ctx, ProfileFilter{Phone: &oldPhone})
+		assert.NoError(t, err)
+		assert.Len(t, output, 1)
+
+		err = repo.UpdateProfile(ctx, ProfileFilter{UserID: &profile.UserId}, ProfilePatch{Phone: &newPhone})
+		assert.NoError(t, err)
+
+		output, err = repo.GetProfile(ctx, ProfileFilter{Phone: &oldPhone})
+		assert.NoError(t, err)
+		assert.Empty(t, output)
+
+		for i := 0; i < 2; i++ {
+			output, err = repo.GetProfile(ctx, ProfileFilter{Phone: &newPhone})
+			assert.NoError(t, err)
+			assert.Len(t, output, 1)
+		}
+		assert.Equal(t, int64(1), repo.Metrics().Snapshot()[CacheKindProfilePhone].Hits)
+	})
+
+	t.Run("Positive Scenario, Password hashes are not cached", func(t *testing.T) {
+		store := cache.NewLRUStore(cache.NewLRUStoreOptions{})
+		repo, profile := newFixture(store)
+
+		for i := 0; i < 2; i++ {
+			output, err := repo.GetProfile(ctx, ProfileFilter{Phone: &profile.Phone})
+			assert.NoError(t, err)
+			if assert.Len(t, output, 1) {
+				assert.Empty(t, output[0].Password)
+			}
+		}
+		value, ok, err := store.Get(ctx, profileCacheKey(profile.UserId))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Contains(t, string(value), `"Password":""`)
+
+		output, err := repo.GetProfile(ctx, ProfileFilter{Phone: &profile.Phone, WithSecrets: true})
+		assert.NoError(t, err)
+		if assert.Len(t, output, 1) {
+			assert.Equal(t, "hash", output[0].Password)
+		}
+		assert.Equal(t, int64(1), repo.Metrics().Snapshot()[CacheKindProfilePhone].Hits)
+	})
+
+	t.Run("Positive Scenario, Revocations inside a transaction are seen once it commits", func(t *testing.T) {
+		repo, profile := newFixture(cache.NewLRUStore(cache.NewLRUStoreOptions{}))
+
+		prefix := "a1b2c3d4"
+		created, err := repo.CreateAPIKey(ctx, APIKey{
+			UserId:     profile.UserId,
+			Prefix:     prefix,
+			SecretHash: "hash",
+		})
+		assert.NoError(t, err)
+
+		_, err = repo.GetAPIKey(ctx, APIKeyFilter{Prefix: &prefix})
+		assert.NoError(t, err)
+
+		// Recording the last use keeps the cached key.
+		now := "2024-01-02 00:00:00"
+		err = repo.UpdateAPIKey(ctx, APIKeyFilter{KeyID: &created.KeyId}, APIKeyPatch{LastUsedAt: &now})
+		assert.NoError(t, err)
+		_, err = repo.GetAPIKey(ctx, APIKeyFilter{Prefix: &prefix})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), repo.Metrics().Snapshot()[CacheKindAPIKey].Hits)
+
+		err = repo.RunInTx(ctx, func(tx RepositoryInterface) error {
+			return tx.UpdateAPIKey(ctx, APIKeyFilter{KeyID: &created.KeyId}, APIKeyPatch{RevokedAt: &now})
+		})
+		assert.NoError(t, err)
+
+		output, err := repo.GetAPIKey(ctx, APIKeyFilter{Prefix: &prefix})
+		assert.NoError(t, err)
+		if assert.Len(t, output, 1) {
+			assert.NotNil(t, output[0].RevokedAt)
+		}
+	})
+
+	t.Run("Negative Scenario, Login replaced during a lookup is not cached", func(t *testing.T) {
+		memory := NewMemoryRepository()
+		racing := &racingLoginRepository{RepositoryInterface: memory}
+		repo := NewCachedRepository(NewCachedRepositoryOptions{
+			Repository: racing,
+			Store:      cache.NewLRUStore(cache.NewLRUStoreOptions{}),
+		})
+		profile, err := repo.CreateProfile(ctx, Profile{FullName: "Cached User", Password: "hash", Phone: "+6281200000001", Status: 1})
+		assert.NoError(t, err)
+		_, err = repo.InsertIntoLogin(ctx, LoginModel{UserId: profile.UserId, Token: "old"})
+		assert.NoError(t, err)
+
+		// The session is replaced after the lookup read the old row but
+		// before it stored it.
+		token := "new"
+		racing.afterGetLogin = func() {
+			err := repo.UpdateLogin(ctx, LoginFilter{UserID: &profile.UserId}, LoginPatch{Token: &token})
+			assert.NoError(t, err)
+		}
+		output, err := repo.GetLogin(ctx, LoginFilter{UserID: &profile.UserId})
+		assert.NoError(t, err)
+		if assert.Len(t, output, 1) {
+			assert.Equal(t, "old", output[0].Token)
+		}
+
+		output, err = repo.GetLogin(ctx, LoginFilter{UserID: &profile.UserId})
+		assert.NoError(t, err)
+		if assert.Len(t, output, 1) {
+			assert.Equal(t, token, output[0].Token)
+		}
+	})
+
+	t.Run("Negative Scenario, Lookups fall back to the database when the store fails", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+		repo, profile := newFixture(cache.NewRedisStore(cache.NewRedisStoreOptions{Client: client}))
+
+		server.SetError("LOADING")
+		output, err := repo.GetProfile(ctx, ProfileFilter{UserID: &profile.UserId})
+		assert.NoError(t, err)
+		assert.Len(t, output, 1)
+		assert.Equal(t, int64(2), repo.Metrics().Snapshot()[CacheKindProfile].Errors)
+
+		// A stale entry could keep a revoked token valid, so failing to
+		// delete one fails the write.
+		name := "Renamed User"
+		err = repo.UpdateProfile(ctx, ProfileFilter{UserID: &profile.UserId}, ProfilePatch{FullName: &name})
+		assert.Error(t, err)
+	})
+}
+
+// racingLoginRepository calls afterGetLogin once, after the next lookup of
+// logins read its rows.
+type racingLoginRepository struct {
+	RepositoryInterface
+	afterGetLogin func()
+}
+
+func (r *racingLoginRepository) GetLogin(ctx context.Context, filter LoginFilter) ([]LoginModel, error) {
+	output, err := r.RepositoryInterface.GetLogin(ctx, filter)
+	if r.afterGetLogin != nil {
+		after := r.afterGetLogin
+		r.afterGetLogin = nil
+		after()
+	}
+	return output, err
+}
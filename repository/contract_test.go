@@ -0,0 +1,867 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+)
+
+// repositoryContractSuite describes the behaviour every RepositoryInterface
+// implementation must share. It runs against the in-memory repository, a
+// temporary SQLite database and, when TEST_DATABASE_URL is set, Postgres.
+type repositoryContractSuite struct {
+	suite.Suite
+	newRepository func() RepositoryInterface
+	repo          RepositoryInterface
+}
+
+func (s *repositoryContractSuite) SetupTest() {
+	s.repo = s.newRepository()
+}
+
+func (s *repositoryContractSuite) createProfile(phone string) Profile {
+	profile, err := s.repo.CreateProfile(context.Background(), Profile{
+		FullName:  "Contract Test",
+		Password:  "hash",
+		Phone:     phone,
+		Status:    1,
+		CreatedAt: "2024-01-01 00:00:00",
+		UpdatedAt: "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+	return profile
+}
+
+func (s *repositoryContractSuite) TestCreateAndGetProfile() {
+	created := s.createProfile("+6281200000001")
+	s.NotZero(created.UserId)
+
+	phone := "+6281200000001"
+	byPhone, err := s.repo.GetProfile(context.Background(), ProfileFilter{Phone: &phone})
+	s.NoError(err)
+	s.Len(byPhone, 1)
+	s.Equal(created.UserId, byPhone[0].UserId)
+	s.Equal("Contract Test", byPhone[0].FullName)
+
+	byID, err := s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &created.UserId})
+	s.NoError(err)
+	s.Len(byID, 1)
+	s.Equal(phone, byID[0].Phone)
+
+	missing := "+6281299999999"
+	none, err := s.repo.GetProfile(context.Background(), ProfileFilter{Phone: &missing})
+	s.NoError(err)
+	s.Empty(none)
+}
+
+func (s *repositoryContractSuite) TestCreateProfileDuplicatePhone() {
+	s.createProfile("+6281200000002")
+
+	_, err := s.repo.CreateProfile(context.Background(), Profile{
+		FullName:  "Duplicate",
+		Password:  "hash",
+		Phone:     "+6281200000002",
+		CreatedAt: "2024-01-01 00:00:00",
+		UpdatedAt: "2024-01-01 00:00:00",
+	})
+	s.ErrorIs(err, ErrConflict)
+}
+
+func (s *repositoryContractSuite) TestUpdateProfile() {
+	first := s.createProfile("+6281200000003")
+	s.createProfile("+6281200000004")
+
+	fullName := "Renamed"
+	err := s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{FullName: &fullName})
+	s.NoError(err)
+
+	updated, err := s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &first.UserId})
+	s.NoError(err)
+	s.Equal("Renamed", updated[0].FullName)
+	s.Empty(updated[0].Locale)
+
+	locale := "id"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{Locale: &locale})
+	s.NoError(err)
+
+	updated, err = s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &first.UserId})
+	s.NoError(err)
+	s.Equal("id", updated[0].Locale)
+
+	verifiedAt := "2024-01-02 00:00:00"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{PhoneVerifiedAt: &verifiedAt})
+	s.NoError(err)
+
+	updated, err = s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &first.UserId})
+	s.NoError(err)
+	s.NotNil(updated[0].PhoneVerifiedAt)
+
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{ClearPhoneVerifiedAt: true})
+	s.NoError(err)
+
+	updated, err = s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &first.UserId})
+	s.NoError(err)
+	s.Nil(updated[0].PhoneVerifiedAt)
+
+	avatarKey := "avatars/1/abc"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{AvatarKey: &avatarKey})
+	s.NoError(err)
+
+	updated, err = s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &first.UserId})
+	s.NoError(err)
+	s.Equal(&avatarKey, updated[0].AvatarKey)
+
+	taken := "+6281200000004"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &first.UserId}, ProfilePatch{Phone: &taken})
+	s.ErrorIs(err, ErrConflict)
+
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{}, ProfilePatch{FullName: &fullName})
+	s.ErrorIs(err, ErrEmptyFilter)
+}
+
+func (s *repositoryContractSuite) TestSearchUsers() {
+	first := s.createProfile("+6281200000005")
+	second := s.createProfile("+6281200000006")
+	third := s.createProfile("+6281200000007")
+
+	fullName := "Budi_Santoso"
+	err := s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &second.UserId}, ProfilePatch{FullName: &fullName})
+	s.NoError(err)
+
+	page, err := s.repo.SearchUsers(context.Background(), UserSearchFilter{}, 0, 2)
+	s.NoError(err)
+	s.Require().Len(page, 2)
+	s.Equal(first.UserId, page[0].UserId)
+	s.Equal("+6281200000005", page[0].Phone)
+
+	page, err = s.repo.SearchUsers(context.Background(), UserSearchFilter{}, page[1].UserId, 2)
+	s.NoError(err)
+	s.Require().Len(page, 1)
+	s.Equal(third.UserId, page[0].UserId)
+
+	// Wildcards in the query are matched literally.
+	query := "I_S"
+	byName, err := s.repo.SearchUsers(context.Background(), UserSearchFilter{Query: &query}, 0, 10)
+	s.NoError(err)
+	s.Require().Len(byName, 1)
+	s.Equal(second.UserId, byName[0].UserId)
+
+	query = "%"
+	none, err := s.repo.SearchUsers(context.Background(), UserSearchFilter{Query: &query}, 0, 10)
+	s.NoError(err)
+	s.Empty(none)
+
+	query = "0000007"
+	byPhone, err := s.repo.SearchUsers(context.Background(), UserSearchFilter{Query: &query}, 0, 10)
+	s.NoError(err)
+	s.Require().Len(byPhone, 1)
+	s.Equal(third.UserId, byPhone[0].UserId)
+
+	org, err := s.repo.CreateOrganization(context.Background(), Organization{
+		Name: "Sawit Nusantara",
+		Type: OrgTypeCompany,
+	})
+	s.Require().NoError(err)
+	_, err = s.repo.CreateOrganizationMember(context.Background(), OrganizationMember{
+		OrgId:  org.OrgId,
+		UserId: third.UserId,
+		Role:   OrgRoleMember,
+	})
+	s.Require().NoError(err)
+
+	from, to := "2024-01-01 00:00:00", "2024-01-02 00:00:00"
+	members, err := s.repo.SearchUsers(context.Background(), UserSearchFilter{
+		OrgID:       &org.OrgId,
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+	}, 0, 10)
+	s.NoError(err)
+	s.Require().Len(members, 1)
+	s.Equal(third.UserId, members[0].UserId)
+
+	from = to
+	none, err = s.repo.SearchUsers(context.Background(), UserSearchFilter{CreatedFrom: &from}, 0, 10)
+	s.NoError(err)
+	s.Empty(none)
+}
+
+func (s *repositoryContractSuite) TestEmail() {
+	email := "Staff@Example.com"
+	created, err := s.repo.CreateProfile(context.Background(), Profile{
+		FullName:  "Contract Test",
+		Password:  "hash",
+		Phone:     "+6281200000007",
+		Email:     &email,
+		CreatedAt: "2024-01-01 00:00:00",
+		UpdatedAt: "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+
+	withoutEmail := s.createProfile("+6281200000008")
+	s.Nil(withoutEmail.Email)
+	s.createProfile("+6281200000009")
+
+	// Only verified addresses are unique.
+	duplicate := "staff@example.COM"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &withoutEmail.UserId}, ProfilePatch{Email: &duplicate})
+	s.NoError(err)
+
+	byEmail, err := s.repo.GetProfile(context.Background(), ProfileFilter{Email: &email})
+	s.NoError(err)
+	s.Len(byEmail, 1)
+	s.Equal(created.UserId, byEmail[0].UserId)
+	s.Nil(byEmail[0].EmailVerifiedAt)
+
+	hash := "verification-hash"
+	expires := "2024-01-02 00:00:00"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &created.UserId}, ProfilePatch{
+		EmailVerificationHash:    &hash,
+		EmailVerificationExpires: &expires,
+	})
+	s.NoError(err)
+
+	byHash, err := s.repo.GetProfile(context.Background(), ProfileFilter{EmailVerificationHash: &hash})
+	s.NoError(err)
+	s.Len(byHash, 1)
+	s.NotNil(byHash[0].EmailVerificationExpires)
+
+	verifiedAt := "2024-01-01 12:00:00"
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &created.UserId}, ProfilePatch{
+		EmailVerifiedAt:        &verifiedAt,
+		ClearEmailVerification: true,
+	})
+	s.NoError(err)
+
+	verified, err := s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &created.UserId})
+	s.NoError(err)
+	s.NotNil(verified[0].EmailVerifiedAt)
+	s.Nil(verified[0].EmailVerificationHash)
+	s.Nil(verified[0].EmailVerificationExpires)
+
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &withoutEmail.UserId}, ProfilePatch{EmailVerifiedAt: &verifiedAt})
+	s.ErrorIs(err, ErrConflict)
+
+	err = s.repo.UpdateProfile(context.Background(), ProfileFilter{UserID: &created.UserId}, ProfilePatch{ClearEmailVerifiedAt: true})
+	s.NoError(err)
+
+	unverified, err := s.repo.GetProfile(context.Background(), ProfileFilter{UserID: &created.UserId})
+	s.NoError(err)
+	s.Nil(unverified[0].EmailVerifiedAt)
+}
+
+func (s *repositoryContractSuite) TestLogin() {
+	profile := s.createProfile("+6281200000005")
+
+	inserted, err := s.repo.InsertIntoLogin(context.Background(), LoginModel{
+		UserId:   profile.UserId,
+		Ip:       "127.0.0.1",
+		Token:    "token",
+		Expires:  "2024-01-04 00:00:00",
+		Requests: 0,
+	})
+	s.NoError(err)
+	s.NotZero(inserted.LoginId)
+
+	requests := int64(1)
+	token := "new-token"
+	err = s.repo.UpdateLogin(context.Background(), LoginFilter{UserID: &profile.UserId}, LoginPatch{
+		Requests: &requests,
+		Token:    &token,
+	})
+	s.NoError(err)
+
+	logins, err := s.repo.GetLogin(context.Background(), LoginFilter{UserID: &profile.UserId})
+	s.NoError(err)
+	s.Len(logins, 1)
+	s.Equal(int64(1), logins[0].Requests)
+	s.Equal("new-token", logins[0].Token)
+
+	_, err = s.repo.InsertIntoLogin(context.Background(), LoginModel{
+		UserId:  profile.UserId + 1000,
+		Ip:      "127.0.0.1",
+		Token:   "token",
+		Expires: "2024-01-04 00:00:00",
+	})
+	s.Error(err)
+}
+
+func (s *repositoryContractSuite) TestLoginOTP() {
+	profile := s.createProfile("+6281200000010")
+
+	for _, createdAt := range []string{"2024-01-01 00:00:00", "2024-01-01 00:10:00"} {
+		_, err := s.repo.CreateLoginOTP(context.Background(), LoginOTP{
+			UserId:    profile.UserId,
+			CodeHash:  "hash-" + createdAt,
+			Expires:   "2024-01-01 00:15:00",
+			CreatedAt: createdAt,
+		})
+		s.Require().NoError(err)
+	}
+
+	all, err := s.repo.GetLoginOTP(context.Background(), LoginOTPFilter{UserID: &profile.UserId})
+	s.NoError(err)
+	s.Len(all, 2)
+	s.Less(all[0].OtpId, all[1].OtpId)
+	s.Nil(all[0].ConsumedAt)
+
+	since := "2024-01-01 00:05:00"
+	recent, err := s.repo.GetLoginOTP(context.Background(), LoginOTPFilter{UserID: &profile.UserId, CreatedAfter: &since})
+	s.NoError(err)
+	s.Len(recent, 1)
+	s.Equal("hash-2024-01-01 00:10:00", recent[0].CodeHash)
+
+	attempts := int64(2)
+	consumedAt := "2024-01-01 00:11:00"
+	err = s.repo.UpdateLoginOTP(context.Background(), LoginOTPFilter{OtpID: &recent[0].OtpId}, LoginOTPPatch{
+		Attempts:   &attempts,
+		ConsumedAt: &consumedAt,
+	})
+	s.NoError(err)
+
+	updated, err := s.repo.GetLoginOTP(context.Background(), LoginOTPFilter{OtpID: &recent[0].OtpId})
+	s.NoError(err)
+	s.Equal(int64(2), updated[0].Attempts)
+	s.NotNil(updated[0].ConsumedAt)
+
+	err = s.repo.UpdateLoginOTP(context.Background(), LoginOTPFilter{}, LoginOTPPatch{Attempts: &attempts})
+	s.ErrorIs(err, ErrEmptyFilter)
+
+	_, err = s.repo.CreateLoginOTP(context.Background(), LoginOTP{
+		UserId:   profile.UserId + 1000,
+		CodeHash: "hash",
+		Expires:  "2024-01-01 00:15:00",
+	})
+	s.Error(err)
+}
+
+func (s *repositoryContractSuite) TestOTPRequest() {
+	phone, other := "+6281200000030", "+6281200000031"
+	for _, createdAt := range []string{"2024-01-01 00:00:00", "2024-01-01 00:10:00"} {
+		_, err := s.repo.CreateOTPRequest(context.Background(), OTPRequest{
+			Phone:     phone,
+			CreatedAt: createdAt,
+		})
+		s.Require().NoError(err)
+	}
+	_, err := s.repo.CreateOTPRequest(context.Background(), OTPRequest{Phone: other})
+	s.Require().NoError(err)
+
+	all, err := s.repo.GetOTPRequest(context.Background(), OTPRequestFilter{Phone: &phone})
+	s.NoError(err)
+	s.Len(all, 2)
+	s.Less(all[0].RequestId, all[1].RequestId)
+
+	since := "2024-01-01 00:05:00"
+	recent, err := s.repo.GetOTPRequest(context.Background(), OTPRequestFilter{Phone: &phone, CreatedAfter: &since})
+	s.NoError(err)
+	s.Len(recent, 1)
+	s.Equal(all[1].RequestId, recent[0].RequestId)
+}
+
+func (s *repositoryContractSuite) TestOAuth() {
+	profile := s.createProfile("+6281200000011")
+	ctx := context.Background()
+
+	secretHash := "secret-hash"
+	client, err := s.repo.CreateOAuthClient(ctx, OAuthClient{
+		ClientId:     "client-1",
+		SecretHash:   &secretHash,
+		Name:         "Partner",
+		RedirectUris: "https://partner.example/callback",
+		GrantTypes:   "authorization_code refresh_token",
+		Scopes:       "profile",
+		CreatedAt:    "2024-01-01 00:00:00",
+		UpdatedAt:    "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+
+	_, err = s.repo.CreateOAuthClient(ctx, client)
+	s.ErrorIs(err, ErrConflict)
+
+	clients, err := s.repo.GetOAuthClient(ctx, OAuthClientFilter{ClientID: &client.ClientId})
+	s.NoError(err)
+	s.Require().Len(clients, 1)
+	s.Equal("Partner", clients[0].Name)
+	s.Equal(secretHash, *clients[0].SecretHash)
+
+	_, err = s.repo.CreateOAuthCode(ctx, OAuthCode{
+		CodeHash:      "code-hash",
+		ClientId:      client.ClientId,
+		UserId:        profile.UserId,
+		RedirectUri:   "https://partner.example/callback",
+		Scope:         "profile",
+		CodeChallenge: "challenge",
+		Nonce:         "nonce",
+		Expires:       "2024-01-01 00:05:00",
+		CreatedAt:     "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+
+	consumedAt := "2024-01-01 00:01:00"
+	codeHash := "code-hash"
+	err = s.repo.UpdateOAuthCode(ctx, OAuthCodeFilter{CodeHash: &codeHash}, OAuthCodePatch{ConsumedAt: &consumedAt})
+	s.NoError(err)
+
+	codes, err := s.repo.GetOAuthCode(ctx, OAuthCodeFilter{CodeHash: &codeHash})
+	s.NoError(err)
+	s.Require().Len(codes, 1)
+	s.Equal(profile.UserId, codes[0].UserId)
+	s.Equal("nonce", codes[0].Nonce)
+	s.NotNil(codes[0].ConsumedAt)
+
+	refreshHash := "refresh-hash"
+	refreshExpires := "2024-01-31 00:00:00"
+	_, err = s.repo.CreateOAuthToken(ctx, OAuthToken{
+		TokenId:        "token-1",
+		ClientId:       client.ClientId,
+		UserId:         &profile.UserId,
+		Scope:          "profile",
+		RefreshHash:    &refreshHash,
+		Expires:        "2024-01-01 01:00:00",
+		RefreshExpires: &refreshExpires,
+		CreatedAt:      "2024-01-01 00:01:00",
+	})
+	s.Require().NoError(err)
+	_, err = s.repo.CreateOAuthToken(ctx, OAuthToken{
+		TokenId:   "token-2",
+		ClientId:  client.ClientId,
+		Expires:   "2024-01-01 01:00:00",
+		CreatedAt: "2024-01-01 00:02:00",
+	})
+	s.Require().NoError(err)
+
+	tokens, err := s.repo.GetOAuthToken(ctx, OAuthTokenFilter{RefreshHash: &refreshHash})
+	s.NoError(err)
+	s.Require().Len(tokens, 1)
+	s.Equal("token-1", tokens[0].TokenId)
+	s.Equal(profile.UserId, *tokens[0].UserId)
+	s.Nil(tokens[0].RevokedAt)
+
+	revokedAt := "2024-01-01 00:03:00"
+	err = s.repo.UpdateOAuthToken(ctx, OAuthTokenFilter{TokenID: &tokens[0].TokenId}, OAuthTokenPatch{RevokedAt: &revokedAt})
+	s.NoError(err)
+
+	tokens, err = s.repo.GetOAuthToken(ctx, OAuthTokenFilter{ClientID: &client.ClientId})
+	s.NoError(err)
+	s.Require().Len(tokens, 2)
+	s.NotNil(tokens[0].RevokedAt)
+	s.Nil(tokens[1].UserId)
+	s.Nil(tokens[1].RevokedAt)
+
+	err = s.repo.UpdateOAuthToken(ctx, OAuthTokenFilter{}, OAuthTokenPatch{RevokedAt: &revokedAt})
+	s.ErrorIs(err, ErrEmptyFilter)
+	err = s.repo.DeleteOAuthClient(ctx, OAuthClientFilter{})
+	s.ErrorIs(err, ErrEmptyFilter)
+
+	err = s.repo.DeleteOAuthClient(ctx, OAuthClientFilter{ClientID: &client.ClientId})
+	s.NoError(err)
+
+	clients, err = s.repo.GetOAuthClient(ctx, OAuthClientFilter{})
+	s.NoError(err)
+	s.Empty(clients)
+	codes, err = s.repo.GetOAuthCode(ctx, OAuthCodeFilter{CodeHash: &codeHash})
+	s.NoError(err)
+	s.Empty(codes)
+	tokens, err = s.repo.GetOAuthToken(ctx, OAuthTokenFilter{ClientID: &client.ClientId})
+	s.NoError(err)
+	s.Empty(tokens)
+}
+
+func (s *repositoryContractSuite) TestAPIKey() {
+	profile := s.createProfile("+6281200000030")
+
+	expires := "2024-02-01 00:00:00"
+	created, err := s.repo.CreateAPIKey(context.Background(), APIKey{
+		UserId:     profile.UserId,
+		Name:       "Data team",
+		Prefix:     "a1b2c3d4",
+		SecretHash: "hash",
+		Scopes:     "profile:read",
+		Expires:    &expires,
+		CreatedAt:  "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+	s.NotZero(created.KeyId)
+
+	_, err = s.repo.CreateAPIKey(context.Background(), APIKey{
+		UserId:     profile.UserId,
+		Name:       "Duplicate",
+		Prefix:     "a1b2c3d4",
+		SecretHash: "other",
+	})
+	s.ErrorIs(err, ErrConflict)
+
+	prefix := "a1b2c3d4"
+	found, err := s.repo.GetAPIKey(context.Background(), APIKeyFilter{Prefix: &prefix})
+	s.NoError(err)
+	s.Require().Len(found, 1)
+	s.Equal("Data team", found[0].Name)
+	s.Equal(profile.UserId, found[0].UserId)
+	s.Nil(found[0].LastUsedAt)
+	s.Nil(found[0].RevokedAt)
+
+	usedAt := "2024-01-02 00:00:00"
+	err = s.repo.UpdateAPIKey(context.Background(), APIKeyFilter{KeyID: &created.KeyId, UserID: &profile.UserId}, APIKeyPatch{
+		LastUsedAt: &usedAt,
+		RevokedAt:  &usedAt,
+	})
+	s.NoError(err)
+
+	updated, err := s.repo.GetAPIKey(context.Background(), APIKeyFilter{UserID: &profile.UserId})
+	s.NoError(err)
+	s.Require().Len(updated, 1)
+	s.NotNil(updated[0].LastUsedAt)
+	s.NotNil(updated[0].RevokedAt)
+
+	err = s.repo.UpdateAPIKey(context.Background(), APIKeyFilter{}, APIKeyPatch{RevokedAt: &usedAt})
+	s.ErrorIs(err, ErrEmptyFilter)
+}
+
+func (s *repositoryContractSuite) TestOrganization() {
+	owner := s.createProfile("+6281200000040")
+	worker := s.createProfile("+6281200000041")
+
+	company, err := s.repo.CreateOrganization(context.Background(), Organization{
+		Name: "Sawit Nusantara",
+		Type: OrgTypeCompany,
+	})
+	s.Require().NoError(err)
+	estate, err := s.repo.CreateOrganization(context.Background(), Organization{
+		ParentId: &company.OrgId,
+		Name:     "Estate Riau",
+		Type:     OrgTypeEstate,
+	})
+	s.Require().NoError(err)
+
+	children, err := s.repo.GetOrganization(context.Background(), OrganizationFilter{ParentID: &company.OrgId})
+	s.NoError(err)
+	s.Require().Len(children, 1)
+	s.Equal(estate.OrgId, children[0].OrgId)
+	s.Equal(company.OrgId, *children[0].ParentId)
+
+	both, err := s.repo.GetOrganization(context.Background(), OrganizationFilter{OrgIDs: []int64{estate.OrgId, company.OrgId}})
+	s.NoError(err)
+	s.Require().Len(both, 2)
+	s.Nil(both[0].ParentId)
+
+	for _, member := range []OrganizationMember{
+		{OrgId: company.OrgId, UserId: owner.UserId, Role: OrgRoleOwner},
+		{OrgId: estate.OrgId, UserId: worker.UserId, Role: OrgRoleMember},
+	} {
+		_, err = s.repo.CreateOrganizationMember(context.Background(), member)
+		s.Require().NoError(err)
+	}
+
+	_, err = s.repo.CreateOrganizationMember(context.Background(), OrganizationMember{OrgId: estate.OrgId, UserId: worker.UserId, Role: OrgRoleAdmin})
+	s.ErrorIs(err, ErrConflict)
+
+	members, err := s.repo.GetOrganizationMember(context.Background(), OrganizationMemberFilter{UserID: &worker.UserId})
+	s.NoError(err)
+	s.Require().Len(members, 1)
+	s.Equal(OrgRoleMember, members[0].Role)
+
+	err = s.repo.DeleteOrganizationMember(context.Background(), OrganizationMemberFilter{})
+	s.ErrorIs(err, ErrEmptyFilter)
+
+	err = s.repo.DeleteOrganizationMember(context.Background(), OrganizationMemberFilter{OrgID: &estate.OrgId, UserID: &worker.UserId})
+	s.NoError(err)
+	members, err = s.repo.GetOrganizationMember(context.Background(), OrganizationMemberFilter{OrgID: &estate.OrgId})
+	s.NoError(err)
+	s.Empty(members)
+}
+
+func (s *repositoryContractSuite) TestInvitation() {
+	manager := s.createProfile("+6281200000050")
+	company, err := s.repo.CreateOrganization(context.Background(), Organization{
+		Name: "Sawit Nusantara",
+		Type: OrgTypeCompany,
+	})
+	s.Require().NoError(err)
+
+	created, err := s.repo.CreateInvitation(context.Background(), Invitation{
+		OrgId:     company.OrgId,
+		Phone:     "+6281200000051",
+		Role:      OrgRoleMember,
+		InvitedBy: &manager.UserId,
+		TokenId:   "token-1",
+		Expires:   "2024-01-08 00:00:00",
+		SentAt:    "2024-01-01 00:00:00",
+		CreatedAt: "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+	s.NotZero(created.InvitationId)
+
+	_, err = s.repo.CreateInvitation(context.Background(), Invitation{
+		OrgId:   company.OrgId,
+		Phone:   "+6281200000052",
+		Role:    OrgRoleMember,
+		TokenId: "token-1",
+		Expires: "2024-01-08 00:00:00",
+		SentAt:  "2024-01-01 00:00:00",
+	})
+	s.ErrorIs(err, ErrConflict)
+
+	phone := "+6281200000051"
+	found, err := s.repo.GetInvitation(context.Background(), InvitationFilter{OrgID: &company.OrgId, Phone: &phone})
+	s.NoError(err)
+	s.Require().Len(found, 1)
+	s.Equal(manager.UserId, *found[0].InvitedBy)
+	s.Nil(found[0].AcceptedAt)
+
+	tokenID := "token-2"
+	acceptedAt := "2024-01-02 00:00:00"
+	affected, err := s.repo.UpdateInvitation(context.Background(), InvitationFilter{InvitationID: &created.InvitationId}, InvitationPatch{
+		TokenID:    &tokenID,
+		AcceptedAt: &acceptedAt,
+	})
+	s.NoError(err)
+	s.Equal(int64(1), affected)
+
+	staleTokenID := "token-1"
+	affected, err = s.repo.UpdateInvitation(context.Background(), InvitationFilter{InvitationID: &created.InvitationId, TokenID: &staleTokenID}, InvitationPatch{
+		RevokedAt: &acceptedAt,
+	})
+	s.NoError(err)
+	s.Zero(affected)
+
+	updated, err := s.repo.GetInvitation(context.Background(), InvitationFilter{TokenID: &tokenID})
+	s.NoError(err)
+	s.Require().Len(updated, 1)
+	s.NotNil(updated[0].AcceptedAt)
+
+	_, err = s.repo.UpdateInvitation(context.Background(), InvitationFilter{}, InvitationPatch{RevokedAt: &acceptedAt})
+	s.ErrorIs(err, ErrEmptyFilter)
+}
+
+func (s *repositoryContractSuite) TestImpersonationAudit() {
+	admin := s.createProfile("+6281200000030")
+	user := s.createProfile("+6281200000031")
+
+	reason := "Ticket 42"
+	for _, audit := range []ImpersonationAudit{
+		{Method: "POST", Path: "/admin/users/2/impersonate", Status: 200, Reason: &reason},
+		{Method: "GET", Path: "/profile", Status: 200},
+	} {
+		audit.SessionId = "session"
+		audit.ActorId = admin.UserId
+		audit.UserId = user.UserId
+		audit.Ip = "127.0.0.1"
+		audit.RequestId = "request"
+		audit.CreatedAt = "2024-01-01 00:00:00"
+		created, err := s.repo.CreateImpersonationAudit(context.Background(), audit)
+		s.Require().NoError(err)
+		s.NotZero(created.AuditId)
+	}
+
+	sessionID := "session"
+	bySession, err := s.repo.GetImpersonationAudit(context.Background(), ImpersonationAuditFilter{SessionID: &sessionID})
+	s.NoError(err)
+	s.Require().Len(bySession, 2)
+	s.Equal(reason, *bySession[0].Reason)
+	s.Nil(bySession[1].Reason)
+	s.Equal("/profile", bySession[1].Path)
+
+	none, err := s.repo.GetImpersonationAudit(context.Background(), ImpersonationAuditFilter{ActorID: &user.UserId})
+	s.NoError(err)
+	s.Empty(none)
+}
+
+func (s *repositoryContractSuite) TestRunInTx() {
+	errRollback := errors.New("rollback")
+	phone := "+6281200000006"
+
+	err := s.repo.RunInTx(context.Background(), func(repo RepositoryInterface) error {
+		_, err := repo.CreateProfile(context.Background(), Profile{
+			FullName:  "Rolled Back",
+			Password:  "hash",
+			Phone:     phone,
+			CreatedAt: "2024-01-01 00:00:00",
+			UpdatedAt: "2024-01-01 00:00:00",
+		})
+		s.Require().NoError(err)
+		return errRollback
+	})
+	s.ErrorIs(err, errRollback)
+
+	profiles, err := s.repo.GetProfile(context.Background(), ProfileFilter{Phone: &phone})
+	s.NoError(err)
+	s.Empty(profiles)
+
+	err = s.repo.RunInTx(context.Background(), func(repo RepositoryInterface) error {
+		_, err := repo.CreateProfile(context.Background(), Profile{
+			FullName:  "Committed",
+			Password:  "hash",
+			Phone:     phone,
+			CreatedAt: "2024-01-01 00:00:00",
+			UpdatedAt: "2024-01-01 00:00:00",
+		})
+		return err
+	})
+	s.NoError(err)
+
+	profiles, err = s.repo.GetProfile(context.Background(), ProfileFilter{Phone: &phone})
+	s.NoError(err)
+	s.Len(profiles, 1)
+}
+
+func (s *repositoryContractSuite) TestProfileAttribute() {
+	user := s.createProfile("+6281200000040")
+
+	created, err := s.repo.CreateProfileAttribute(context.Background(), ProfileAttribute{
+		Name:       "employee_number",
+		Label:      "Employee number",
+		Type:       AttrTypeString,
+		Rules:      "alphanum,max=10",
+		Visibility: AttrVisibilityReadOnly,
+		CreatedAt:  "2024-01-01 00:00:00",
+		UpdatedAt:  "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+	s.NotZero(created.AttributeId)
+
+	_, err = s.repo.CreateProfileAttribute(context.Background(), ProfileAttribute{
+		Name:       "employee_number",
+		Label:      "Duplicate",
+		Type:       AttrTypeInteger,
+		Visibility: AttrVisibilityEditable,
+		CreatedAt:  "2024-01-01 00:00:00",
+		UpdatedAt:  "2024-01-01 00:00:00",
+	})
+	s.ErrorIs(err, ErrConflict)
+
+	rules := ""
+	err = s.repo.UpdateProfileAttribute(context.Background(), ProfileAttributeFilter{AttributeID: &created.AttributeId}, ProfileAttributePatch{Rules: &rules})
+	s.NoError(err)
+
+	name := "employee_number"
+	byName, err := s.repo.GetProfileAttribute(context.Background(), ProfileAttributeFilter{Name: &name})
+	s.NoError(err)
+	s.Require().Len(byName, 1)
+	s.Equal("", byName[0].Rules)
+	s.Equal(AttrTypeString, byName[0].Type)
+
+	_, err = s.repo.CreateProfileAttributeValue(context.Background(), ProfileAttributeValue{
+		UserId:      user.UserId,
+		AttributeId: created.AttributeId,
+		Value:       "E123",
+		UpdatedAt:   "2024-01-01 00:00:00",
+	})
+	s.Require().NoError(err)
+	_, err = s.repo.CreateProfileAttributeValue(context.Background(), ProfileAttributeValue{
+		UserId:      user.UserId,
+		AttributeId: created.AttributeId,
+		Value:       "E124",
+		UpdatedAt:   "2024-01-01 00:00:00",
+	})
+	s.ErrorIs(err, ErrConflict)
+
+	value := "E125"
+	filter := ProfileAttributeValueFilter{UserID: &user.UserId, AttributeID: &created.AttributeId}
+	err = s.repo.UpdateProfileAttributeValue(context.Background(), filter, ProfileAttributeValuePatch{Value: &value})
+	s.NoError(err)
+
+	values, err := s.repo.GetProfileAttributeValue(context.Background(), ProfileAttributeValueFilter{UserID: &user.UserId})
+	s.NoError(err)
+	s.Require().Len(values, 1)
+	s.Equal("E125", values[0].Value)
+
+	// Deleting an attribute deletes its values.
+	err = s.repo.DeleteProfileAttribute(context.Background(), ProfileAttributeFilter{AttributeID: &created.AttributeId})
+	s.NoError(err)
+	values, err = s.repo.GetProfileAttributeValue(context.Background(), ProfileAttributeValueFilter{UserID: &user.UserId})
+	s.NoError(err)
+	s.Empty(values)
+
+	err = s.repo.DeleteProfileAttributeValue(context.Background(), ProfileAttributeValueFilter{})
+	s.ErrorIs(err, ErrEmptyFilter)
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	suite.Run(t, &repositoryContractSuite{
+		newRepository: func() RepositoryInterface {
+			return NewMemoryRepository()
+		},
+	})
+}
+
+// The cache must not change the behaviour of the repository it wraps.
+func TestCachedRepositoryContract(t *testing.T) {
+	t.Run("LRUStore", func(t *testing.T) {
+		suite.Run(t, &repositoryContractSuite{
+			newRepository: func() RepositoryInterface {
+				return NewCachedRepository(NewCachedRepositoryOptions{
+					Repository: NewMemoryRepository(),
+					Store:      cache.NewLRUStore(cache.NewLRUStoreOptions{}),
+				})
+			},
+		})
+	})
+
+	t.Run("RedisStore", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+
+		suite.Run(t, &repositoryContractSuite{
+			newRepository: func() RepositoryInterface {
+				server.FlushAll()
+				return NewCachedRepository(NewCachedRepositoryOptions{
+					Repository: NewMemoryRepository(),
+					Store:      cache.NewRedisStore(cache.NewRedisStoreOptions{Client: client}),
+				})
+			},
+		})
+	})
+}
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	suite.Run(t, &repositoryContractSuite{
+		newRepository: func() RepositoryInterface {
+			repo := NewRepository(NewRepositoryOptions{
+				Driver: DriverSQLite,
+				Dsn:    filepath.Join(t.TempDir(), "contract.db"),
+			})
+			migrator, err := migrations.NewMigrator(repo.Db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = migrator.Up(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		},
+	})
+}
+
+func TestPostgresRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" || testing.Short() {
+		t.Skip("set TEST_DATABASE_URL to run the contract against Postgres")
+	}
+
+	repo := NewRepository(NewRepositoryOptions{Dsn: dsn})
+	migrator, err := migrations.NewMigrator(repo.Db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	suite.Run(t, &repositoryContractSuite{
+		newRepository: func() RepositoryInterface {
+			if err := repo.Db.Exec("TRUNCATE users, login, login_otp, oauth_clients, oauth_codes, oauth_tokens, api_keys, organizations, organization_members, invitations, impersonation_audit, profile_attributes, profile_attribute_values RESTART IDENTITY CASCADE").Error; err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		},
+	})
+}
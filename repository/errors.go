@@ -0,0 +1,23 @@
+// This file contains the errors returned by the repository layer.
+package repository
+
+import (
+	"errors"
+
+	"github.com/SawitProRecruitment/UserService/apperrors"
+	"gorm.io/gorm"
+)
+
+// ErrConflict is returned when a write violates a unique constraint, for
+// example a phone number that is already registered. Every implementation
+// returns it, whatever the database, so callers can use errors.Is instead
+// of inspecting driver error codes.
+var ErrConflict = apperrors.ErrConflict
+
+// normalizeError maps driver specific errors to the repository errors.
+func normalizeError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,684 @@
+// This file contains the typed filters and patches accepted by the
+// repository layer. They are translated to a fixed whitelist of columns, so
+// no caller-provided string is ever interpolated into SQL.
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var ErrEmptyFilter = errors.New("refusing to update without a filter")
+
+var (
+	profileColumns = map[string]bool{
+		"user_id":    true,
+		"full_name":  true,
+		"phone":      true,
+		"locale":     true,
+		"role":       true,
+		"updated_at": true,
+
+		"email":                      true,
+		"email_verified_at":          true,
+		"email_verification_hash":    true,
+		"email_verification_expires": true,
+		"phone_verified_at":          true,
+		"avatar_key":                 true,
+	}
+	userSearchColumns = map[string]bool{
+		"role":   true,
+		"status": true,
+	}
+	loginColumns = map[string]bool{
+		"login_id":   true,
+		"user_id":    true,
+		"ip":         true,
+		"token":      true,
+		"expires":    true,
+		"requests":   true,
+		"updated_at": true,
+	}
+	loginOTPColumns = map[string]bool{
+		"otp_id":      true,
+		"user_id":     true,
+		"attempts":    true,
+		"consumed_at": true,
+	}
+	oauthClientColumns = map[string]bool{
+		"client_id": true,
+	}
+	oauthCodeColumns = map[string]bool{
+		"code_hash":   true,
+		"consumed_at": true,
+	}
+	oauthTokenColumns = map[string]bool{
+		"token_id":     true,
+		"client_id":    true,
+		"refresh_hash": true,
+		"revoked_at":   true,
+	}
+	apiKeyColumns = map[string]bool{
+		"key_id":       true,
+		"user_id":      true,
+		"prefix":       true,
+		"last_used_at": true,
+		"revoked_at":   true,
+	}
+	organizationColumns = map[string]bool{
+		"org_id":    true,
+		"parent_id": true,
+	}
+	organizationMemberColumns = map[string]bool{
+		"org_id":  true,
+		"user_id": true,
+	}
+	invitationColumns = map[string]bool{
+		"invitation_id": true,
+		"org_id":        true,
+		"phone":         true,
+		"token_id":      true,
+		"expires":       true,
+		"sent_at":       true,
+		"accepted_at":   true,
+		"revoked_at":    true,
+	}
+	otpRequestColumns = map[string]bool{
+		"phone": true,
+	}
+	impersonationAuditColumns = map[string]bool{
+		"session_id": true,
+		"actor_id":   true,
+		"user_id":    true,
+	}
+	profileAttributeColumns = map[string]bool{
+		"attribute_id": true,
+		"name":         true,
+		"label":        true,
+		"rules":        true,
+		"visibility":   true,
+		"updated_at":   true,
+	}
+	profileAttributeValueColumns = map[string]bool{
+		"user_id":      true,
+		"attribute_id": true,
+		"value":        true,
+		"updated_at":   true,
+	}
+)
+
+// ProfileFilter selects users rows. Nil fields are ignored and set fields
+// are combined with AND.
+type ProfileFilter struct {
+	UserID                *int64
+	Phone                 *string
+	Email                 *string
+	EmailVerificationHash *string
+
+	// WithSecrets asks for the password hash and the email verification
+	// columns, which CachedRepository does not keep, so such lookups are
+	// read from the database. Other repositories always return them.
+	WithSecrets bool
+}
+
+// ProfilePatch lists the users columns to update. Nil fields are left
+// untouched, and the Clear fields set nullable columns back to NULL.
+type ProfilePatch struct {
+	FullName  *string
+	Phone     *string
+	Locale    *string
+	Role      *string
+	UpdatedAt *string
+
+	Email                    *string
+	EmailVerifiedAt          *string
+	EmailVerificationHash    *string
+	EmailVerificationExpires *string
+	// ClearEmailVerifiedAt marks the email as unverified.
+	ClearEmailVerifiedAt bool
+	// ClearEmailVerification discards the pending verification token.
+	ClearEmailVerification bool
+
+	PhoneVerifiedAt *string
+	// ClearPhoneVerifiedAt marks the phone number as unverified.
+	ClearPhoneVerifiedAt bool
+
+	AvatarKey *string
+}
+
+// UserSearchFilter selects the users an admin searches for. Nil fields are
+// ignored and set fields are combined with AND. Query matches a part of the
+// full name regardless of case, or of the phone number. OrgID selects the
+// direct members of an organization. CreatedFrom is inclusive and
+// CreatedTo exclusive.
+type UserSearchFilter struct {
+	Query       *string
+	Role        *string
+	Status      *int64
+	OrgID       *int64
+	CreatedFrom *string
+	CreatedTo   *string
+}
+
+// LoginFilter selects login rows. Nil fields are ignored and set fields are
+// combined with AND.
+type LoginFilter struct {
+	LoginID *int64
+	UserID  *int64
+}
+
+// LoginPatch lists the login columns to update. Nil fields are left
+// untouched.
+type LoginPatch struct {
+	Ip        *string
+	Token     *string
+	Expires   *string
+	Requests  *int64
+	UpdatedAt *string
+}
+
+// LoginOTPFilter selects login_otp rows. Nil fields are ignored and set
+// fields are combined with AND. CreatedAfter is inclusive.
+type LoginOTPFilter struct {
+	OtpID        *int64
+	UserID       *int64
+	CreatedAfter *string
+}
+
+// LoginOTPPatch lists the login_otp columns to update. Nil fields are left
+// untouched.
+type LoginOTPPatch struct {
+	Attempts   *int64
+	ConsumedAt *string
+}
+
+// OTPRequestFilter selects otp_requests rows. Nil fields are ignored and
+// set fields are combined with AND. CreatedAfter is inclusive.
+type OTPRequestFilter struct {
+	Phone        *string
+	CreatedAfter *string
+}
+
+// OAuthClientFilter selects oauth_clients rows. An empty filter selects
+// every client.
+type OAuthClientFilter struct {
+	ClientID *string
+}
+
+// OAuthCodeFilter selects oauth_codes rows. Nil fields are ignored and set
+// fields are combined with AND.
+type OAuthCodeFilter struct {
+	CodeHash *string
+}
+
+// OAuthCodePatch lists the oauth_codes columns to update. Nil fields are
+// left untouched.
+type OAuthCodePatch struct {
+	ConsumedAt *string
+}
+
+// OAuthTokenFilter selects oauth_tokens rows. Nil fields are ignored and
+// set fields are combined with AND.
+type OAuthTokenFilter struct {
+	TokenID     *string
+	ClientID    *string
+	RefreshHash *string
+}
+
+// OAuthTokenPatch lists the oauth_tokens columns to update. Nil fields are
+// left untouched.
+type OAuthTokenPatch struct {
+	RevokedAt *string
+}
+
+// APIKeyFilter selects api_keys rows. Nil fields are ignored and set
+// fields are combined with AND.
+type APIKeyFilter struct {
+	KeyID  *int64
+	UserID *int64
+	Prefix *string
+}
+
+// APIKeyPatch lists the api_keys columns to update. Nil fields are left
+// untouched.
+type APIKeyPatch struct {
+	LastUsedAt *string
+	RevokedAt  *string
+}
+
+// OrganizationFilter selects organizations rows. Nil fields are ignored and
+// set fields are combined with AND. OrgIDs matches any of the IDs, and an
+// empty OrgIDs is ignored.
+type OrganizationFilter struct {
+	OrgID    *int64
+	OrgIDs   []int64
+	ParentID *int64
+}
+
+// OrganizationMemberFilter selects organization_members rows. Nil fields
+// are ignored and set fields are combined with AND.
+type OrganizationMemberFilter struct {
+	OrgID  *int64
+	UserID *int64
+}
+
+// InvitationFilter selects invitations rows. Nil fields are ignored and
+// set fields are combined with AND.
+type InvitationFilter struct {
+	InvitationID *int64
+	OrgID        *int64
+	Phone        *string
+	TokenID      *string
+}
+
+// ImpersonationAuditFilter selects impersonation_audit rows. Nil fields are
+// ignored and set fields are combined with AND.
+type ImpersonationAuditFilter struct {
+	SessionID *string
+	ActorID   *int64
+	UserID    *int64
+}
+
+// ProfileAttributeFilter selects profile_attributes rows. Nil fields are
+// ignored and set fields are combined with AND.
+type ProfileAttributeFilter struct {
+	AttributeID *int64
+	Name        *string
+}
+
+// ProfileAttributePatch lists the profile_attributes columns to update. Nil
+// fields are left untouched. The type cannot change, as values are stored
+// in the form of their type.
+type ProfileAttributePatch struct {
+	Label      *string
+	Rules      *string
+	Visibility *string
+	UpdatedAt  *string
+}
+
+// ProfileAttributeValueFilter selects profile_attribute_values rows. Nil
+// fields are ignored and set fields are combined with AND.
+type ProfileAttributeValueFilter struct {
+	UserID      *int64
+	AttributeID *int64
+}
+
+// ProfileAttributeValuePatch lists the profile_attribute_values columns to
+// update. Nil fields are left untouched.
+type ProfileAttributeValuePatch struct {
+	Value     *string
+	UpdatedAt *string
+}
+
+// InvitationPatch lists the invitations columns to update. Nil fields are
+// left untouched.
+type InvitationPatch struct {
+	TokenID    *string
+	Expires    *string
+	SentAt     *string
+	AcceptedAt *string
+	RevokedAt  *string
+}
+
+func (f ProfileFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	if f.Phone != nil {
+		output["phone"] = *f.Phone
+	}
+	if f.Email != nil {
+		output["email"] = *f.Email
+	}
+	if f.EmailVerificationHash != nil {
+		output["email_verification_hash"] = *f.EmailVerificationHash
+	}
+	return output
+}
+
+func (p ProfilePatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.FullName != nil {
+		output["full_name"] = *p.FullName
+	}
+	if p.Phone != nil {
+		output["phone"] = *p.Phone
+	}
+	if p.Locale != nil {
+		output["locale"] = *p.Locale
+	}
+	if p.Role != nil {
+		output["role"] = *p.Role
+	}
+	if p.UpdatedAt != nil {
+		output["updated_at"] = *p.UpdatedAt
+	}
+	if p.Email != nil {
+		output["email"] = *p.Email
+	}
+	if p.EmailVerifiedAt != nil {
+		output["email_verified_at"] = *p.EmailVerifiedAt
+	}
+	if p.ClearEmailVerifiedAt {
+		output["email_verified_at"] = nil
+	}
+	if p.EmailVerificationHash != nil {
+		output["email_verification_hash"] = *p.EmailVerificationHash
+	}
+	if p.EmailVerificationExpires != nil {
+		output["email_verification_expires"] = *p.EmailVerificationExpires
+	}
+	if p.ClearEmailVerification {
+		output["email_verification_hash"] = nil
+		output["email_verification_expires"] = nil
+	}
+	if p.PhoneVerifiedAt != nil {
+		output["phone_verified_at"] = *p.PhoneVerifiedAt
+	}
+	if p.ClearPhoneVerifiedAt {
+		output["phone_verified_at"] = nil
+	}
+	if p.AvatarKey != nil {
+		output["avatar_key"] = *p.AvatarKey
+	}
+	return output
+}
+
+// columns returns the equality conditions of the filter; the other fields
+// are applied separately.
+func (f UserSearchFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.Role != nil {
+		output["role"] = *f.Role
+	}
+	if f.Status != nil {
+		output["status"] = *f.Status
+	}
+	return output
+}
+
+func (f LoginFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.LoginID != nil {
+		output["login_id"] = *f.LoginID
+	}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	return output
+}
+
+func (p LoginPatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.Ip != nil {
+		output["ip"] = *p.Ip
+	}
+	if p.Token != nil {
+		output["token"] = *p.Token
+	}
+	if p.Expires != nil {
+		output["expires"] = *p.Expires
+	}
+	if p.Requests != nil {
+		output["requests"] = *p.Requests
+	}
+	if p.UpdatedAt != nil {
+		output["updated_at"] = *p.UpdatedAt
+	}
+	return output
+}
+
+// columns returns the equality conditions of the filter; CreatedAfter is a
+// range and is applied separately.
+func (f LoginOTPFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.OtpID != nil {
+		output["otp_id"] = *f.OtpID
+	}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	return output
+}
+
+func (p LoginOTPPatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.Attempts != nil {
+		output["attempts"] = *p.Attempts
+	}
+	if p.ConsumedAt != nil {
+		output["consumed_at"] = *p.ConsumedAt
+	}
+	return output
+}
+
+// columns returns the equality conditions of the filter; CreatedAfter is a
+// range and is applied separately.
+func (f OTPRequestFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.Phone != nil {
+		output["phone"] = *f.Phone
+	}
+	return output
+}
+
+func (f OAuthClientFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.ClientID != nil {
+		output["client_id"] = *f.ClientID
+	}
+	return output
+}
+
+func (f OAuthCodeFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.CodeHash != nil {
+		output["code_hash"] = *f.CodeHash
+	}
+	return output
+}
+
+func (p OAuthCodePatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.ConsumedAt != nil {
+		output["consumed_at"] = *p.ConsumedAt
+	}
+	return output
+}
+
+func (f OAuthTokenFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.TokenID != nil {
+		output["token_id"] = *f.TokenID
+	}
+	if f.ClientID != nil {
+		output["client_id"] = *f.ClientID
+	}
+	if f.RefreshHash != nil {
+		output["refresh_hash"] = *f.RefreshHash
+	}
+	return output
+}
+
+func (p OAuthTokenPatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.RevokedAt != nil {
+		output["revoked_at"] = *p.RevokedAt
+	}
+	return output
+}
+
+func (f APIKeyFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.KeyID != nil {
+		output["key_id"] = *f.KeyID
+	}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	if f.Prefix != nil {
+		output["prefix"] = *f.Prefix
+	}
+	return output
+}
+
+func (p APIKeyPatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.LastUsedAt != nil {
+		output["last_used_at"] = *p.LastUsedAt
+	}
+	if p.RevokedAt != nil {
+		output["revoked_at"] = *p.RevokedAt
+	}
+	return output
+}
+
+// columns returns the equality conditions of the filter; OrgIDs is a list
+// and is applied separately.
+func (f OrganizationFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.OrgID != nil {
+		output["org_id"] = *f.OrgID
+	}
+	if f.ParentID != nil {
+		output["parent_id"] = *f.ParentID
+	}
+	return output
+}
+
+func (f OrganizationMemberFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.OrgID != nil {
+		output["org_id"] = *f.OrgID
+	}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	return output
+}
+
+func (f InvitationFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.InvitationID != nil {
+		output["invitation_id"] = *f.InvitationID
+	}
+	if f.OrgID != nil {
+		output["org_id"] = *f.OrgID
+	}
+	if f.Phone != nil {
+		output["phone"] = *f.Phone
+	}
+	if f.TokenID != nil {
+		output["token_id"] = *f.TokenID
+	}
+	return output
+}
+
+func (f ImpersonationAuditFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.SessionID != nil {
+		output["session_id"] = *f.SessionID
+	}
+	if f.ActorID != nil {
+		output["actor_id"] = *f.ActorID
+	}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	return output
+}
+
+func (f ProfileAttributeFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.AttributeID != nil {
+		output["attribute_id"] = *f.AttributeID
+	}
+	if f.Name != nil {
+		output["name"] = *f.Name
+	}
+	return output
+}
+
+func (p ProfileAttributePatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.Label != nil {
+		output["label"] = *p.Label
+	}
+	if p.Rules != nil {
+		output["rules"] = *p.Rules
+	}
+	if p.Visibility != nil {
+		output["visibility"] = *p.Visibility
+	}
+	if p.UpdatedAt != nil {
+		output["updated_at"] = *p.UpdatedAt
+	}
+	return output
+}
+
+func (f ProfileAttributeValueFilter) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if f.UserID != nil {
+		output["user_id"] = *f.UserID
+	}
+	if f.AttributeID != nil {
+		output["attribute_id"] = *f.AttributeID
+	}
+	return output
+}
+
+func (p ProfileAttributeValuePatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.Value != nil {
+		output["value"] = *p.Value
+	}
+	if p.UpdatedAt != nil {
+		output["updated_at"] = *p.UpdatedAt
+	}
+	return output
+}
+
+func (p InvitationPatch) columns() map[string]interface{} {
+	output := map[string]interface{}{}
+	if p.TokenID != nil {
+		output["token_id"] = *p.TokenID
+	}
+	if p.Expires != nil {
+		output["expires"] = *p.Expires
+	}
+	if p.SentAt != nil {
+		output["sent_at"] = *p.SentAt
+	}
+	if p.AcceptedAt != nil {
+		output["accepted_at"] = *p.AcceptedAt
+	}
+	if p.RevokedAt != nil {
+		output["revoked_at"] = *p.RevokedAt
+	}
+	return output
+}
+
+// where adds one equality condition per column, rejecting any column that
+// is not in the whitelist.
+func where(tx *gorm.DB, whitelist map[string]bool, columns map[string]interface{}) (*gorm.DB, error) {
+	for column, value := range columns {
+		if !whitelist[column] {
+			return nil, fmt.Errorf("column %q is not allowed in a filter", column)
+		}
+		tx = tx.Where(fmt.Sprintf("%s = ?", column), value)
+	}
+	return tx, nil
+}
+
+// set validates the columns of a patch against the whitelist.
+func set(whitelist map[string]bool, columns map[string]interface{}) (map[string]interface{}, error) {
+	for column := range columns {
+		if !whitelist[column] {
+			return nil, fmt.Errorf("column %q is not allowed in a patch", column)
+		}
+	}
+	return columns, nil
+}
@@ -2,13 +2,13 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"strings"
 )
 
 func (r *Repository) CreateProfile(ctx context.Context, profile Profile) (output Profile, err error) {
 	tx := r.Db.WithContext(ctx).Create(&profile)
 	if tx.Error != nil {
-		err = tx.Error
+		err = normalizeError(tx.Error)
 	}
 
 	output = profile
@@ -16,11 +16,12 @@ func (r *Repository) CreateProfile(ctx context.Context, profile Profile) (output
 	return
 }
 
-func (r *Repository) GetProfile(ctx context.Context, filter map[string]interface{}) (output []Profile, err error) {
-	tx := r.Db.WithContext(ctx).Select("user_id, full_name, password, phone, status, created_at, updated_at")
+func (r *Repository) GetProfile(ctx context.Context, filter ProfileFilter) (output []Profile, err error) {
+	tx := r.Db.WithContext(ctx).Select("user_id, full_name, password, phone, status, locale, role, created_at, updated_at, email, email_verified_at, email_verification_hash, email_verification_expires, phone_verified_at, avatar_key")
 
-	for k, v := range filter {
-		tx = tx.Where(fmt.Sprintf("%s = ?", k), v)
+	tx, err = where(tx, profileColumns, filter.columns())
+	if err != nil {
+		return
 	}
 
 	find := tx.Find(&output)
@@ -28,26 +29,70 @@ func (r *Repository) GetProfile(ctx context.Context, filter map[string]interface
 	return
 }
 
-func (r *Repository) UpdateProfile(ctx context.Context, updatedBy map[string]interface{}, updatedData map[string]interface{}) error {
-	tx := r.Db.Table("users")
+// SearchUsers returns up to limit users matching filter with an ID above
+// afterID, in the order of their IDs, so a caller pages through all of them
+// by passing the last ID it received.
+func (r *Repository) SearchUsers(ctx context.Context, filter UserSearchFilter, afterID int64, limit int) (output []UserSummary, err error) {
+	tx := r.Db.WithContext(ctx).Select("user_id, full_name, phone, email, status, role, locale, email_verified_at, phone_verified_at, created_at, updated_at")
 
-	for k, v := range updatedBy {
-		tx = tx.Where(fmt.Sprintf("%s = ?", k), v)
+	tx, err = where(tx, userSearchColumns, filter.columns())
+	if err != nil {
+		return
+	}
+	if filter.Query != nil {
+		pattern := "%" + escapeLike(strings.ToLower(*filter.Query)) + "%"
+		tx = tx.Where(`(LOWER(full_name) LIKE ? ESCAPE '\' OR phone LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	if filter.OrgID != nil {
+		tx = tx.Where("user_id IN (SELECT user_id FROM organization_members WHERE org_id = ?)", *filter.OrgID)
+	}
+	if filter.CreatedFrom != nil {
+		tx = tx.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		tx = tx.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	find := tx.Where("user_id > ?", afterID).Order("user_id").Limit(limit).Find(&output)
+	err = find.Error
+	return
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (r *Repository) UpdateProfile(ctx context.Context, filter ProfileFilter, patch ProfilePatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(profileColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("users"), profileColumns, conditions)
+	if err != nil {
+		return err
 	}
 
 	res := tx.WithContext(ctx).Updates(updatedData)
 	if res.Error != nil {
-		return res.Error
+		return normalizeError(res.Error)
 	}
 
 	return nil
 }
 
-func (r *Repository) GetLogin(ctx context.Context, filter map[string]interface{}) (output []LoginModel, err error) {
+func (r *Repository) GetLogin(ctx context.Context, filter LoginFilter) (output []LoginModel, err error) {
 	tx := r.Db.WithContext(ctx).Select("login_id, user_id, ip, token, expires, requests, created_at, updated_at")
 
-	for k, v := range filter {
-		tx = tx.Where(fmt.Sprintf("%s = ?", k), v)
+	tx, err = where(tx, loginColumns, filter.columns())
+	if err != nil {
+		return
 	}
 
 	find := tx.Find(&output)
@@ -58,23 +103,564 @@ func (r *Repository) GetLogin(ctx context.Context, filter map[string]interface{}
 func (r *Repository) InsertIntoLogin(ctx context.Context, login LoginModel) (output LoginModel, err error) {
 	tx := r.Db.WithContext(ctx).Create(&login)
 	if tx.Error != nil {
-		err = tx.Error
+		err = normalizeError(tx.Error)
 	}
 
 	output = login
 	return
 }
 
-func (r *Repository) UpdateLogin(ctx context.Context, updatedBy map[string]interface{}, updatedData map[string]interface{}) error {
-	tx := r.Db.Table("login")
+func (r *Repository) UpdateLogin(ctx context.Context, filter LoginFilter, patch LoginPatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(loginColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("login"), loginColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateLoginOTP(ctx context.Context, otp LoginOTP) (output LoginOTP, err error) {
+	tx := r.Db.WithContext(ctx).Create(&otp)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = otp
+	return
+}
+
+func (r *Repository) GetLoginOTP(ctx context.Context, filter LoginOTPFilter) (output []LoginOTP, err error) {
+	tx := r.Db.WithContext(ctx).Select("otp_id, user_id, code_hash, attempts, expires, consumed_at, created_at")
+
+	tx, err = where(tx, loginOTPColumns, filter.columns())
+	if err != nil {
+		return
+	}
+	if filter.CreatedAfter != nil {
+		tx = tx.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+
+	find := tx.Order("otp_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateLoginOTP(ctx context.Context, filter LoginOTPFilter, patch LoginOTPPatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(loginOTPColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("login_otp"), loginOTPColumns, conditions)
+	if err != nil {
+		return err
+	}
+	if filter.CreatedAfter != nil {
+		tx = tx.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateOTPRequest(ctx context.Context, request OTPRequest) (output OTPRequest, err error) {
+	tx := r.Db.WithContext(ctx).Create(&request)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = request
+	return
+}
+
+func (r *Repository) GetOTPRequest(ctx context.Context, filter OTPRequestFilter) (output []OTPRequest, err error) {
+	tx := r.Db.WithContext(ctx).Select("request_id, phone, created_at")
+
+	tx, err = where(tx, otpRequestColumns, filter.columns())
+	if err != nil {
+		return
+	}
+	if filter.CreatedAfter != nil {
+		tx = tx.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+
+	find := tx.Order("request_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) CreateOAuthClient(ctx context.Context, client OAuthClient) (output OAuthClient, err error) {
+	tx := r.Db.WithContext(ctx).Create(&client)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = client
+	return
+}
+
+func (r *Repository) GetOAuthClient(ctx context.Context, filter OAuthClientFilter) (output []OAuthClient, err error) {
+	tx := r.Db.WithContext(ctx).Select("client_id, secret_hash, name, redirect_uris, grant_types, scopes, created_at, updated_at")
+
+	tx, err = where(tx, oauthClientColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("created_at, client_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) DeleteOAuthClient(ctx context.Context, filter OAuthClientFilter) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	tx, err := where(r.Db.Table("oauth_clients"), oauthClientColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Delete(&OAuthClient{})
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateOAuthCode(ctx context.Context, code OAuthCode) (output OAuthCode, err error) {
+	tx := r.Db.WithContext(ctx).Create(&code)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = code
+	return
+}
+
+func (r *Repository) GetOAuthCode(ctx context.Context, filter OAuthCodeFilter) (output []OAuthCode, err error) {
+	tx := r.Db.WithContext(ctx).Select("code_hash, client_id, user_id, redirect_uri, scope, code_challenge, nonce, expires, consumed_at, created_at")
+
+	tx, err = where(tx, oauthCodeColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateOAuthCode(ctx context.Context, filter OAuthCodeFilter, patch OAuthCodePatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(oauthCodeColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("oauth_codes"), oauthCodeColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateOAuthToken(ctx context.Context, token OAuthToken) (output OAuthToken, err error) {
+	tx := r.Db.WithContext(ctx).Create(&token)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = token
+	return
+}
+
+func (r *Repository) GetOAuthToken(ctx context.Context, filter OAuthTokenFilter) (output []OAuthToken, err error) {
+	tx := r.Db.WithContext(ctx).Select("token_id, client_id, user_id, scope, refresh_hash, expires, refresh_expires, revoked_at, created_at")
+
+	tx, err = where(tx, oauthTokenColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("created_at, token_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateOAuthToken(ctx context.Context, filter OAuthTokenFilter, patch OAuthTokenPatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(oauthTokenColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("oauth_tokens"), oauthTokenColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateAPIKey(ctx context.Context, key APIKey) (output APIKey, err error) {
+	tx := r.Db.WithContext(ctx).Create(&key)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = key
+	return
+}
+
+func (r *Repository) GetAPIKey(ctx context.Context, filter APIKeyFilter) (output []APIKey, err error) {
+	tx := r.Db.WithContext(ctx).Select("key_id, user_id, name, prefix, secret_hash, scopes, expires, last_used_at, revoked_at, created_at")
+
+	tx, err = where(tx, apiKeyColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("key_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateAPIKey(ctx context.Context, filter APIKeyFilter, patch APIKeyPatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(apiKeyColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("api_keys"), apiKeyColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateOrganization(ctx context.Context, org Organization) (output Organization, err error) {
+	tx := r.Db.WithContext(ctx).Create(&org)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = org
+	return
+}
+
+func (r *Repository) GetOrganization(ctx context.Context, filter OrganizationFilter) (output []Organization, err error) {
+	tx := r.Db.WithContext(ctx).Select("org_id, parent_id, name, type, created_at, updated_at")
+
+	tx, err = where(tx, organizationColumns, filter.columns())
+	if err != nil {
+		return
+	}
+	if len(filter.OrgIDs) > 0 {
+		tx = tx.Where("org_id IN ?", filter.OrgIDs)
+	}
+
+	find := tx.Order("org_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) CreateOrganizationMember(ctx context.Context, member OrganizationMember) (output OrganizationMember, err error) {
+	tx := r.Db.WithContext(ctx).Create(&member)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = member
+	return
+}
+
+func (r *Repository) GetOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) (output []OrganizationMember, err error) {
+	tx := r.Db.WithContext(ctx).Select("org_id, user_id, role, created_at")
+
+	tx, err = where(tx, organizationMemberColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("org_id, user_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) DeleteOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	tx, err := where(r.Db.Table("organization_members"), organizationMemberColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Delete(&OrganizationMember{})
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
 
-	for k, v := range updatedBy {
-		tx = tx.Where(fmt.Sprintf("%s = ?", k), v)
+func (r *Repository) CreateInvitation(ctx context.Context, invitation Invitation) (output Invitation, err error) {
+	tx := r.Db.WithContext(ctx).Create(&invitation)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = invitation
+	return
+}
+
+func (r *Repository) GetInvitation(ctx context.Context, filter InvitationFilter) (output []Invitation, err error) {
+	tx := r.Db.WithContext(ctx).Select("invitation_id, org_id, phone, role, invited_by, token_id, expires, sent_at, accepted_at, revoked_at, created_at")
+
+	tx, err = where(tx, invitationColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("invitation_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateInvitation(ctx context.Context, filter InvitationFilter, patch InvitationPatch) (int64, error) {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return 0, ErrEmptyFilter
+	}
+
+	updatedData, err := set(invitationColumns, patch.columns())
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := where(r.Db.Table("invitations"), invitationColumns, conditions)
+	if err != nil {
+		return 0, err
 	}
 
 	res := tx.WithContext(ctx).Updates(updatedData)
 	if res.Error != nil {
-		return res.Error
+		return 0, normalizeError(res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
+
+func (r *Repository) CreateImpersonationAudit(ctx context.Context, audit ImpersonationAudit) (output ImpersonationAudit, err error) {
+	tx := r.Db.WithContext(ctx).Create(&audit)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = audit
+	return
+}
+
+func (r *Repository) GetImpersonationAudit(ctx context.Context, filter ImpersonationAuditFilter) (output []ImpersonationAudit, err error) {
+	tx := r.Db.WithContext(ctx).Select("audit_id, session_id, actor_id, user_id, method, path, status, ip, request_id, reason, created_at")
+
+	tx, err = where(tx, impersonationAuditColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("audit_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) CreateProfileAttribute(ctx context.Context, attribute ProfileAttribute) (output ProfileAttribute, err error) {
+	tx := r.Db.WithContext(ctx).Create(&attribute)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = attribute
+	return
+}
+
+func (r *Repository) GetProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) (output []ProfileAttribute, err error) {
+	tx := r.Db.WithContext(ctx).Select("attribute_id, name, label, type, rules, visibility, created_at, updated_at")
+
+	tx, err = where(tx, profileAttributeColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("attribute_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateProfileAttribute(ctx context.Context, filter ProfileAttributeFilter, patch ProfileAttributePatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(profileAttributeColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("profile_attributes"), profileAttributeColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	tx, err := where(r.Db.Table("profile_attributes"), profileAttributeColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Delete(&ProfileAttribute{})
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateProfileAttributeValue(ctx context.Context, value ProfileAttributeValue) (output ProfileAttributeValue, err error) {
+	tx := r.Db.WithContext(ctx).Create(&value)
+	if tx.Error != nil {
+		err = normalizeError(tx.Error)
+	}
+
+	output = value
+	return
+}
+
+func (r *Repository) GetProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) (output []ProfileAttributeValue, err error) {
+	tx := r.Db.WithContext(ctx).Select("user_id, attribute_id, value, updated_at")
+
+	tx, err = where(tx, profileAttributeValueColumns, filter.columns())
+	if err != nil {
+		return
+	}
+
+	find := tx.Order("user_id, attribute_id").Find(&output)
+	err = find.Error
+	return
+}
+
+func (r *Repository) UpdateProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter, patch ProfileAttributeValuePatch) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	updatedData, err := set(profileAttributeValueColumns, patch.columns())
+	if err != nil {
+		return err
+	}
+
+	tx, err := where(r.Db.Table("profile_attribute_values"), profileAttributeValueColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Updates(updatedData)
+	if res.Error != nil {
+		return normalizeError(res.Error)
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) error {
+	conditions := filter.columns()
+	if len(conditions) == 0 {
+		return ErrEmptyFilter
+	}
+
+	tx, err := where(r.Db.Table("profile_attribute_values"), profileAttributeValueColumns, conditions)
+	if err != nil {
+		return err
+	}
+
+	res := tx.WithContext(ctx).Delete(&ProfileAttributeValue{})
+	if res.Error != nil {
+		return normalizeError(res.Error)
 	}
 
 	return nil
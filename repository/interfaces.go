@@ -8,9 +8,46 @@ import "context"
 
 type RepositoryInterface interface {
 	CreateProfile(ctx context.Context, profile Profile) (output Profile, err error)
-	GetProfile(ctx context.Context, filter map[string]interface{}) (output []Profile, err error)
-	UpdateProfile(ctx context.Context, updatedBy map[string]interface{}, updatedData map[string]interface{}) error
-	GetLogin(ctx context.Context, filter map[string]interface{}) (output []LoginModel, err error)
+	GetProfile(ctx context.Context, filter ProfileFilter) (output []Profile, err error)
+	SearchUsers(ctx context.Context, filter UserSearchFilter, afterID int64, limit int) (output []UserSummary, err error)
+	UpdateProfile(ctx context.Context, filter ProfileFilter, patch ProfilePatch) error
+	GetLogin(ctx context.Context, filter LoginFilter) (output []LoginModel, err error)
 	InsertIntoLogin(ctx context.Context, login LoginModel) (output LoginModel, err error)
-	UpdateLogin(ctx context.Context, updatedBy map[string]interface{}, updatedData map[string]interface{}) error
+	UpdateLogin(ctx context.Context, filter LoginFilter, patch LoginPatch) error
+	CreateLoginOTP(ctx context.Context, otp LoginOTP) (output LoginOTP, err error)
+	GetLoginOTP(ctx context.Context, filter LoginOTPFilter) (output []LoginOTP, err error)
+	UpdateLoginOTP(ctx context.Context, filter LoginOTPFilter, patch LoginOTPPatch) error
+	CreateOTPRequest(ctx context.Context, request OTPRequest) (output OTPRequest, err error)
+	GetOTPRequest(ctx context.Context, filter OTPRequestFilter) (output []OTPRequest, err error)
+	CreateOAuthClient(ctx context.Context, client OAuthClient) (output OAuthClient, err error)
+	GetOAuthClient(ctx context.Context, filter OAuthClientFilter) (output []OAuthClient, err error)
+	DeleteOAuthClient(ctx context.Context, filter OAuthClientFilter) error
+	CreateOAuthCode(ctx context.Context, code OAuthCode) (output OAuthCode, err error)
+	GetOAuthCode(ctx context.Context, filter OAuthCodeFilter) (output []OAuthCode, err error)
+	UpdateOAuthCode(ctx context.Context, filter OAuthCodeFilter, patch OAuthCodePatch) error
+	CreateOAuthToken(ctx context.Context, token OAuthToken) (output OAuthToken, err error)
+	GetOAuthToken(ctx context.Context, filter OAuthTokenFilter) (output []OAuthToken, err error)
+	UpdateOAuthToken(ctx context.Context, filter OAuthTokenFilter, patch OAuthTokenPatch) error
+	CreateAPIKey(ctx context.Context, key APIKey) (output APIKey, err error)
+	GetAPIKey(ctx context.Context, filter APIKeyFilter) (output []APIKey, err error)
+	UpdateAPIKey(ctx context.Context, filter APIKeyFilter, patch APIKeyPatch) error
+	CreateOrganization(ctx context.Context, org Organization) (output Organization, err error)
+	GetOrganization(ctx context.Context, filter OrganizationFilter) (output []Organization, err error)
+	CreateOrganizationMember(ctx context.Context, member OrganizationMember) (output OrganizationMember, err error)
+	GetOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) (output []OrganizationMember, err error)
+	DeleteOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) error
+	CreateInvitation(ctx context.Context, invitation Invitation) (output Invitation, err error)
+	GetInvitation(ctx context.Context, filter InvitationFilter) (output []Invitation, err error)
+	UpdateInvitation(ctx context.Context, filter InvitationFilter, patch InvitationPatch) (affected int64, err error)
+	CreateImpersonationAudit(ctx context.Context, audit ImpersonationAudit) (output ImpersonationAudit, err error)
+	GetImpersonationAudit(ctx context.Context, filter ImpersonationAuditFilter) (output []ImpersonationAudit, err error)
+	CreateProfileAttribute(ctx context.Context, attribute ProfileAttribute) (output ProfileAttribute, err error)
+	GetProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) (output []ProfileAttribute, err error)
+	UpdateProfileAttribute(ctx context.Context, filter ProfileAttributeFilter, patch ProfileAttributePatch) error
+	DeleteProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) error
+	CreateProfileAttributeValue(ctx context.Context, value ProfileAttributeValue) (output ProfileAttributeValue, err error)
+	GetProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) (output []ProfileAttributeValue, err error)
+	UpdateProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter, patch ProfileAttributeValuePatch) error
+	DeleteProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) error
+	RunInTx(ctx context.Context, fn func(repo RepositoryInterface) error) error
 }
@@ -34,6 +34,156 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// CreateAPIKey mocks base method.
+func (m *MockRepositoryInterface) CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateAPIKey), ctx, key)
+}
+
+// CreateImpersonationAudit mocks base method.
+func (m *MockRepositoryInterface) CreateImpersonationAudit(ctx context.Context, audit ImpersonationAudit) (ImpersonationAudit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImpersonationAudit", ctx, audit)
+	ret0, _ := ret[0].(ImpersonationAudit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateImpersonationAudit indicates an expected call of CreateImpersonationAudit.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateImpersonationAudit(ctx, audit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImpersonationAudit", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateImpersonationAudit), ctx, audit)
+}
+
+// CreateInvitation mocks base method.
+func (m *MockRepositoryInterface) CreateInvitation(ctx context.Context, invitation Invitation) (Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvitation", ctx, invitation)
+	ret0, _ := ret[0].(Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateInvitation indicates an expected call of CreateInvitation.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateInvitation(ctx, invitation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvitation", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateInvitation), ctx, invitation)
+}
+
+// CreateLoginOTP mocks base method.
+func (m *MockRepositoryInterface) CreateLoginOTP(ctx context.Context, otp LoginOTP) (LoginOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginOTP", ctx, otp)
+	ret0, _ := ret[0].(LoginOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLoginOTP indicates an expected call of CreateLoginOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateLoginOTP(ctx, otp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateLoginOTP), ctx, otp)
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockRepositoryInterface) CreateOAuthClient(ctx context.Context, client OAuthClient) (OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", ctx, client)
+	ret0, _ := ret[0].(OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOAuthClient(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOAuthClient), ctx, client)
+}
+
+// CreateOAuthCode mocks base method.
+func (m *MockRepositoryInterface) CreateOAuthCode(ctx context.Context, code OAuthCode) (OAuthCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthCode", ctx, code)
+	ret0, _ := ret[0].(OAuthCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthCode indicates an expected call of CreateOAuthCode.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOAuthCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthCode", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOAuthCode), ctx, code)
+}
+
+// CreateOAuthToken mocks base method.
+func (m *MockRepositoryInterface) CreateOAuthToken(ctx context.Context, token OAuthToken) (OAuthToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthToken", ctx, token)
+	ret0, _ := ret[0].(OAuthToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthToken indicates an expected call of CreateOAuthToken.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOAuthToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthToken", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOAuthToken), ctx, token)
+}
+
+// CreateOTPRequest mocks base method.
+func (m *MockRepositoryInterface) CreateOTPRequest(ctx context.Context, request OTPRequest) (OTPRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOTPRequest", ctx, request)
+	ret0, _ := ret[0].(OTPRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOTPRequest indicates an expected call of CreateOTPRequest.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOTPRequest(ctx, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOTPRequest", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOTPRequest), ctx, request)
+}
+
+// CreateOrganization mocks base method.
+func (m *MockRepositoryInterface) CreateOrganization(ctx context.Context, org Organization) (Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrganization", ctx, org)
+	ret0, _ := ret[0].(Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrganization indicates an expected call of CreateOrganization.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOrganization(ctx, org interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganization", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOrganization), ctx, org)
+}
+
+// CreateOrganizationMember mocks base method.
+func (m *MockRepositoryInterface) CreateOrganizationMember(ctx context.Context, member OrganizationMember) (OrganizationMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrganizationMember", ctx, member)
+	ret0, _ := ret[0].(OrganizationMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrganizationMember indicates an expected call of CreateOrganizationMember.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOrganizationMember(ctx, member interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganizationMember", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOrganizationMember), ctx, member)
+}
+
 // CreateProfile mocks base method.
 func (m *MockRepositoryInterface) CreateProfile(ctx context.Context, profile Profile) (Profile, error) {
 	m.ctrl.T.Helper()
@@ -49,8 +199,139 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateProfile(ctx, profile interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateProfile), ctx, profile)
 }
 
+// CreateProfileAttribute mocks base method.
+func (m *MockRepositoryInterface) CreateProfileAttribute(ctx context.Context, attribute ProfileAttribute) (ProfileAttribute, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProfileAttribute", ctx, attribute)
+	ret0, _ := ret[0].(ProfileAttribute)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProfileAttribute indicates an expected call of CreateProfileAttribute.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateProfileAttribute(ctx, attribute interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProfileAttribute", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateProfileAttribute), ctx, attribute)
+}
+
+// CreateProfileAttributeValue mocks base method.
+func (m *MockRepositoryInterface) CreateProfileAttributeValue(ctx context.Context, value ProfileAttributeValue) (ProfileAttributeValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProfileAttributeValue", ctx, value)
+	ret0, _ := ret[0].(ProfileAttributeValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProfileAttributeValue indicates an expected call of CreateProfileAttributeValue.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateProfileAttributeValue(ctx, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProfileAttributeValue", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateProfileAttributeValue), ctx, value)
+}
+
+// DeleteOAuthClient mocks base method.
+func (m *MockRepositoryInterface) DeleteOAuthClient(ctx context.Context, filter OAuthClientFilter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuthClient", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuthClient indicates an expected call of DeleteOAuthClient.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteOAuthClient(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuthClient", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteOAuthClient), ctx, filter)
+}
+
+// DeleteOrganizationMember mocks base method.
+func (m *MockRepositoryInterface) DeleteOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrganizationMember", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOrganizationMember indicates an expected call of DeleteOrganizationMember.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteOrganizationMember(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrganizationMember", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteOrganizationMember), ctx, filter)
+}
+
+// DeleteProfileAttribute mocks base method.
+func (m *MockRepositoryInterface) DeleteProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProfileAttribute", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProfileAttribute indicates an expected call of DeleteProfileAttribute.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteProfileAttribute(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfileAttribute", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteProfileAttribute), ctx, filter)
+}
+
+// DeleteProfileAttributeValue mocks base method.
+func (m *MockRepositoryInterface) DeleteProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProfileAttributeValue", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProfileAttributeValue indicates an expected call of DeleteProfileAttributeValue.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteProfileAttributeValue(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfileAttributeValue", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteProfileAttributeValue), ctx, filter)
+}
+
+// GetAPIKey mocks base method.
+func (m *MockRepositoryInterface) GetAPIKey(ctx context.Context, filter APIKeyFilter) ([]APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKey", ctx, filter)
+	ret0, _ := ret[0].([]APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKey indicates an expected call of GetAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) GetAPIKey(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).GetAPIKey), ctx, filter)
+}
+
+// GetImpersonationAudit mocks base method.
+func (m *MockRepositoryInterface) GetImpersonationAudit(ctx context.Context, filter ImpersonationAuditFilter) ([]ImpersonationAudit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImpersonationAudit", ctx, filter)
+	ret0, _ := ret[0].([]ImpersonationAudit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImpersonationAudit indicates an expected call of GetImpersonationAudit.
+func (mr *MockRepositoryInterfaceMockRecorder) GetImpersonationAudit(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImpersonationAudit", reflect.TypeOf((*MockRepositoryInterface)(nil).GetImpersonationAudit), ctx, filter)
+}
+
+// GetInvitation mocks base method.
+func (m *MockRepositoryInterface) GetInvitation(ctx context.Context, filter InvitationFilter) ([]Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitation", ctx, filter)
+	ret0, _ := ret[0].([]Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitation indicates an expected call of GetInvitation.
+func (mr *MockRepositoryInterfaceMockRecorder) GetInvitation(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitation", reflect.TypeOf((*MockRepositoryInterface)(nil).GetInvitation), ctx, filter)
+}
+
 // GetLogin mocks base method.
-func (m *MockRepositoryInterface) GetLogin(ctx context.Context, filter map[string]interface{}) ([]LoginModel, error) {
+func (m *MockRepositoryInterface) GetLogin(ctx context.Context, filter LoginFilter) ([]LoginModel, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetLogin", ctx, filter)
 	ret0, _ := ret[0].([]LoginModel)
@@ -64,8 +345,113 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetLogin(ctx, filter interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).GetLogin), ctx, filter)
 }
 
+// GetLoginOTP mocks base method.
+func (m *MockRepositoryInterface) GetLoginOTP(ctx context.Context, filter LoginOTPFilter) ([]LoginOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginOTP", ctx, filter)
+	ret0, _ := ret[0].([]LoginOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginOTP indicates an expected call of GetLoginOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) GetLoginOTP(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).GetLoginOTP), ctx, filter)
+}
+
+// GetOAuthClient mocks base method.
+func (m *MockRepositoryInterface) GetOAuthClient(ctx context.Context, filter OAuthClientFilter) ([]OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClient", ctx, filter)
+	ret0, _ := ret[0].([]OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClient indicates an expected call of GetOAuthClient.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOAuthClient(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClient", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOAuthClient), ctx, filter)
+}
+
+// GetOAuthCode mocks base method.
+func (m *MockRepositoryInterface) GetOAuthCode(ctx context.Context, filter OAuthCodeFilter) ([]OAuthCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthCode", ctx, filter)
+	ret0, _ := ret[0].([]OAuthCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthCode indicates an expected call of GetOAuthCode.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOAuthCode(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthCode", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOAuthCode), ctx, filter)
+}
+
+// GetOAuthToken mocks base method.
+func (m *MockRepositoryInterface) GetOAuthToken(ctx context.Context, filter OAuthTokenFilter) ([]OAuthToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthToken", ctx, filter)
+	ret0, _ := ret[0].([]OAuthToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthToken indicates an expected call of GetOAuthToken.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOAuthToken(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthToken", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOAuthToken), ctx, filter)
+}
+
+// GetOTPRequest mocks base method.
+func (m *MockRepositoryInterface) GetOTPRequest(ctx context.Context, filter OTPRequestFilter) ([]OTPRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOTPRequest", ctx, filter)
+	ret0, _ := ret[0].([]OTPRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOTPRequest indicates an expected call of GetOTPRequest.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOTPRequest(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOTPRequest", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOTPRequest), ctx, filter)
+}
+
+// GetOrganization mocks base method.
+func (m *MockRepositoryInterface) GetOrganization(ctx context.Context, filter OrganizationFilter) ([]Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrganization", ctx, filter)
+	ret0, _ := ret[0].([]Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrganization indicates an expected call of GetOrganization.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOrganization(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrganization", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOrganization), ctx, filter)
+}
+
+// GetOrganizationMember mocks base method.
+func (m *MockRepositoryInterface) GetOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) ([]OrganizationMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrganizationMember", ctx, filter)
+	ret0, _ := ret[0].([]OrganizationMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrganizationMember indicates an expected call of GetOrganizationMember.
+func (mr *MockRepositoryInterfaceMockRecorder) GetOrganizationMember(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrganizationMember", reflect.TypeOf((*MockRepositoryInterface)(nil).GetOrganizationMember), ctx, filter)
+}
+
 // GetProfile mocks base method.
-func (m *MockRepositoryInterface) GetProfile(ctx context.Context, filter map[string]interface{}) ([]Profile, error) {
+func (m *MockRepositoryInterface) GetProfile(ctx context.Context, filter ProfileFilter) ([]Profile, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetProfile", ctx, filter)
 	ret0, _ := ret[0].([]Profile)
@@ -79,6 +465,36 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetProfile(ctx, filter interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).GetProfile), ctx, filter)
 }
 
+// GetProfileAttribute mocks base method.
+func (m *MockRepositoryInterface) GetProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) ([]ProfileAttribute, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileAttribute", ctx, filter)
+	ret0, _ := ret[0].([]ProfileAttribute)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileAttribute indicates an expected call of GetProfileAttribute.
+func (mr *MockRepositoryInterfaceMockRecorder) GetProfileAttribute(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileAttribute", reflect.TypeOf((*MockRepositoryInterface)(nil).GetProfileAttribute), ctx, filter)
+}
+
+// GetProfileAttributeValue mocks base method.
+func (m *MockRepositoryInterface) GetProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) ([]ProfileAttributeValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileAttributeValue", ctx, filter)
+	ret0, _ := ret[0].([]ProfileAttributeValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileAttributeValue indicates an expected call of GetProfileAttributeValue.
+func (mr *MockRepositoryInterfaceMockRecorder) GetProfileAttributeValue(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileAttributeValue", reflect.TypeOf((*MockRepositoryInterface)(nil).GetProfileAttributeValue), ctx, filter)
+}
+
 // InsertIntoLogin mocks base method.
 func (m *MockRepositoryInterface) InsertIntoLogin(ctx context.Context, login LoginModel) (LoginModel, error) {
 	m.ctrl.T.Helper()
@@ -94,30 +510,158 @@ func (mr *MockRepositoryInterfaceMockRecorder) InsertIntoLogin(ctx, login interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertIntoLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertIntoLogin), ctx, login)
 }
 
+// RunInTx mocks base method.
+func (m *MockRepositoryInterface) RunInTx(ctx context.Context, fn func(RepositoryInterface) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunInTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunInTx indicates an expected call of RunInTx.
+func (mr *MockRepositoryInterfaceMockRecorder) RunInTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInTx", reflect.TypeOf((*MockRepositoryInterface)(nil).RunInTx), ctx, fn)
+}
+
+// SearchUsers mocks base method.
+func (m *MockRepositoryInterface) SearchUsers(ctx context.Context, filter UserSearchFilter, afterID int64, limit int) ([]UserSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, filter, afterID, limit)
+	ret0, _ := ret[0].([]UserSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) SearchUsers(ctx, filter, afterID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).SearchUsers), ctx, filter, afterID, limit)
+}
+
+// UpdateAPIKey mocks base method.
+func (m *MockRepositoryInterface) UpdateAPIKey(ctx context.Context, filter APIKeyFilter, patch APIKeyPatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAPIKey", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAPIKey indicates an expected call of UpdateAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateAPIKey(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateAPIKey), ctx, filter, patch)
+}
+
+// UpdateInvitation mocks base method.
+func (m *MockRepositoryInterface) UpdateInvitation(ctx context.Context, filter InvitationFilter, patch InvitationPatch) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvitation", ctx, filter, patch)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateInvitation indicates an expected call of UpdateInvitation.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateInvitation(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvitation", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateInvitation), ctx, filter, patch)
+}
+
 // UpdateLogin mocks base method.
-func (m *MockRepositoryInterface) UpdateLogin(ctx context.Context, updatedBy, updatedData map[string]interface{}) error {
+func (m *MockRepositoryInterface) UpdateLogin(ctx context.Context, filter LoginFilter, patch LoginPatch) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateLogin", ctx, updatedBy, updatedData)
+	ret := m.ctrl.Call(m, "UpdateLogin", ctx, filter, patch)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateLogin indicates an expected call of UpdateLogin.
-func (mr *MockRepositoryInterfaceMockRecorder) UpdateLogin(ctx, updatedBy, updatedData interface{}) *gomock.Call {
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateLogin(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateLogin), ctx, filter, patch)
+}
+
+// UpdateLoginOTP mocks base method.
+func (m *MockRepositoryInterface) UpdateLoginOTP(ctx context.Context, filter LoginOTPFilter, patch LoginOTPPatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLoginOTP", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLoginOTP indicates an expected call of UpdateLoginOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateLoginOTP(ctx, filter, patch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateLogin), ctx, updatedBy, updatedData)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLoginOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateLoginOTP), ctx, filter, patch)
+}
+
+// UpdateOAuthCode mocks base method.
+func (m *MockRepositoryInterface) UpdateOAuthCode(ctx context.Context, filter OAuthCodeFilter, patch OAuthCodePatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOAuthCode", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOAuthCode indicates an expected call of UpdateOAuthCode.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateOAuthCode(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOAuthCode", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateOAuthCode), ctx, filter, patch)
+}
+
+// UpdateOAuthToken mocks base method.
+func (m *MockRepositoryInterface) UpdateOAuthToken(ctx context.Context, filter OAuthTokenFilter, patch OAuthTokenPatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOAuthToken", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOAuthToken indicates an expected call of UpdateOAuthToken.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateOAuthToken(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOAuthToken", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateOAuthToken), ctx, filter, patch)
 }
 
 // UpdateProfile mocks base method.
-func (m *MockRepositoryInterface) UpdateProfile(ctx context.Context, updatedBy, updatedData map[string]interface{}) error {
+func (m *MockRepositoryInterface) UpdateProfile(ctx context.Context, filter ProfileFilter, patch ProfilePatch) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProfile", ctx, updatedBy, updatedData)
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, filter, patch)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateProfile indicates an expected call of UpdateProfile.
-func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfile(ctx, updatedBy, updatedData interface{}) *gomock.Call {
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfile(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfile), ctx, filter, patch)
+}
+
+// UpdateProfileAttribute mocks base method.
+func (m *MockRepositoryInterface) UpdateProfileAttribute(ctx context.Context, filter ProfileAttributeFilter, patch ProfileAttributePatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfileAttribute", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfileAttribute indicates an expected call of UpdateProfileAttribute.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfileAttribute(ctx, filter, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileAttribute", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfileAttribute), ctx, filter, patch)
+}
+
+// UpdateProfileAttributeValue mocks base method.
+func (m *MockRepositoryInterface) UpdateProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter, patch ProfileAttributeValuePatch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfileAttributeValue", ctx, filter, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfileAttributeValue indicates an expected call of UpdateProfileAttributeValue.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfileAttributeValue(ctx, filter, patch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfile), ctx, updatedBy, updatedData)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileAttributeValue", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfileAttributeValue), ctx, filter, patch)
 }
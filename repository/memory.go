@@ -0,0 +1,1397 @@
+// This file contains an in-memory implementation of RepositoryInterface for
+// tests and for running the service locally without Postgres.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps users and logins in maps guarded by a mutex. It
+// mirrors the constraints of the SQL schema, including the unique phone
+// number, and reports violations with the same errors as Repository.
+type MemoryRepository struct {
+	// mu is nil for the repository handed to a RunInTx callback, because
+	// the enclosing RunInTx already holds the lock.
+	mu   *sync.Mutex
+	data *memoryData
+}
+
+type memoryData struct {
+	users       map[int64]Profile
+	logins      map[int64]LoginModel
+	loginOTPs   map[int64]LoginOTP
+	otpRequests map[int64]OTPRequest
+	clients     map[string]OAuthClient
+	codes       map[string]OAuthCode
+	tokens      map[string]OAuthToken
+	apiKeys     map[int64]APIKey
+	orgs        map[int64]Organization
+	members     map[memberKey]OrganizationMember
+	invitations map[int64]Invitation
+	audits      map[int64]ImpersonationAudit
+	attributes  map[int64]ProfileAttribute
+	attrValues  map[attrValueKey]ProfileAttributeValue
+	nextUserID  int64
+	nextLoginID int64
+	nextOTPID   int64
+	nextReqID   int64
+	nextKeyID   int64
+	nextOrgID   int64
+	nextInvID   int64
+	nextAuditID int64
+	nextAttrID  int64
+}
+
+type memberKey struct {
+	orgID  int64
+	userID int64
+}
+
+type attrValueKey struct {
+	userID      int64
+	attributeID int64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		mu: &sync.Mutex{},
+		data: &memoryData{
+			users:       map[int64]Profile{},
+			logins:      map[int64]LoginModel{},
+			loginOTPs:   map[int64]LoginOTP{},
+			otpRequests: map[int64]OTPRequest{},
+			clients:     map[string]OAuthClient{},
+			codes:       map[string]OAuthCode{},
+			tokens:      map[string]OAuthToken{},
+			apiKeys:     map[int64]APIKey{},
+			orgs:        map[int64]Organization{},
+			members:     map[memberKey]OrganizationMember{},
+			invitations: map[int64]Invitation{},
+			audits:      map[int64]ImpersonationAudit{},
+			attributes:  map[int64]ProfileAttribute{},
+			attrValues:  map[attrValueKey]ProfileAttributeValue{},
+		},
+	}
+}
+
+func (r *MemoryRepository) lock() func() {
+	if r.mu == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *MemoryRepository) CreateProfile(ctx context.Context, profile Profile) (output Profile, err error) {
+	defer r.lock()()
+
+	if err = r.checkUniquePhone(profile.Phone, 0); err != nil {
+		return
+	}
+	if profile.EmailVerifiedAt != nil {
+		if err = r.checkUniqueEmail(profile.Email, 0); err != nil {
+			return
+		}
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if profile.CreatedAt == "" {
+		profile.CreatedAt = now
+	}
+	if profile.UpdatedAt == "" {
+		profile.UpdatedAt = now
+	}
+
+	r.data.nextUserID++
+	profile.UserId = r.data.nextUserID
+	r.data.users[profile.UserId] = profile
+
+	output = profile
+	return
+}
+
+func (r *MemoryRepository) GetProfile(ctx context.Context, filter ProfileFilter) (output []Profile, err error) {
+	defer r.lock()()
+
+	for _, profile := range r.data.users {
+		if profileMatches(profile, filter) {
+			output = append(output, profile)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].UserId < output[j].UserId
+	})
+	return
+}
+
+func (r *MemoryRepository) SearchUsers(ctx context.Context, filter UserSearchFilter, afterID int64, limit int) (output []UserSummary, err error) {
+	defer r.lock()()
+
+	for _, profile := range r.data.users {
+		if profile.UserId > afterID && r.userSearchMatches(profile, filter) {
+			output = append(output, UserSummary{
+				UserId:          profile.UserId,
+				FullName:        profile.FullName,
+				Phone:           profile.Phone,
+				Email:           profile.Email,
+				Status:          profile.Status,
+				Role:            profile.Role,
+				Locale:          profile.Locale,
+				EmailVerifiedAt: profile.EmailVerifiedAt,
+				PhoneVerifiedAt: profile.PhoneVerifiedAt,
+				CreatedAt:       profile.CreatedAt,
+				UpdatedAt:       profile.UpdatedAt,
+			})
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].UserId < output[j].UserId
+	})
+	if len(output) > limit {
+		output = output[:limit]
+	}
+	return
+}
+
+func (r *MemoryRepository) UpdateProfile(ctx context.Context, filter ProfileFilter, patch ProfilePatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	var ids []int64
+	for id, profile := range r.data.users {
+		if profileMatches(profile, filter) {
+			ids = append(ids, id)
+		}
+	}
+
+	// Validate before touching any row, so the update is all or nothing
+	// like a single UPDATE statement.
+	if patch.Phone != nil && len(ids) > 0 {
+		if len(ids) > 1 {
+			return phoneConflict(*patch.Phone)
+		}
+		if err := r.checkUniquePhone(*patch.Phone, ids[0]); err != nil {
+			return err
+		}
+	}
+	if (patch.Email != nil || patch.EmailVerifiedAt != nil) && len(ids) > 0 {
+		profile := r.data.users[ids[0]]
+		email := profile.Email
+		if patch.Email != nil {
+			email = patch.Email
+		}
+		verified := patch.EmailVerifiedAt != nil || (profile.EmailVerifiedAt != nil && !patch.ClearEmailVerifiedAt)
+		if verified && email != nil {
+			if len(ids) > 1 {
+				return emailConflict(*email)
+			}
+			if err := r.checkUniqueEmail(email, ids[0]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range ids {
+		profile := r.data.users[id]
+		if patch.Phone != nil {
+			profile.Phone = *patch.Phone
+		}
+		if patch.FullName != nil {
+			profile.FullName = *patch.FullName
+		}
+		if patch.Locale != nil {
+			profile.Locale = *patch.Locale
+		}
+		if patch.Role != nil {
+			profile.Role = *patch.Role
+		}
+		if patch.UpdatedAt != nil {
+			profile.UpdatedAt = *patch.UpdatedAt
+		}
+		if patch.Email != nil {
+			profile.Email = copyString(patch.Email)
+		}
+		if patch.EmailVerifiedAt != nil {
+			profile.EmailVerifiedAt = copyString(patch.EmailVerifiedAt)
+		}
+		if patch.ClearEmailVerifiedAt {
+			profile.EmailVerifiedAt = nil
+		}
+		if patch.EmailVerificationHash != nil {
+			profile.EmailVerificationHash = copyString(patch.EmailVerificationHash)
+		}
+		if patch.EmailVerificationExpires != nil {
+			profile.EmailVerificationExpires = copyString(patch.EmailVerificationExpires)
+		}
+		if patch.ClearEmailVerification {
+			profile.EmailVerificationHash = nil
+			profile.EmailVerificationExpires = nil
+		}
+		if patch.PhoneVerifiedAt != nil {
+			profile.PhoneVerifiedAt = copyString(patch.PhoneVerifiedAt)
+		}
+		if patch.ClearPhoneVerifiedAt {
+			profile.PhoneVerifiedAt = nil
+		}
+		if patch.AvatarKey != nil {
+			profile.AvatarKey = copyString(patch.AvatarKey)
+		}
+		r.data.users[id] = profile
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) GetLogin(ctx context.Context, filter LoginFilter) (output []LoginModel, err error) {
+	defer r.lock()()
+
+	for _, login := range r.data.logins {
+		if loginMatches(login, filter) {
+			output = append(output, login)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].LoginId < output[j].LoginId
+	})
+	return
+}
+
+func (r *MemoryRepository) InsertIntoLogin(ctx context.Context, login LoginModel) (output LoginModel, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.users[login.UserId]; !ok {
+		err = fmt.Errorf("login references unknown user %d", login.UserId)
+		return
+	}
+
+	r.data.nextLoginID++
+	login.LoginId = r.data.nextLoginID
+	r.data.logins[login.LoginId] = login
+
+	output = login
+	return
+}
+
+func (r *MemoryRepository) UpdateLogin(ctx context.Context, filter LoginFilter, patch LoginPatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id, login := range r.data.logins {
+		if !loginMatches(login, filter) {
+			continue
+		}
+
+		if patch.Ip != nil {
+			login.Ip = *patch.Ip
+		}
+		if patch.Token != nil {
+			login.Token = *patch.Token
+		}
+		if patch.Expires != nil {
+			login.Expires = *patch.Expires
+		}
+		if patch.Requests != nil {
+			login.Requests = *patch.Requests
+		}
+		r.data.logins[id] = login
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateLoginOTP(ctx context.Context, otp LoginOTP) (output LoginOTP, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.users[otp.UserId]; !ok {
+		err = fmt.Errorf("login otp references unknown user %d", otp.UserId)
+		return
+	}
+
+	if otp.CreatedAt == "" {
+		otp.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	r.data.nextOTPID++
+	otp.OtpId = r.data.nextOTPID
+	r.data.loginOTPs[otp.OtpId] = otp
+
+	output = otp
+	return
+}
+
+func (r *MemoryRepository) GetLoginOTP(ctx context.Context, filter LoginOTPFilter) (output []LoginOTP, err error) {
+	defer r.lock()()
+
+	for _, otp := range r.data.loginOTPs {
+		if loginOTPMatches(otp, filter) {
+			output = append(output, otp)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].OtpId < output[j].OtpId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateLoginOTP(ctx context.Context, filter LoginOTPFilter, patch LoginOTPPatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id, otp := range r.data.loginOTPs {
+		if !loginOTPMatches(otp, filter) {
+			continue
+		}
+
+		if patch.Attempts != nil {
+			otp.Attempts = *patch.Attempts
+		}
+		if patch.ConsumedAt != nil {
+			otp.ConsumedAt = copyString(patch.ConsumedAt)
+		}
+		r.data.loginOTPs[id] = otp
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateOTPRequest(ctx context.Context, request OTPRequest) (output OTPRequest, err error) {
+	defer r.lock()()
+
+	if request.CreatedAt == "" {
+		request.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	r.data.nextReqID++
+	request.RequestId = r.data.nextReqID
+	r.data.otpRequests[request.RequestId] = request
+
+	output = request
+	return
+}
+
+func (r *MemoryRepository) GetOTPRequest(ctx context.Context, filter OTPRequestFilter) (output []OTPRequest, err error) {
+	defer r.lock()()
+
+	for _, request := range r.data.otpRequests {
+		if otpRequestMatches(request, filter) {
+			output = append(output, request)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].RequestId < output[j].RequestId
+	})
+	return
+}
+
+func (r *MemoryRepository) CreateOAuthClient(ctx context.Context, client OAuthClient) (output OAuthClient, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.clients[client.ClientId]; ok {
+		err = ErrConflict.Wrap(fmt.Errorf("oauth client %s already exists", client.ClientId))
+		return
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if client.CreatedAt == "" {
+		client.CreatedAt = now
+	}
+	if client.UpdatedAt == "" {
+		client.UpdatedAt = now
+	}
+	client.SecretHash = copyNullableString(client.SecretHash)
+	r.data.clients[client.ClientId] = client
+
+	output = client
+	return
+}
+
+func (r *MemoryRepository) GetOAuthClient(ctx context.Context, filter OAuthClientFilter) (output []OAuthClient, err error) {
+	defer r.lock()()
+
+	for _, client := range r.data.clients {
+		if filter.ClientID == nil || client.ClientId == *filter.ClientID {
+			output = append(output, client)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].CreatedAt != output[j].CreatedAt {
+			return output[i].CreatedAt < output[j].CreatedAt
+		}
+		return output[i].ClientId < output[j].ClientId
+	})
+	return
+}
+
+// DeleteOAuthClient also deletes the codes and tokens of the client, like
+// the ON DELETE CASCADE foreign keys of the SQL schema.
+func (r *MemoryRepository) DeleteOAuthClient(ctx context.Context, filter OAuthClientFilter) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id := range r.data.clients {
+		if id != *filter.ClientID {
+			continue
+		}
+		delete(r.data.clients, id)
+		for hash, code := range r.data.codes {
+			if code.ClientId == id {
+				delete(r.data.codes, hash)
+			}
+		}
+		for tokenID, token := range r.data.tokens {
+			if token.ClientId == id {
+				delete(r.data.tokens, tokenID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateOAuthCode(ctx context.Context, code OAuthCode) (output OAuthCode, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.clients[code.ClientId]; !ok {
+		err = fmt.Errorf("oauth code references unknown client %s", code.ClientId)
+		return
+	}
+	if _, ok := r.data.users[code.UserId]; !ok {
+		err = fmt.Errorf("oauth code references unknown user %d", code.UserId)
+		return
+	}
+	if _, ok := r.data.codes[code.CodeHash]; ok {
+		err = ErrConflict.Wrap(fmt.Errorf("oauth code already exists"))
+		return
+	}
+
+	if code.CreatedAt == "" {
+		code.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	code.ConsumedAt = copyNullableString(code.ConsumedAt)
+	r.data.codes[code.CodeHash] = code
+
+	output = code
+	return
+}
+
+func (r *MemoryRepository) GetOAuthCode(ctx context.Context, filter OAuthCodeFilter) (output []OAuthCode, err error) {
+	defer r.lock()()
+
+	for _, code := range r.data.codes {
+		if filter.CodeHash == nil || code.CodeHash == *filter.CodeHash {
+			output = append(output, code)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].CodeHash < output[j].CodeHash
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateOAuthCode(ctx context.Context, filter OAuthCodeFilter, patch OAuthCodePatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for hash, code := range r.data.codes {
+		if hash != *filter.CodeHash {
+			continue
+		}
+
+		if patch.ConsumedAt != nil {
+			code.ConsumedAt = copyString(patch.ConsumedAt)
+		}
+		r.data.codes[hash] = code
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateOAuthToken(ctx context.Context, token OAuthToken) (output OAuthToken, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.clients[token.ClientId]; !ok {
+		err = fmt.Errorf("oauth token references unknown client %s", token.ClientId)
+		return
+	}
+	if token.UserId != nil {
+		if _, ok := r.data.users[*token.UserId]; !ok {
+			err = fmt.Errorf("oauth token references unknown user %d", *token.UserId)
+			return
+		}
+	}
+	for tokenID, other := range r.data.tokens {
+		if tokenID == token.TokenId || (token.RefreshHash != nil && other.RefreshHash != nil && *other.RefreshHash == *token.RefreshHash) {
+			err = ErrConflict.Wrap(fmt.Errorf("oauth token already exists"))
+			return
+		}
+	}
+
+	if token.CreatedAt == "" {
+		token.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	if token.UserId != nil {
+		userID := *token.UserId
+		token.UserId = &userID
+	}
+	token.RefreshHash = copyNullableString(token.RefreshHash)
+	token.RefreshExpires = copyNullableString(token.RefreshExpires)
+	token.RevokedAt = copyNullableString(token.RevokedAt)
+	r.data.tokens[token.TokenId] = token
+
+	output = token
+	return
+}
+
+func (r *MemoryRepository) GetOAuthToken(ctx context.Context, filter OAuthTokenFilter) (output []OAuthToken, err error) {
+	defer r.lock()()
+
+	for _, token := range r.data.tokens {
+		if oauthTokenMatches(token, filter) {
+			output = append(output, token)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].CreatedAt != output[j].CreatedAt {
+			return output[i].CreatedAt < output[j].CreatedAt
+		}
+		return output[i].TokenId < output[j].TokenId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateOAuthToken(ctx context.Context, filter OAuthTokenFilter, patch OAuthTokenPatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for tokenID, token := range r.data.tokens {
+		if !oauthTokenMatches(token, filter) {
+			continue
+		}
+
+		if patch.RevokedAt != nil {
+			token.RevokedAt = copyString(patch.RevokedAt)
+		}
+		r.data.tokens[tokenID] = token
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateAPIKey(ctx context.Context, key APIKey) (output APIKey, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.users[key.UserId]; !ok {
+		err = fmt.Errorf("api key references unknown user %d", key.UserId)
+		return
+	}
+	for _, other := range r.data.apiKeys {
+		if other.Prefix == key.Prefix {
+			err = ErrConflict.Wrap(fmt.Errorf("api key prefix %s already exists", key.Prefix))
+			return
+		}
+	}
+
+	if key.CreatedAt == "" {
+		key.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	key.Expires = copyNullableString(key.Expires)
+	key.LastUsedAt = copyNullableString(key.LastUsedAt)
+	key.RevokedAt = copyNullableString(key.RevokedAt)
+
+	r.data.nextKeyID++
+	key.KeyId = r.data.nextKeyID
+	r.data.apiKeys[key.KeyId] = key
+
+	output = key
+	return
+}
+
+func (r *MemoryRepository) GetAPIKey(ctx context.Context, filter APIKeyFilter) (output []APIKey, err error) {
+	defer r.lock()()
+
+	for _, key := range r.data.apiKeys {
+		if apiKeyMatches(key, filter) {
+			output = append(output, key)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].KeyId < output[j].KeyId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateAPIKey(ctx context.Context, filter APIKeyFilter, patch APIKeyPatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id, key := range r.data.apiKeys {
+		if !apiKeyMatches(key, filter) {
+			continue
+		}
+
+		if patch.LastUsedAt != nil {
+			key.LastUsedAt = copyString(patch.LastUsedAt)
+		}
+		if patch.RevokedAt != nil {
+			key.RevokedAt = copyString(patch.RevokedAt)
+		}
+		r.data.apiKeys[id] = key
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateOrganization(ctx context.Context, org Organization) (output Organization, err error) {
+	defer r.lock()()
+
+	if org.ParentId != nil {
+		if _, ok := r.data.orgs[*org.ParentId]; !ok {
+			err = fmt.Errorf("organization references unknown parent %d", *org.ParentId)
+			return
+		}
+		parentID := *org.ParentId
+		org.ParentId = &parentID
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if org.CreatedAt == "" {
+		org.CreatedAt = now
+	}
+	if org.UpdatedAt == "" {
+		org.UpdatedAt = now
+	}
+
+	r.data.nextOrgID++
+	org.OrgId = r.data.nextOrgID
+	r.data.orgs[org.OrgId] = org
+
+	output = org
+	return
+}
+
+func (r *MemoryRepository) GetOrganization(ctx context.Context, filter OrganizationFilter) (output []Organization, err error) {
+	defer r.lock()()
+
+	for _, org := range r.data.orgs {
+		if organizationMatches(org, filter) {
+			output = append(output, org)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].OrgId < output[j].OrgId
+	})
+	return
+}
+
+func (r *MemoryRepository) CreateOrganizationMember(ctx context.Context, member OrganizationMember) (output OrganizationMember, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.orgs[member.OrgId]; !ok {
+		err = fmt.Errorf("organization member references unknown organization %d", member.OrgId)
+		return
+	}
+	if _, ok := r.data.users[member.UserId]; !ok {
+		err = fmt.Errorf("organization member references unknown user %d", member.UserId)
+		return
+	}
+
+	key := memberKey{orgID: member.OrgId, userID: member.UserId}
+	if _, ok := r.data.members[key]; ok {
+		err = ErrConflict.Wrap(fmt.Errorf("user %d is already a member of organization %d", member.UserId, member.OrgId))
+		return
+	}
+
+	if member.CreatedAt == "" {
+		member.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	r.data.members[key] = member
+
+	output = member
+	return
+}
+
+func (r *MemoryRepository) GetOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) (output []OrganizationMember, err error) {
+	defer r.lock()()
+
+	for _, member := range r.data.members {
+		if organizationMemberMatches(member, filter) {
+			output = append(output, member)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].OrgId != output[j].OrgId {
+			return output[i].OrgId < output[j].OrgId
+		}
+		return output[i].UserId < output[j].UserId
+	})
+	return
+}
+
+func (r *MemoryRepository) DeleteOrganizationMember(ctx context.Context, filter OrganizationMemberFilter) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for key, member := range r.data.members {
+		if organizationMemberMatches(member, filter) {
+			delete(r.data.members, key)
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateInvitation(ctx context.Context, invitation Invitation) (output Invitation, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.orgs[invitation.OrgId]; !ok {
+		err = fmt.Errorf("invitation references unknown organization %d", invitation.OrgId)
+		return
+	}
+	if invitation.InvitedBy != nil {
+		if _, ok := r.data.users[*invitation.InvitedBy]; !ok {
+			err = fmt.Errorf("invitation references unknown user %d", *invitation.InvitedBy)
+			return
+		}
+		invitedBy := *invitation.InvitedBy
+		invitation.InvitedBy = &invitedBy
+	}
+	for _, other := range r.data.invitations {
+		if other.TokenId == invitation.TokenId {
+			err = ErrConflict.Wrap(fmt.Errorf("invitation token %s already exists", invitation.TokenId))
+			return
+		}
+	}
+
+	if invitation.CreatedAt == "" {
+		invitation.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	invitation.AcceptedAt = copyNullableString(invitation.AcceptedAt)
+	invitation.RevokedAt = copyNullableString(invitation.RevokedAt)
+
+	r.data.nextInvID++
+	invitation.InvitationId = r.data.nextInvID
+	r.data.invitations[invitation.InvitationId] = invitation
+
+	output = invitation
+	return
+}
+
+func (r *MemoryRepository) GetInvitation(ctx context.Context, filter InvitationFilter) (output []Invitation, err error) {
+	defer r.lock()()
+
+	for _, invitation := range r.data.invitations {
+		if invitationMatches(invitation, filter) {
+			output = append(output, invitation)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].InvitationId < output[j].InvitationId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateInvitation(ctx context.Context, filter InvitationFilter, patch InvitationPatch) (int64, error) {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return 0, ErrEmptyFilter
+	}
+
+	var affected int64
+	for id, invitation := range r.data.invitations {
+		if !invitationMatches(invitation, filter) {
+			continue
+		}
+
+		if patch.TokenID != nil {
+			for otherID, other := range r.data.invitations {
+				if otherID != id && other.TokenId == *patch.TokenID {
+					return 0, ErrConflict.Wrap(fmt.Errorf("invitation token %s already exists", *patch.TokenID))
+				}
+			}
+			invitation.TokenId = *patch.TokenID
+		}
+		if patch.Expires != nil {
+			invitation.Expires = *patch.Expires
+		}
+		if patch.SentAt != nil {
+			invitation.SentAt = *patch.SentAt
+		}
+		if patch.AcceptedAt != nil {
+			invitation.AcceptedAt = copyString(patch.AcceptedAt)
+		}
+		if patch.RevokedAt != nil {
+			invitation.RevokedAt = copyString(patch.RevokedAt)
+		}
+		r.data.invitations[id] = invitation
+		affected++
+	}
+
+	return affected, nil
+}
+
+func (r *MemoryRepository) CreateImpersonationAudit(ctx context.Context, audit ImpersonationAudit) (output ImpersonationAudit, err error) {
+	defer r.lock()()
+
+	if audit.CreatedAt == "" {
+		audit.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	audit.Reason = copyNullableString(audit.Reason)
+
+	r.data.nextAuditID++
+	audit.AuditId = r.data.nextAuditID
+	r.data.audits[audit.AuditId] = audit
+
+	output = audit
+	return
+}
+
+func (r *MemoryRepository) GetImpersonationAudit(ctx context.Context, filter ImpersonationAuditFilter) (output []ImpersonationAudit, err error) {
+	defer r.lock()()
+
+	for _, audit := range r.data.audits {
+		if impersonationAuditMatches(audit, filter) {
+			output = append(output, audit)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].AuditId < output[j].AuditId
+	})
+	return
+}
+
+func (r *MemoryRepository) CreateProfileAttribute(ctx context.Context, attribute ProfileAttribute) (output ProfileAttribute, err error) {
+	defer r.lock()()
+
+	for _, other := range r.data.attributes {
+		if other.Name == attribute.Name {
+			err = ErrConflict.Wrap(fmt.Errorf("profile attribute %s already exists", attribute.Name))
+			return
+		}
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if attribute.CreatedAt == "" {
+		attribute.CreatedAt = now
+	}
+	if attribute.UpdatedAt == "" {
+		attribute.UpdatedAt = now
+	}
+
+	r.data.nextAttrID++
+	attribute.AttributeId = r.data.nextAttrID
+	r.data.attributes[attribute.AttributeId] = attribute
+
+	output = attribute
+	return
+}
+
+func (r *MemoryRepository) GetProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) (output []ProfileAttribute, err error) {
+	defer r.lock()()
+
+	for _, attribute := range r.data.attributes {
+		if profileAttributeMatches(attribute, filter) {
+			output = append(output, attribute)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].AttributeId < output[j].AttributeId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateProfileAttribute(ctx context.Context, filter ProfileAttributeFilter, patch ProfileAttributePatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id, attribute := range r.data.attributes {
+		if !profileAttributeMatches(attribute, filter) {
+			continue
+		}
+
+		if patch.Label != nil {
+			attribute.Label = *patch.Label
+		}
+		if patch.Rules != nil {
+			attribute.Rules = *patch.Rules
+		}
+		if patch.Visibility != nil {
+			attribute.Visibility = *patch.Visibility
+		}
+		if patch.UpdatedAt != nil {
+			attribute.UpdatedAt = *patch.UpdatedAt
+		}
+		r.data.attributes[id] = attribute
+	}
+
+	return nil
+}
+
+// DeleteProfileAttribute deletes the values of the attributes too, like the
+// foreign key of the SQL schema.
+func (r *MemoryRepository) DeleteProfileAttribute(ctx context.Context, filter ProfileAttributeFilter) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for id, attribute := range r.data.attributes {
+		if !profileAttributeMatches(attribute, filter) {
+			continue
+		}
+
+		delete(r.data.attributes, id)
+		for key := range r.data.attrValues {
+			if key.attributeID == id {
+				delete(r.data.attrValues, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateProfileAttributeValue(ctx context.Context, value ProfileAttributeValue) (output ProfileAttributeValue, err error) {
+	defer r.lock()()
+
+	if _, ok := r.data.users[value.UserId]; !ok {
+		err = fmt.Errorf("profile attribute value references unknown user %d", value.UserId)
+		return
+	}
+	if _, ok := r.data.attributes[value.AttributeId]; !ok {
+		err = fmt.Errorf("profile attribute value references unknown attribute %d", value.AttributeId)
+		return
+	}
+
+	key := attrValueKey{userID: value.UserId, attributeID: value.AttributeId}
+	if _, ok := r.data.attrValues[key]; ok {
+		err = ErrConflict.Wrap(fmt.Errorf("user %d already has a value of attribute %d", value.UserId, value.AttributeId))
+		return
+	}
+
+	if value.UpdatedAt == "" {
+		value.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+	r.data.attrValues[key] = value
+
+	output = value
+	return
+}
+
+func (r *MemoryRepository) GetProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) (output []ProfileAttributeValue, err error) {
+	defer r.lock()()
+
+	for _, value := range r.data.attrValues {
+		if profileAttributeValueMatches(value, filter) {
+			output = append(output, value)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].UserId != output[j].UserId {
+			return output[i].UserId < output[j].UserId
+		}
+		return output[i].AttributeId < output[j].AttributeId
+	})
+	return
+}
+
+func (r *MemoryRepository) UpdateProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter, patch ProfileAttributeValuePatch) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for key, value := range r.data.attrValues {
+		if !profileAttributeValueMatches(value, filter) {
+			continue
+		}
+
+		if patch.Value != nil {
+			value.Value = *patch.Value
+		}
+		if patch.UpdatedAt != nil {
+			value.UpdatedAt = *patch.UpdatedAt
+		}
+		r.data.attrValues[key] = value
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) DeleteProfileAttributeValue(ctx context.Context, filter ProfileAttributeValueFilter) error {
+	defer r.lock()()
+
+	if len(filter.columns()) == 0 {
+		return ErrEmptyFilter
+	}
+
+	for key, value := range r.data.attrValues {
+		if profileAttributeValueMatches(value, filter) {
+			delete(r.data.attrValues, key)
+		}
+	}
+
+	return nil
+}
+
+// RunInTx runs fn against a copy of the data while holding the lock, and
+// publishes the copy only when fn succeeds. Transactions are therefore
+// serialized and never need to be retried.
+func (r *MemoryRepository) RunInTx(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+	defer r.lock()()
+
+	snapshot := r.data.clone()
+	if err := fn(&MemoryRepository{data: snapshot}); err != nil {
+		return err
+	}
+
+	*r.data = *snapshot
+	return nil
+}
+
+func (r *MemoryRepository) checkUniquePhone(phone string, exceptUserID int64) error {
+	for id, profile := range r.data.users {
+		if id != exceptUserID && profile.Phone == phone {
+			return phoneConflict(phone)
+		}
+	}
+	return nil
+}
+
+// checkUniqueEmail mirrors the unique index on LOWER(email) of verified
+// addresses, for a user verifying email.
+func (r *MemoryRepository) checkUniqueEmail(email *string, exceptUserID int64) error {
+	if email == nil {
+		return nil
+	}
+	for id, profile := range r.data.users {
+		if id != exceptUserID && profile.Email != nil && profile.EmailVerifiedAt != nil && strings.EqualFold(*profile.Email, *email) {
+			return emailConflict(*email)
+		}
+	}
+	return nil
+}
+
+func phoneConflict(phone string) error {
+	return ErrConflict.Wrap(fmt.Errorf("phone %s is already registered", phone))
+}
+
+func emailConflict(email string) error {
+	return ErrConflict.Wrap(fmt.Errorf("email %s is already registered", email))
+}
+
+// copyString keeps stored profiles from sharing memory with the caller.
+func copyString(s *string) *string {
+	output := *s
+	return &output
+}
+
+// copyNullableString is copyString for values that may be nil.
+func copyNullableString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	return copyString(s)
+}
+
+func (d *memoryData) clone() *memoryData {
+	output := &memoryData{
+		users:       make(map[int64]Profile, len(d.users)),
+		logins:      make(map[int64]LoginModel, len(d.logins)),
+		loginOTPs:   make(map[int64]LoginOTP, len(d.loginOTPs)),
+		otpRequests: make(map[int64]OTPRequest, len(d.otpRequests)),
+		clients:     make(map[string]OAuthClient, len(d.clients)),
+		codes:       make(map[string]OAuthCode, len(d.codes)),
+		tokens:      make(map[string]OAuthToken, len(d.tokens)),
+		apiKeys:     make(map[int64]APIKey, len(d.apiKeys)),
+		orgs:        make(map[int64]Organization, len(d.orgs)),
+		members:     make(map[memberKey]OrganizationMember, len(d.members)),
+		invitations: make(map[int64]Invitation, len(d.invitations)),
+		audits:      make(map[int64]ImpersonationAudit, len(d.audits)),
+		attributes:  make(map[int64]ProfileAttribute, len(d.attributes)),
+		attrValues:  make(map[attrValueKey]ProfileAttributeValue, len(d.attrValues)),
+		nextUserID:  d.nextUserID,
+		nextLoginID: d.nextLoginID,
+		nextOTPID:   d.nextOTPID,
+		nextReqID:   d.nextReqID,
+		nextKeyID:   d.nextKeyID,
+		nextOrgID:   d.nextOrgID,
+		nextInvID:   d.nextInvID,
+		nextAuditID: d.nextAuditID,
+		nextAttrID:  d.nextAttrID,
+	}
+	for id, profile := range d.users {
+		output.users[id] = profile
+	}
+	for id, login := range d.logins {
+		output.logins[id] = login
+	}
+	for id, otp := range d.loginOTPs {
+		output.loginOTPs[id] = otp
+	}
+	for id, request := range d.otpRequests {
+		output.otpRequests[id] = request
+	}
+	for id, client := range d.clients {
+		output.clients[id] = client
+	}
+	for hash, code := range d.codes {
+		output.codes[hash] = code
+	}
+	for id, token := range d.tokens {
+		output.tokens[id] = token
+	}
+	for id, key := range d.apiKeys {
+		output.apiKeys[id] = key
+	}
+	for id, org := range d.orgs {
+		output.orgs[id] = org
+	}
+	for key, member := range d.members {
+		output.members[key] = member
+	}
+	for id, invitation := range d.invitations {
+		output.invitations[id] = invitation
+	}
+	for id, audit := range d.audits {
+		output.audits[id] = audit
+	}
+	for id, attribute := range d.attributes {
+		output.attributes[id] = attribute
+	}
+	for key, value := range d.attrValues {
+		output.attrValues[key] = value
+	}
+	return output
+}
+
+func (r *MemoryRepository) userSearchMatches(profile Profile, filter UserSearchFilter) bool {
+	if filter.Role != nil && profile.Role != *filter.Role {
+		return false
+	}
+	if filter.Status != nil && profile.Status != *filter.Status {
+		return false
+	}
+	if filter.Query != nil {
+		query := strings.ToLower(*filter.Query)
+		if !strings.Contains(strings.ToLower(profile.FullName), query) && !strings.Contains(profile.Phone, query) {
+			return false
+		}
+	}
+	if filter.OrgID != nil {
+		if _, ok := r.data.members[memberKey{orgID: *filter.OrgID, userID: profile.UserId}]; !ok {
+			return false
+		}
+	}
+	if filter.CreatedFrom != nil && profile.CreatedAt < *filter.CreatedFrom {
+		return false
+	}
+	if filter.CreatedTo != nil && profile.CreatedAt >= *filter.CreatedTo {
+		return false
+	}
+	return true
+}
+
+func profileMatches(profile Profile, filter ProfileFilter) bool {
+	if filter.UserID != nil && profile.UserId != *filter.UserID {
+		return false
+	}
+	if filter.Phone != nil && profile.Phone != *filter.Phone {
+		return false
+	}
+	if filter.Email != nil && (profile.Email == nil || *profile.Email != *filter.Email) {
+		return false
+	}
+	if filter.EmailVerificationHash != nil && (profile.EmailVerificationHash == nil || *profile.EmailVerificationHash != *filter.EmailVerificationHash) {
+		return false
+	}
+	return true
+}
+
+func loginMatches(login LoginModel, filter LoginFilter) bool {
+	if filter.LoginID != nil && login.LoginId != *filter.LoginID {
+		return false
+	}
+	if filter.UserID != nil && login.UserId != *filter.UserID {
+		return false
+	}
+	return true
+}
+
+func loginOTPMatches(otp LoginOTP, filter LoginOTPFilter) bool {
+	if filter.OtpID != nil && otp.OtpId != *filter.OtpID {
+		return false
+	}
+	if filter.UserID != nil && otp.UserId != *filter.UserID {
+		return false
+	}
+	// Timestamps are written in one fixed-width layout, so they compare
+	// as strings.
+	if filter.CreatedAfter != nil && otp.CreatedAt < *filter.CreatedAfter {
+		return false
+	}
+	return true
+}
+
+func otpRequestMatches(request OTPRequest, filter OTPRequestFilter) bool {
+	if filter.Phone != nil && request.Phone != *filter.Phone {
+		return false
+	}
+	if filter.CreatedAfter != nil && request.CreatedAt < *filter.CreatedAfter {
+		return false
+	}
+	return true
+}
+
+func oauthTokenMatches(token OAuthToken, filter OAuthTokenFilter) bool {
+	if filter.TokenID != nil && token.TokenId != *filter.TokenID {
+		return false
+	}
+	if filter.ClientID != nil && token.ClientId != *filter.ClientID {
+		return false
+	}
+	if filter.RefreshHash != nil && (token.RefreshHash == nil || *token.RefreshHash != *filter.RefreshHash) {
+		return false
+	}
+	return true
+}
+
+func apiKeyMatches(key APIKey, filter APIKeyFilter) bool {
+	if filter.KeyID != nil && key.KeyId != *filter.KeyID {
+		return false
+	}
+	if filter.UserID != nil && key.UserId != *filter.UserID {
+		return false
+	}
+	if filter.Prefix != nil && key.Prefix != *filter.Prefix {
+		return false
+	}
+	return true
+}
+
+func organizationMatches(org Organization, filter OrganizationFilter) bool {
+	if filter.OrgID != nil && org.OrgId != *filter.OrgID {
+		return false
+	}
+	if filter.ParentID != nil && (org.ParentId == nil || *org.ParentId != *filter.ParentID) {
+		return false
+	}
+	if len(filter.OrgIDs) > 0 {
+		found := false
+		for _, id := range filter.OrgIDs {
+			found = found || org.OrgId == id
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func organizationMemberMatches(member OrganizationMember, filter OrganizationMemberFilter) bool {
+	if filter.OrgID != nil && member.OrgId != *filter.OrgID {
+		return false
+	}
+	if filter.UserID != nil && member.UserId != *filter.UserID {
+		return false
+	}
+	return true
+}
+
+func invitationMatches(invitation Invitation, filter InvitationFilter) bool {
+	if filter.InvitationID != nil && invitation.InvitationId != *filter.InvitationID {
+		return false
+	}
+	if filter.OrgID != nil && invitation.OrgId != *filter.OrgID {
+		return false
+	}
+	if filter.Phone != nil && invitation.Phone != *filter.Phone {
+		return false
+	}
+	if filter.TokenID != nil && invitation.TokenId != *filter.TokenID {
+		return false
+	}
+	return true
+}
+
+func impersonationAuditMatches(audit ImpersonationAudit, filter ImpersonationAuditFilter) bool {
+	if filter.SessionID != nil && audit.SessionId != *filter.SessionID {
+		return false
+	}
+	if filter.ActorID != nil && audit.ActorId != *filter.ActorID {
+		return false
+	}
+	if filter.UserID != nil && audit.UserId != *filter.UserID {
+		return false
+	}
+	return true
+}
+
+func profileAttributeMatches(attribute ProfileAttribute, filter ProfileAttributeFilter) bool {
+	if filter.AttributeID != nil && attribute.AttributeId != *filter.AttributeID {
+		return false
+	}
+	if filter.Name != nil && attribute.Name != *filter.Name {
+		return false
+	}
+	return true
+}
+
+func profileAttributeValueMatches(value ProfileAttributeValue, filter ProfileAttributeValueFilter) bool {
+	if filter.UserID != nil && value.UserId != *filter.UserID {
+		return false
+	}
+	if filter.AttributeID != nil && value.AttributeId != *filter.AttributeID {
+		return false
+	}
+	return true
+}
@@ -3,35 +3,105 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type Repository struct {
 	Db *gorm.DB
+
+	txIsolation  sql.IsolationLevel
+	txMaxRetries int
 }
 
 type NewRepositoryOptions struct {
-	Dsn string
+	// Driver is DriverPostgres (the default) or DriverSQLite. For SQLite,
+	// Dsn is the database file path, e.g. "sawit.db".
+	Driver string
+	Dsn    string
+
+	// TxIsolation is the isolation level used by RunInTx. It defaults to
+	// sql.LevelSerializable on Postgres, which turns check-then-insert races
+	// into serialization failures that RunInTx retries. SQLite transactions
+	// are always serializable and ignore it.
+	TxIsolation sql.IsolationLevel
+	// TxMaxRetries is how many times RunInTx retries a transaction that
+	// failed with a serialization failure or deadlock. It defaults to 3.
+	TxMaxRetries int
 }
 
 func NewRepository(opts NewRepositoryOptions) *Repository {
-	sqlDB, err := sql.Open("pgx", opts.Dsn)
-	if err != nil {
-		panic("failed to connect database")
+	if opts.Driver == "" {
+		opts.Driver = DriverPostgres
 	}
 
-	gormDB, err := gorm.Open(postgres.New(postgres.Config{
-		Conn: sqlDB,
-	}), &gorm.Config{})
-	if err != nil {
-		panic("failed to connect database")
+	gormConfig := &gorm.Config{
+		// Let the dialect translate driver errors such as unique violations
+		// into gorm errors, so normalizeError does not depend on the driver.
+		TranslateError: true,
+	}
+
+	var gormDB *gorm.DB
+	switch opts.Driver {
+	case DriverPostgres:
+		sqlDB, err := sql.Open("pgx", opts.Dsn)
+		if err != nil {
+			panic("failed to connect database")
+		}
+
+		gormDB, err = gorm.Open(postgres.New(postgres.Config{
+			Conn: sqlDB,
+		}), gormConfig)
+		if err != nil {
+			panic("failed to connect database")
+		}
+
+		sqlCon, _ := gormDB.DB()
+		sqlCon.SetMaxIdleConns(10)
+		sqlCon.SetMaxOpenConns(100)
+		sqlCon.SetConnMaxLifetime(0)
+
+		if opts.TxIsolation == sql.LevelDefault {
+			opts.TxIsolation = sql.LevelSerializable
+		}
+	case DriverSQLite:
+		var err error
+		gormDB, err = gorm.Open(sqlite.Open(opts.Dsn), gormConfig)
+		if err != nil {
+			panic("failed to connect database")
+		}
+
+		// SQLite allows a single writer. One connection serializes access in
+		// the pool instead of failing with "database is locked", and keeps
+		// the per-connection foreign_keys pragma in effect.
+		sqlCon, _ := gormDB.DB()
+		sqlCon.SetMaxIdleConns(1)
+		sqlCon.SetMaxOpenConns(1)
+		sqlCon.SetConnMaxLifetime(0)
+
+		if err = gormDB.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+			panic("failed to enable foreign keys")
+		}
+
+		opts.TxIsolation = sql.LevelDefault
+	default:
+		panic(fmt.Sprintf("unsupported database driver %q", opts.Driver))
 	}
 
-	sqlCon, _ := gormDB.DB()
-	sqlCon.SetMaxIdleConns(10)
-	sqlCon.SetMaxOpenConns(100)
-	sqlCon.SetConnMaxLifetime(0)
+	if opts.TxMaxRetries == 0 {
+		opts.TxMaxRetries = 3
+	}
 
-	return &Repository{gormDB}
+	return &Repository{
+		Db:           gormDB,
+		txIsolation:  opts.TxIsolation,
+		txMaxRetries: opts.TxMaxRetries,
+	}
 }
@@ -0,0 +1,51 @@
+// This file contains the unit-of-work support of the repository layer.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// RunInTx runs fn inside a single database transaction. The repository
+// passed to fn is bound to that transaction; fn must use it instead of the
+// outer repository for its statements to be part of the unit of work.
+//
+// The transaction is committed when fn returns nil and rolled back
+// otherwise. When it fails with a serialization failure or a deadlock, fn
+// is run again in a fresh transaction, so fn must not have side effects
+// outside the database. Calling RunInTx on a repository that is already in
+// a transaction creates a savepoint and never retries.
+func (r *Repository) RunInTx(ctx context.Context, fn func(repo RepositoryInterface) error) (err error) {
+	for attempt := 0; ; attempt++ {
+		err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(&Repository{
+				Db:          tx,
+				txIsolation: r.txIsolation,
+			})
+		}, &sql.TxOptions{Isolation: r.txIsolation})
+		if err == nil || !isRetryable(err) || attempt >= r.txMaxRetries {
+			return normalizeError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 10 * time.Millisecond):
+		}
+	}
+}
+
+// isRetryable reports whether err is a serialization failure (40001) or a
+// deadlock (40P01), after which the whole transaction can be retried.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
@@ -9,16 +9,77 @@ type GetTestByIdOutput struct {
 	Name string
 }
 
+// Roles of a user. Admins can manage the OAuth clients.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Profile struct {
 	UserId    int64  `gorm:"column:user_id;PRIMARY_KEY;AUTO_INCREMENT"`
 	FullName  string `gorm:"column:full_name"`
 	Password  string `gorm:"column:password"`
 	Phone     string `gorm:"column:phone"`
 	Status    int64  `gorm:"column:status"`
+	Locale    string `gorm:"column:locale"`
+	Role      string `gorm:"column:role"`
 	CreatedAt string `gorm:"column:created_at"`
 	UpdatedAt string `gorm:"column:updated_at"`
+
+	// Email is optional and unique regardless of case. The verification
+	// columns hold the hash of the pending verification token, never the
+	// token itself.
+	Email                    *string `gorm:"column:email"`
+	EmailVerifiedAt          *string `gorm:"column:email_verified_at"`
+	EmailVerificationHash    *string `gorm:"column:email_verification_hash"`
+	EmailVerificationExpires *string `gorm:"column:email_verification_expires"`
+
+	// PhoneVerifiedAt is set when the user proves they receive SMS on the
+	// phone number, by logging in with a one-time code.
+	PhoneVerifiedAt *string `gorm:"column:phone_verified_at"`
+
+	// AvatarKey is the prefix of the keys of the avatar thumbnails in the
+	// blob store, if the user uploaded one.
+	AvatarKey *string `gorm:"column:avatar_key"`
+}
+
+// UserSummary is a user without the password hash and the other secrets,
+// for listing users to admins. It is the only type SearchUsers returns, so
+// those columns cannot end up in a listing or an export.
+type UserSummary struct {
+	UserId          int64   `gorm:"column:user_id"`
+	FullName        string  `gorm:"column:full_name"`
+	Phone           string  `gorm:"column:phone"`
+	Email           *string `gorm:"column:email"`
+	Status          int64   `gorm:"column:status"`
+	Role            string  `gorm:"column:role"`
+	Locale          string  `gorm:"column:locale"`
+	EmailVerifiedAt *string `gorm:"column:email_verified_at"`
+	PhoneVerifiedAt *string `gorm:"column:phone_verified_at"`
+	CreatedAt       string  `gorm:"column:created_at"`
+	UpdatedAt       string  `gorm:"column:updated_at"`
+}
+
+func (UserSummary) TableName() string {
+	return "users"
 }
 
+// Types of organizations. Companies are at the top, estates belong to a
+// company and divisions to an estate.
+const (
+	OrgTypeCompany  = "company"
+	OrgTypeEstate   = "estate"
+	OrgTypeDivision = "division"
+)
+
+// Roles of the members of an organization, from the most to the least
+// privileged. A role also applies to the organizations below.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
 func (Profile) TableName() string {
 	return "users"
 }
@@ -35,3 +96,227 @@ type LoginModel struct {
 func (LoginModel) TableName() string {
 	return "login"
 }
+
+// LoginOTP is a one-time code sent by SMS to log in without a password.
+// Only the hash of the code is stored.
+type LoginOTP struct {
+	OtpId      int64   `gorm:"column:otp_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	UserId     int64   `gorm:"column:user_id"`
+	CodeHash   string  `gorm:"column:code_hash"`
+	Attempts   int64   `gorm:"column:attempts"`
+	Expires    string  `gorm:"column:expires"`
+	ConsumedAt *string `gorm:"column:consumed_at"`
+	CreatedAt  string  `gorm:"column:created_at"`
+}
+
+func (LoginOTP) TableName() string {
+	return "login_otp"
+}
+
+// OTPRequest records a request for a one-time login code sent to Phone,
+// whether or not a user has that number.
+type OTPRequest struct {
+	RequestId int64  `gorm:"column:request_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	Phone     string `gorm:"column:phone"`
+	CreatedAt string `gorm:"column:created_at"`
+}
+
+func (OTPRequest) TableName() string {
+	return "otp_requests"
+}
+
+// OAuthClient is an application allowed to request tokens from the OAuth
+// authorization server. Public clients, such as mobile apps, have no
+// secret. RedirectUris, GrantTypes and Scopes are space separated lists.
+type OAuthClient struct {
+	ClientId     string  `gorm:"column:client_id;PRIMARY_KEY"`
+	SecretHash   *string `gorm:"column:secret_hash"`
+	Name         string  `gorm:"column:name"`
+	RedirectUris string  `gorm:"column:redirect_uris"`
+	GrantTypes   string  `gorm:"column:grant_types"`
+	Scopes       string  `gorm:"column:scopes"`
+	CreatedAt    string  `gorm:"column:created_at"`
+	UpdatedAt    string  `gorm:"column:updated_at"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// OAuthCode is an authorization code issued to a client on behalf of a
+// user. Only the hash of the code is stored, together with the PKCE
+// challenge the client must answer to redeem it.
+type OAuthCode struct {
+	CodeHash      string  `gorm:"column:code_hash;PRIMARY_KEY"`
+	ClientId      string  `gorm:"column:client_id"`
+	UserId        int64   `gorm:"column:user_id"`
+	RedirectUri   string  `gorm:"column:redirect_uri"`
+	Scope         string  `gorm:"column:scope"`
+	CodeChallenge string  `gorm:"column:code_challenge"`
+	Nonce         string  `gorm:"column:nonce"`
+	Expires       string  `gorm:"column:expires"`
+	ConsumedAt    *string `gorm:"column:consumed_at"`
+	CreatedAt     string  `gorm:"column:created_at"`
+}
+
+func (OAuthCode) TableName() string {
+	return "oauth_codes"
+}
+
+// OAuthToken records an access token issued to a client, keyed by the jti
+// of the JWT, and the refresh token issued with it, if any. UserId is nil
+// for tokens issued to the client itself.
+type OAuthToken struct {
+	TokenId        string  `gorm:"column:token_id;PRIMARY_KEY"`
+	ClientId       string  `gorm:"column:client_id"`
+	UserId         *int64  `gorm:"column:user_id"`
+	Scope          string  `gorm:"column:scope"`
+	RefreshHash    *string `gorm:"column:refresh_hash"`
+	Expires        string  `gorm:"column:expires"`
+	RefreshExpires *string `gorm:"column:refresh_expires"`
+	RevokedAt      *string `gorm:"column:revoked_at"`
+	CreatedAt      string  `gorm:"column:created_at"`
+}
+
+func (OAuthToken) TableName() string {
+	return "oauth_tokens"
+}
+
+// APIKey is a personal access token a user creates for scripts. Keys are
+// looked up by Prefix, the part of the key before the secret, and only the
+// hash of the whole key is stored. Scopes is a space separated list.
+type APIKey struct {
+	KeyId      int64   `gorm:"column:key_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	UserId     int64   `gorm:"column:user_id"`
+	Name       string  `gorm:"column:name"`
+	Prefix     string  `gorm:"column:prefix"`
+	SecretHash string  `gorm:"column:secret_hash"`
+	Scopes     string  `gorm:"column:scopes"`
+	Expires    *string `gorm:"column:expires"`
+	LastUsedAt *string `gorm:"column:last_used_at"`
+	RevokedAt  *string `gorm:"column:revoked_at"`
+	CreatedAt  string  `gorm:"column:created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Organization is a company, estate or division. ParentId is nil for
+// companies.
+type Organization struct {
+	OrgId     int64  `gorm:"column:org_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	ParentId  *int64 `gorm:"column:parent_id"`
+	Name      string `gorm:"column:name"`
+	Type      string `gorm:"column:type"`
+	CreatedAt string `gorm:"column:created_at"`
+	UpdatedAt string `gorm:"column:updated_at"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationMember gives a user a role in an organization.
+type OrganizationMember struct {
+	OrgId     int64  `gorm:"column:org_id;PRIMARY_KEY"`
+	UserId    int64  `gorm:"column:user_id;PRIMARY_KEY"`
+	Role      string `gorm:"column:role"`
+	CreatedAt string `gorm:"column:created_at"`
+}
+
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// Invitation asks the owner of a phone number to register and join an
+// organization with Role. TokenId is the ID of the signed token last sent,
+// so resending an invitation invalidates the previous token.
+type Invitation struct {
+	InvitationId int64   `gorm:"column:invitation_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	OrgId        int64   `gorm:"column:org_id"`
+	Phone        string  `gorm:"column:phone"`
+	Role         string  `gorm:"column:role"`
+	InvitedBy    *int64  `gorm:"column:invited_by"`
+	TokenId      string  `gorm:"column:token_id"`
+	Expires      string  `gorm:"column:expires"`
+	SentAt       string  `gorm:"column:sent_at"`
+	AcceptedAt   *string `gorm:"column:accepted_at"`
+	RevokedAt    *string `gorm:"column:revoked_at"`
+	CreatedAt    string  `gorm:"column:created_at"`
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// ImpersonationAudit records a request an admin, ActorId, made as the user
+// UserId. SessionId is the ID of the impersonation token, shared by all the
+// requests made with it. Reason is only set on the request that started the
+// impersonation.
+type ImpersonationAudit struct {
+	AuditId   int64   `gorm:"column:audit_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	SessionId string  `gorm:"column:session_id"`
+	ActorId   int64   `gorm:"column:actor_id"`
+	UserId    int64   `gorm:"column:user_id"`
+	Method    string  `gorm:"column:method"`
+	Path      string  `gorm:"column:path"`
+	Status    int     `gorm:"column:status"`
+	Ip        string  `gorm:"column:ip"`
+	RequestId string  `gorm:"column:request_id"`
+	Reason    *string `gorm:"column:reason"`
+	CreatedAt string  `gorm:"column:created_at"`
+}
+
+func (ImpersonationAudit) TableName() string {
+	return "impersonation_audit"
+}
+
+// Types of the values of profile attributes.
+const (
+	AttrTypeString  = "string"
+	AttrTypeInteger = "integer"
+	AttrTypeNumber  = "number"
+	AttrTypeBoolean = "boolean"
+	AttrTypeDate    = "date"
+)
+
+// Visibilities of profile attributes. Users see and change editable
+// attributes, only see read-only ones, and do not see hidden ones; admins
+// see and change all of them.
+const (
+	AttrVisibilityEditable = "editable"
+	AttrVisibilityReadOnly = "readonly"
+	AttrVisibilityHidden   = "hidden"
+)
+
+// ProfileAttribute is a custom attribute of user profiles defined by
+// admins. Rules are validation rules in the syntax of the validate struct
+// tags, e.g. "min=3,max=20".
+type ProfileAttribute struct {
+	AttributeId int64  `gorm:"column:attribute_id;PRIMARY_KEY;AUTO_INCREMENT"`
+	Name        string `gorm:"column:name"`
+	Label       string `gorm:"column:label"`
+	Type        string `gorm:"column:type"`
+	Rules       string `gorm:"column:rules"`
+	Visibility  string `gorm:"column:visibility"`
+	CreatedAt   string `gorm:"column:created_at"`
+	UpdatedAt   string `gorm:"column:updated_at"`
+}
+
+func (ProfileAttribute) TableName() string {
+	return "profile_attributes"
+}
+
+// ProfileAttributeValue is the value of an attribute for a user, in the
+// canonical text form of the type of the attribute.
+type ProfileAttributeValue struct {
+	UserId      int64  `gorm:"column:user_id;PRIMARY_KEY"`
+	AttributeId int64  `gorm:"column:attribute_id;PRIMARY_KEY"`
+	Value       string `gorm:"column:value"`
+	UpdatedAt   string `gorm:"column:updated_at"`
+}
+
+func (ProfileAttributeValue) TableName() string {
+	return "profile_attribute_values"
+}
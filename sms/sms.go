@@ -0,0 +1,156 @@
+// Package sms sends text messages such as one-time login codes. Sender is
+// the extension point for SMS gateways: HTTPSender posts messages to a
+// gateway, WriterSender writes them to the log for local development only,
+// DisabledSender refuses to send, and FakeSender records them for tests.
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDisabled is returned by DisabledSender.
+var ErrDisabled = errors.New("sms: no sender is configured")
+
+type Message struct {
+	// To is the phone number in E.164.
+	To   string
+	Body string
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// HTTPSender posts every message as JSON, {"to": ..., "body": ...}, to the
+// URL of an SMS gateway or of a relay to one, with the token as a bearer
+// token when it is set. Any status other than 2xx fails the send.
+type HTTPSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type NewHTTPSenderOptions struct {
+	URL   string
+	Token string
+	// Client defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+func NewHTTPSender(opts NewHTTPSenderOptions) *HTTPSender {
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &HTTPSender{
+		url:    opts.URL,
+		token:  opts.Token,
+		client: client,
+	}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, message Message) error {
+	body, err := json.Marshal(map[string]string{
+		"to":   message.To,
+		"body": message.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sms: gateway responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// DisabledSender fails every send with ErrDisabled, for servers that have
+// no SMS gateway.
+type DisabledSender struct{}
+
+func NewDisabledSender() DisabledSender {
+	return DisabledSender{}
+}
+
+func (DisabledSender) Send(ctx context.Context, message Message) error {
+	return ErrDisabled
+}
+
+// WriterSender writes every message to w instead of delivering it. Messages
+// carry login codes and invitation tokens, so it is for local development
+// only: anyone reading w can log in as the recipients.
+type WriterSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSender(w io.Writer) *WriterSender {
+	return &WriterSender{
+		w: w,
+	}
+}
+
+func (s *WriterSender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.w, "SMS to %s: %s\n", message.To, message.Body)
+	return err
+}
+
+// FakeSender records the messages it is asked to send, and fails with Err
+// when it is set.
+type FakeSender struct {
+	mu       sync.Mutex
+	messages []Message
+
+	Err error
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+func (s *FakeSender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (s *FakeSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
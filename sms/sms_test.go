@@ -0,0 +1,45 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSender(t *testing.T) {
+	ctx := context.Background()
+	message := Message{To: "+6281234567891", Body: "Your code is 123456"}
+
+	t.Run("Positive Scenario, Messages are posted as JSON with the token", func(t *testing.T) {
+		var (
+			authorization string
+			body          map[string]string
+		)
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer gateway.Close()
+
+		sender := NewHTTPSender(NewHTTPSenderOptions{URL: gateway.URL, Token: "secret"})
+		assert.NoError(t, sender.Send(ctx, message))
+		assert.Equal(t, "Bearer secret", authorization)
+		assert.Equal(t, map[string]string{"to": message.To, "body": message.Body}, body)
+	})
+
+	t.Run("Negative Scenario, Gateway errors fail the send", func(t *testing.T) {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer gateway.Close()
+
+		sender := NewHTTPSender(NewHTTPSenderOptions{URL: gateway.URL})
+		assert.Error(t, sender.Send(ctx, message))
+		assert.ErrorIs(t, NewDisabledSender().Send(ctx, message), ErrDisabled)
+	})
+}
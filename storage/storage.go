@@ -0,0 +1,179 @@
+// Package storage keeps files such as avatars. BlobStore is the extension
+// point for object storage services; FileStore keeps files in a local
+// directory served by the service itself, and MemoryStore keeps them in
+// memory for tests.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidKey is returned for keys that are not a clean relative path.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores blobs under keys such as "avatars/1/a1b2/128.jpg".
+// Keys are relative paths separated by slashes.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data io.Reader) error
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients download the blob from.
+	URL(key string) string
+}
+
+// checkKey rejects keys that would escape the root of the store.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+type FileStore struct {
+	dir     string
+	baseURL string
+}
+
+type NewFileStoreOptions struct {
+	// Dir is the directory the blobs are written to. It is created when
+	// missing.
+	Dir string
+	// BaseURL is the URL Dir is served at, such as
+	// http://localhost:1323/blobs.
+	BaseURL string
+}
+
+func NewFileStore(opts NewFileStoreOptions) *FileStore {
+	return &FileStore{
+		dir:     opts.Dir,
+		baseURL: strings.TrimRight(opts.BaseURL, "/"),
+	}
+}
+
+// Put writes the blob to a temporary file first and renames it, so the
+// blob is never served half written. The content type is implied by the
+// extension of the key when the file is served.
+func (s *FileStore) Put(ctx context.Context, key, contentType string, data io.Reader) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+// Delete removes the blob. Deleting a missing blob is not an error.
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Blob is a blob kept by MemoryStore.
+type Blob struct {
+	ContentType string
+	Data        []byte
+}
+
+// MemoryStore keeps blobs in memory, and fails with Err when it is set.
+type MemoryStore struct {
+	mu    sync.Mutex
+	blobs map[string]Blob
+
+	Err error
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		blobs: map[string]Blob{},
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key, contentType string, data io.Reader) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, data); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.blobs[key] = Blob{
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	delete(s.blobs, key)
+	return nil
+}
+
+func (s *MemoryStore) URL(key string) string {
+	return "/blobs/" + key
+}
+
+// Blobs returns a copy of the blobs by key.
+func (s *MemoryStore) Blobs() map[string]Blob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	output := make(map[string]Blob, len(s.blobs))
+	for key, blob := range s.blobs {
+		output[key] = blob
+	}
+	return output
+}
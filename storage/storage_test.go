@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Run("Positive Scenario, Blobs are written, served and deleted", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileStore(NewFileStoreOptions{
+			Dir:     dir,
+			BaseURL: "http://localhost:1323/blobs/",
+		})
+
+		err := store.Put(context.Background(), "avatars/1/abc/64.jpg", "image/jpeg", strings.NewReader("jpeg"))
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, "avatars", "1", "abc", "64.jpg"))
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", string(data))
+		assert.Equal(t, "http://localhost:1323/blobs/avatars/1/abc/64.jpg", store.URL("avatars/1/abc/64.jpg"))
+
+		assert.NoError(t, store.Delete(context.Background(), "avatars/1/abc/64.jpg"))
+		_, err = os.Stat(filepath.Join(dir, "avatars", "1", "abc", "64.jpg"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.NoError(t, store.Delete(context.Background(), "avatars/1/abc/64.jpg"))
+	})
+
+	t.Run("Negative Scenario, Keys cannot leave the directory", func(t *testing.T) {
+		store := NewFileStore(NewFileStoreOptions{Dir: t.TempDir()})
+		for _, key := range []string{"", "/etc/passwd", "../secret", "avatars/../../secret", "avatars//1"} {
+			err := store.Put(context.Background(), key, "image/jpeg", strings.NewReader("jpeg"))
+			assert.ErrorIs(t, err, ErrInvalidKey, key)
+		}
+	})
+}
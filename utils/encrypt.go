@@ -4,8 +4,11 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordCost is the bcrypt cost of HashPassword.
+const PasswordCost = 14
+
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
 	return string(bytes), err
 }
 
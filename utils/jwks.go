@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a public RSA signing key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// PublicJWK returns the key that verifies the tokens signed by this
+// package, for publishing in a JWKS.
+func PublicJWK() (JWK, error) {
+	pubKey, err := ioutil.ReadFile("secret_cert/id_rsa.pub")
+	if err != nil {
+		return JWK{}, err
+	}
+
+	key, err := jwt.ParseRSAPublicKeyFromPEM(pubKey)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	n, e := jwkComponents(key)
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: keyID(key),
+		N:   n,
+		E:   e,
+	}, nil
+}
+
+// keyID is the JWK thumbprint of the key (RFC 7638), so it changes when the
+// key is rotated.
+func keyID(key *rsa.PublicKey) string {
+	n, e := jwkComponents(key)
+	// The members are in lexicographic order, as the thumbprint requires,
+	// and json.Marshal writes struct fields in declaration order.
+	thumbprint, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{e, "RSA", n})
+
+	sum := sha256.Sum256(thumbprint)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func jwkComponents(key *rsa.PublicKey) (n, e string) {
+	n = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	return
+}
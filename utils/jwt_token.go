@@ -7,46 +7,136 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"io/ioutil"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// jwtCustomClaims are the claims of session tokens. They identify the user
+// but carry none of the profile, which can change while the token is valid.
 type jwtCustomClaims struct {
-	repository.Profile
+	UserId int64 `json:"UserId"`
+	// OrganizationId is the organization the user is acting for, if any.
+	OrganizationId *int64 `json:"OrganizationId,omitempty"`
+	// Act is set when an admin impersonates the user.
+	Act *ActorClaim `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaim is the act claim of RFC 8693, naming who acts as the subject
+// of a token. Subject is the user ID of the admin.
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
+// ImpersonationTTL is how long impersonation tokens are valid.
+const ImpersonationTTL = 15 * time.Minute
+
+// AccessTokenClaims are the claims of the access tokens issued to OAuth
+// clients. Subject is the user ID, or the client ID for tokens the client
+// requested for itself, and ID is the key of the token in oauth_tokens.
+type AccessTokenClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenClaims are the claims of OpenID Connect id_tokens. The user claims
+// are only set when the scopes of the token release them.
+type IDTokenClaims struct {
+	Nonce               string `json:"nonce,omitempty"`
+	Name                string `json:"name,omitempty"`
+	PhoneNumber         string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool  `json:"phone_number_verified,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(dataUser repository.Profile) (t, expiresAtStr string, err error) {
-	expiresAt := time.Now().Add(time.Hour * 72)
-	expiresAtStr = expiresAt.Format("2006-01-02 15:04:04")
+	return generateSessionToken(dataUser, jwtCustomClaims{}, time.Hour*72)
+}
 
-	prvKey, err := ioutil.ReadFile("secret_cert/id_rsa")
-	if err != nil {
-		log.Fatalln(err)
-	}
+// GenerateOrganizationToken signs a session token like GenerateToken that
+// also carries the active organization of the user.
+func GenerateOrganizationToken(dataUser repository.Profile, organizationID int64) (t, expiresAtStr string, err error) {
+	return generateSessionToken(dataUser, jwtCustomClaims{OrganizationId: &organizationID}, time.Hour*72)
+}
 
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(prvKey)
-	if err != nil {
-		return
-	}
+// GenerateImpersonationToken signs a session token like GenerateToken for
+// the admin actorID to act as dataUser. It is valid for ImpersonationTTL,
+// and its ID, sessionID, groups the requests made with it in the audit.
+func GenerateImpersonationToken(dataUser repository.Profile, actorID int64, sessionID string) (t, expiresAtStr string, err error) {
+	return generateSessionToken(dataUser, jwtCustomClaims{
+		Act: &ActorClaim{Subject: strconv.FormatInt(actorID, 10)},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID: sessionID,
+		},
+	}, ImpersonationTTL)
+}
+
+// generateSessionToken signs the user ID of dataUser merged into extra,
+// expiring after ttl.
+func generateSessionToken(dataUser repository.Profile, extra jwtCustomClaims, ttl time.Duration) (t, expiresAtStr string, err error) {
+	expiresAt := time.Now().Add(ttl)
+	expiresAtStr = expiresAt.Format(TimestampLayout)
 
 	// Set custom claims
 	claims := &jwtCustomClaims{
-		Profile: repository.Profile{
-			UserId:   dataUser.UserId,
-			FullName: dataUser.FullName,
-			Phone:    dataUser.Phone,
-		},
+		UserId:         dataUser.UserId,
+		OrganizationId: extra.OrganizationId,
+		Act:            extra.Act,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        extra.ID,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
 	// Create token with claims
-	t, err = jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	t, err = signClaims(claims)
 	return
 }
 
+// InvitationAudience is the aud claim of invitation tokens. Session tokens
+// have no audience, which keeps invitations from being used as sessions.
+const InvitationAudience = "invitation"
+
+// GenerateInvitationToken signs the token texted with an invitation. Its ID
+// is the TokenId of the invitation.
+func GenerateInvitationToken(tokenID string, expiresAt time.Time) (string, error) {
+	return signClaims(&jwt.RegisteredClaims{
+		ID:        tokenID,
+		Audience:  jwt.ClaimStrings{InvitationAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+}
+
+// GenerateAccessToken signs an OAuth access token with the same key as the
+// tokens issued by GenerateToken, so ValidateToken accepts both.
+func GenerateAccessToken(claims AccessTokenClaims) (string, error) {
+	return signClaims(&claims)
+}
+
+// GenerateIDToken signs an OpenID Connect id_token.
+func GenerateIDToken(claims IDTokenClaims) (string, error) {
+	return signClaims(&claims)
+}
+
+func signClaims(claims jwt.Claims) (string, error) {
+	prvKey, err := ioutil.ReadFile("secret_cert/id_rsa")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(prvKey)
+	if err != nil {
+		return "", err
+	}
+
+	// The kid lets OpenID Connect clients pick the key from the JWKS.
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID(&key.PublicKey)
+	return token.SignedString(key)
+}
+
 func ValidateToken(tokenReq string) (claims interface{}, err error) {
 	tokenString := strings.Replace(tokenReq, "Bearer ", "", -1)
 
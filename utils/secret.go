@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewSecret returns a random URL-safe secret of size bytes and its hash.
+// Only the hash should be stored, so a leaked table does not leak secrets.
+func NewSecret(size int) (secret, hash string, err error) {
+	b := make([]byte, size)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	secret = hex.EncodeToString(b)
+	hash = HashSecret(secret)
+	return
+}
+
+// HashSecret hashes a secret created by NewSecret or another random token
+// of at least 128 bits, which a fast unsalted hash cannot be reversed for.
+// Short secrets such as one-time codes can be brute forced from such a hash
+// and must be hashed with HMACSecret instead.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// HMACSecret hashes a short secret with a server-side key, so that reading
+// the hashes is not enough to try every possible secret.
+func HMACSecret(key []byte, secret string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
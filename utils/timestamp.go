@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"time"
+)
+
+// TimestampLayout is the format timestamps are written to the database in.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// ParseTimestamp parses a timestamp read from the database. Drivers return
+// TIMESTAMP columns either in TimestampLayout or, after converting them to
+// time.Time, in RFC 3339. The columns have no time zone, so both are read
+// as local wall-clock time, which is how they were written.
+func ParseTimestamp(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(TimestampLayout, s, time.Local); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local), nil
+}